@@ -0,0 +1,196 @@
+// Package api serves JSON endpoints over plain HTTP, for scripts and
+// integrations that don't need a websocket, and for clients that can't
+// keep one open. Endpoints that act as a player are authenticated with
+// a socket session token or an API key, sent as
+// "Authorization: Bearer <token>". The endpoints are described by the
+// OpenAPI document at /api/openapi.json
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// Sessions looks up the players logged in to websocket sessions, and
+// serves sessions as event streams
+type Sessions interface {
+	SessionPlayer(token string) (playerID string, ok bool)
+	StreamEvents(w http.ResponseWriter, r *http.Request, playerID string) error
+}
+
+type handler struct {
+	games    *store.GameService
+	sessions Sessions
+	openings *book.Book
+}
+
+// Register adds the API routes to the given echo server. The opening
+// explorer serves openings, and is disabled if it is nil
+func Register(server *echo.Echo, games *store.GameService, sessions Sessions, openings *book.Book) {
+	h := &handler{games, sessions, openings}
+
+	server.GET("/api/openapi.json", serveOpenAPI)
+	server.GET("/api/players", h.lookupPlayer)
+	server.GET("/api/players/:id", h.lookupPlayer)
+	server.GET("/api/players/:id/stats", h.playerStats)
+	server.GET("/api/leaderboard", h.leaderboard)
+	server.GET("/api/book", h.explore)
+
+	server.POST("/api/keys", h.createAPIKey, h.authenticate)
+	server.DELETE("/api/keys", h.revokeAPIKeys, h.authenticate)
+	server.GET("/api/games", h.listGames, h.authenticate)
+	server.GET("/api/games/:id", h.getGame, h.authenticate)
+	server.GET("/api/games/:id/record", h.gameRecord, h.authenticate)
+	server.GET("/api/games/:id/review", h.gameReview, h.authenticate)
+	server.POST("/api/games/:id/moves", h.playMove, h.authenticate)
+	server.POST("/api/challenges", h.challenge, h.authenticate)
+	server.GET("/api/events", h.streamEvents, h.authenticateStream)
+}
+
+// statusCodes maps the errors a request can fail with to the status
+// they are reported with. Any other error is internal
+var statusCodes = map[error]int{
+	game.ErrSquarePlayed:      http.StatusConflict,
+	game.ErrWrongTurn:         http.StatusConflict,
+	game.ErrWrongSubgrid:      http.StatusConflict,
+	game.ErrGameOver:          http.StatusConflict,
+	game.ErrInvalidPlayer:     http.StatusForbidden,
+	game.ErrInvalidCoordinate: http.StatusBadRequest,
+
+	store.ErrGameNotFound:    http.StatusNotFound,
+	store.ErrPrivateGame:     http.StatusForbidden,
+	store.ErrBlocked:         http.StatusForbidden,
+	store.ErrInvalidSettings: http.StatusBadRequest,
+	store.ErrInvalidColor:    http.StatusBadRequest,
+	store.ErrGameNotFinished: http.StatusConflict,
+	store.ErrNotReviewable:   http.StatusNotFound,
+}
+
+// httpError converts an error from the game or store packages into an
+// HTTP error. Errors without a status are returned unchanged, and
+// reported as internal
+func httpError(err error) error {
+	status, ok := statusCodes[err]
+	if !ok {
+		return err
+	}
+	return echo.NewHTTPError(status, err.Error())
+}
+
+// queryInt parses an optional integer query parameter
+func queryInt(e echo.Context, name string) (int, error) {
+	value := e.QueryParam(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name)
+	}
+	return i, nil
+}
+
+type playerResponse struct {
+	Username string         `json:"username"`
+	PlayerID string         `json:"playerID"`
+	Ratings  []store.Rating `json:"ratings"`
+}
+
+// lookupPlayer serves GET /api/players/:id and
+// GET /api/players?username=<name>
+func (h *handler) lookupPlayer(e echo.Context) error {
+	var player *store.Player
+	var err error
+	if playerID := e.Param("id"); playerID != "" {
+		player, err = h.games.TryLookupPlayerUUID(playerID)
+	} else if username := e.QueryParam("username"); username != "" {
+		player, err = h.games.TryLookupPlayerUsername(username)
+	} else {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing username")
+	}
+	if err != nil {
+		return err
+	} else if player == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "player does not exist")
+	}
+
+	ratings, err := h.games.PlayerRatings(player.UUID)
+	if err != nil {
+		return err
+	}
+
+	return e.JSON(http.StatusOK, playerResponse{
+		Username: player.Username,
+		PlayerID: player.UUID,
+		Ratings:  ratings,
+	})
+}
+
+type playerStatsResponse struct {
+	Stats      *store.PlayerStats `json:"stats"`
+	HeadToHead *store.HeadToHead  `json:"headToHead,omitempty"`
+}
+
+// playerStats serves GET /api/players/:id/stats. Passing ?opponent=<id>
+// adds the head-to-head record against that player
+func (h *handler) playerStats(e echo.Context) error {
+	playerID := e.Param("id")
+	player, err := h.games.TryLookupPlayerUUID(playerID)
+	if err != nil {
+		return err
+	} else if player == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "player does not exist")
+	}
+
+	res := playerStatsResponse{}
+	res.Stats, err = h.games.PlayerStats(playerID)
+	if err != nil {
+		return err
+	}
+
+	if opponentID := e.QueryParam("opponent"); opponentID != "" {
+		res.HeadToHead, err = h.games.HeadToHead(playerID, opponentID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.JSON(http.StatusOK, res)
+}
+
+// leaderboard serves GET /api/leaderboard?by=rating|wins&pool=&offset=&limit=
+func (h *handler) leaderboard(e echo.Context) error {
+	offset, err := queryInt(e, "offset")
+	if err != nil {
+		return err
+	}
+	limit, err := queryInt(e, "limit")
+	if err != nil {
+		return err
+	}
+
+	by := e.QueryParam("by")
+	if by == "" {
+		by = store.LeaderboardByRating
+	}
+	pool := e.QueryParam("pool")
+	if pool == "" {
+		pool = store.Pool(store.GameSettings{})
+	}
+
+	page, err := h.games.Leaderboard(by, pool, offset, limit)
+	if err == store.ErrInvalidLeaderboard {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	} else if err != nil {
+		return err
+	}
+
+	return e.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// noSessions is a Sessions without any logged in players, so that only
+// API keys are accepted. Event streams end straight away
+type noSessions struct{}
+
+func (noSessions) SessionPlayer(token string) (string, bool) { return "", false }
+
+func (noSessions) StreamEvents(w http.ResponseWriter, r *http.Request, playerID string) error {
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// testAPI serves the API on a fresh database
+type testAPI struct {
+	games *store.GameService
+	url   string
+}
+
+// startAPI serves the API behind the given middleware
+func startAPI(t *testing.T, middleware ...echo.MiddlewareFunc) *testAPI {
+	games, err := store.NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := echo.New()
+	server.Use(middleware...)
+	Register(server, games, noSessions{}, nil)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return &testAPI{games: games, url: ts.URL}
+}
+
+// player creates a player, returning their ID and an API key for them
+func (ta *testAPI) player(t *testing.T, username string) (string, string) {
+	p, err := ta.games.CreatePlayer(username, "google-"+username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ta.games.CreateAPIKey(p.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.UUID, key
+}
+
+// do sends a request with key as its bearer token, if it isn't empty,
+// and returns the response's status and body
+func (ta *testAPI) do(t *testing.T, method, path, key, body string) (int, string) {
+	req, err := http.NewRequest(method, ta.url+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+key)
+	}
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(b)
+}
+
+func coordinateJSON(t *testing.T, s string) string {
+	c, err := game.ParseCoordinate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+func TestUnauthorized(t *testing.T) {
+	ta := startAPI(t)
+
+	for _, key := range []string{"", "not-a-key"} {
+		status, _ := ta.do(t, "GET", "/api/games", key, "")
+		if status != http.StatusUnauthorized {
+			t.Errorf("got %v with key %q, want 401", status, key)
+		}
+	}
+}
+
+func TestPrivateGame(t *testing.T) {
+	ta := startAPI(t)
+	alice, aliceKey := ta.player(t, "alice")
+	bob, _ := ta.player(t, "bob")
+	_, carolKey := ta.player(t, "carol")
+
+	gameID, err := ta.games.NewGame(alice, bob, store.ColorX, store.GameSettings{Private: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, body := ta.do(t, "GET", "/api/games/"+gameID, aliceKey, ""); status != http.StatusOK {
+		t.Errorf("player got %v: %v", status, body)
+	}
+	for _, path := range []string{"/api/games/" + gameID, "/api/games/" + gameID + "/record"} {
+		if status, _ := ta.do(t, "GET", path, carolKey, ""); status != http.StatusForbidden {
+			t.Errorf("got %v for %v, want 403", status, path)
+		}
+	}
+}
+
+func TestMoveOutOfTurn(t *testing.T) {
+	ta := startAPI(t)
+	alice, _ := ta.player(t, "alice")
+	bob, bobKey := ta.player(t, "bob")
+
+	gameID, err := ta.games.NewGame(alice, bob, store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := ta.do(t, "POST", "/api/games/"+gameID+"/moves", bobKey, coordinateJSON(t, "B2b2"))
+	if status != http.StatusConflict {
+		t.Errorf("got %v: %v, want 409", status, body)
+	}
+}
+
+func TestGameRecord(t *testing.T) {
+	ta := startAPI(t)
+	alice, aliceKey := ta.player(t, "alice")
+	bob, bobKey := ta.player(t, "bob")
+
+	gameID, err := ta.games.NewGame(alice, bob, store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, m := range []string{"B2b2", "B2a1", "A1b2"} {
+		key := aliceKey
+		if i%2 == 1 {
+			key = bobKey
+		}
+		status, body := ta.do(t, "POST", "/api/games/"+gameID+"/moves", key, coordinateJSON(t, m))
+		if status != http.StatusOK {
+			t.Fatalf("playing %v got %v: %v", m, status, body)
+		}
+	}
+
+	status, body := ta.do(t, "GET", "/api/games/"+gameID+"/record", bobKey, "")
+	if status != http.StatusOK {
+		t.Fatalf("got %v: %v", status, body)
+	}
+
+	record, err := game.ParseRecord(body)
+	if err != nil {
+		t.Fatalf("%v parsing %q", err, body)
+	}
+	tags := map[string]string{}
+	for _, tag := range record.Tags {
+		tags[tag.Name] = tag.Value
+	}
+	if tags["Game"] != gameID || tags["X"] != "alice" || tags["O"] != "bob" || tags["Result"] != game.ResultUnfinished {
+		t.Errorf("got tags %v", tags)
+	}
+	if _, ok := tags["Incomplete"]; ok {
+		t.Error("record is incomplete")
+	}
+	if !strings.HasSuffix(body, "1. B2b2 B2a1 2. A1b2 *\n") {
+		t.Errorf("got moves in %q", body)
+	}
+}
+
+func TestQueryTokenNotLogged(t *testing.T) {
+	logs := &bytes.Buffer{}
+	ta := startAPI(t, middleware.LoggerWithConfig(middleware.LoggerConfig{Output: logs}))
+	_, key := ta.player(t, "alice")
+
+	// only the event stream takes the token from the query
+	if status, body := ta.do(t, "GET", "/api/events?access_token="+key, "", ""); status != http.StatusOK {
+		t.Errorf("event stream got %v: %v", status, body)
+	}
+	if status, _ := ta.do(t, "GET", "/api/games?access_token="+key, "", ""); status != http.StatusUnauthorized {
+		t.Errorf("got %v listing games, want 401", status)
+	}
+	if status, _ := ta.do(t, "GET", "/api/games?access_token="+key, key, ""); status != http.StatusOK {
+		t.Errorf("got %v listing games with a bearer token, want 200", status)
+	}
+
+	if strings.Contains(logs.String(), key) {
+		t.Errorf("the key was logged:\n%v", logs)
+	} else if strings.Count(logs.String(), "\n") != 3 {
+		t.Errorf("got logs:\n%v", logs)
+	}
+}
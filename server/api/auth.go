@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+
+	"github.com/heartles/uttt/server/store"
+)
+
+// playerKey is where authenticate leaves the ID of the requesting
+// player in the echo context
+const playerKey = "playerID"
+
+// authenticate is middleware that rejects requests without a valid
+// bearer token. The token is either the session token of a websocket
+// session, or an API key
+func (h *handler) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return h.checkToken(next, false)
+}
+
+// authenticateStream is authenticate for event streams, which also take
+// the token as ?access_token=, since browsers can't set headers on an
+// EventSource
+func (h *handler) authenticateStream(next echo.HandlerFunc) echo.HandlerFunc {
+	return h.checkToken(next, true)
+}
+
+func (h *handler) checkToken(next echo.HandlerFunc, fromQuery bool) echo.HandlerFunc {
+	return func(e echo.Context) error {
+		auth := e.Request().Header.Get(echo.HeaderAuthorization)
+		if token := e.QueryParam("access_token"); fromQuery && auth == "" && token != "" {
+			auth = "Bearer " + token
+		}
+		stripToken(e.Request())
+		if !strings.HasPrefix(auth, "Bearer ") {
+			e.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+			return echo.NewHTTPError(http.StatusUnauthorized, "missing bearer token")
+		}
+		token := strings.TrimPrefix(auth, "Bearer ")
+
+		playerID, ok := h.sessions.SessionPlayer(token)
+		if !ok {
+			var err error
+			playerID, err = h.games.APIKeyPlayer(token)
+			if err == store.ErrInvalidAPIKey {
+				e.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid bearer token")
+			} else if err != nil {
+				return err
+			}
+		}
+
+		e.Set(playerKey, playerID)
+		return next(e)
+	}
+}
+
+// stripToken removes any access_token from the request's URL, so that
+// request logs don't record it
+func stripToken(r *http.Request) {
+	query := r.URL.Query()
+	if _, ok := query["access_token"]; !ok {
+		return
+	}
+
+	query.Del("access_token")
+	r.URL.RawQuery = query.Encode()
+	r.RequestURI = r.URL.RequestURI()
+}
+
+// player returns the ID of the authenticated player
+func player(e echo.Context) string {
+	return e.Get(playerKey).(string)
+}
+
+type apiKeyResponse struct {
+	APIKey string `json:"apiKey"`
+}
+
+// createAPIKey serves POST /api/keys. The key is only shown once
+func (h *handler) createAPIKey(e echo.Context) error {
+	key, err := h.games.CreateAPIKey(player(e))
+	if err != nil {
+		return err
+	}
+
+	return e.JSON(http.StatusCreated, apiKeyResponse{key})
+}
+
+// revokeAPIKeys serves DELETE /api/keys, revoking every key issued to
+// the player
+func (h *handler) revokeAPIKeys(e echo.Context) error {
+	err := h.games.RevokeAPIKeys(player(e))
+	if err != nil {
+		return err
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// streamEvents serves GET /api/events, streaming the player's session
+// as Server-Sent Events until they disconnect
+func (h *handler) streamEvents(e echo.Context) error {
+	return h.sessions.StreamEvents(e.Response(), e.Request(), player(e))
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/game"
+)
+
+type bookMove struct {
+	book.MoveStats
+	// Notation is the move in move notation
+	Notation string `json:"notation"`
+	// WinRate and DrawRate are the share of games the player making the
+	// move went on to win and draw
+	WinRate  float64 `json:"winRate"`
+	DrawRate float64 `json:"drawRate"`
+}
+
+type bookResponse struct {
+	Position string     `json:"position"`
+	Moves    []bookMove `json:"moves"`
+}
+
+// explore serves GET /api/book?position=<position>, the moves played
+// from a position in the opening book, most played first. The position
+// is in position notation, and defaults to the start of the game
+func (h *handler) explore(e echo.Context) error {
+	if h.openings == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "no opening book")
+	}
+
+	position := e.QueryParam("position")
+	if position == "" {
+		position = game.StartPosition
+	}
+	moves, err := h.openings.Moves(position)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid position")
+	}
+
+	// the player to move is X when both have as many squares
+	board := strings.Fields(position)[0]
+	xToMove := strings.Count(board, "X") == strings.Count(board, "O")
+	res := bookResponse{Position: position, Moves: []bookMove{}}
+	for _, m := range moves {
+		wins := m.OWins
+		if xToMove {
+			wins = m.XWins
+		}
+		res.Moves = append(res.Moves, bookMove{
+			MoveStats: m,
+			Notation:  m.Move.String(),
+			WinRate:   float64(wins) / float64(m.Games),
+			DrawRate:  float64(m.Draws) / float64(m.Games),
+		})
+	}
+
+	return e.JSON(http.StatusOK, res)
+}
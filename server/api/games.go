@@ -0,0 +1,115 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// listGames serves GET /api/games, every game the player has played
+// in, newest first
+func (h *handler) listGames(e echo.Context) error {
+	games, err := h.games.PlayerGames(player(e))
+	if err != nil {
+		return err
+	}
+
+	return e.JSON(http.StatusOK, games)
+}
+
+// getGame serves GET /api/games/:id
+func (h *handler) getGame(e echo.Context) error {
+	state, err := h.games.GameState(e.Param("id"), player(e))
+	if err != nil {
+		return httpError(err)
+	}
+
+	return e.JSON(http.StatusOK, state)
+}
+
+// gameRecord serves GET /api/games/:id/record, the game's moves in
+// the notation written by game.Record
+func (h *handler) gameRecord(e echo.Context) error {
+	record, err := h.games.GameRecord(e.Param("id"), player(e))
+	if err != nil {
+		return httpError(err)
+	}
+
+	return e.String(http.StatusOK, record.String())
+}
+
+// gameReview serves GET /api/games/:id/review, the engine's annotations
+// of a finished game's moves
+func (h *handler) gameReview(e echo.Context) error {
+	review, err := h.games.GameReview(e.Param("id"), player(e))
+	if err != nil {
+		return httpError(err)
+	}
+
+	return e.JSON(http.StatusOK, review)
+}
+
+// playMove serves POST /api/games/:id/moves. The body is the
+// coordinate to play, and the response the game's new state
+func (h *handler) playMove(e echo.Context) error {
+	var c game.Coordinate
+	err := e.Bind(&c)
+	if err != nil {
+		return err
+	}
+
+	state, err := h.games.PlayMove(e.Param("id"), game.Move{
+		PlayerID:   player(e),
+		Coordinate: c,
+	})
+	if err != nil {
+		return httpError(err)
+	}
+
+	return e.JSON(http.StatusOK, state)
+}
+
+type challengeRequest struct {
+	OpponentID  string            `json:"opponentID"`
+	Color       store.ColorChoice `json:"color"`
+	Variant     string            `json:"variant"`
+	TimeControl string            `json:"timeControl"`
+	Casual      bool              `json:"casual"`
+	Private     bool              `json:"private"`
+}
+
+type challengeResponse struct {
+	GameID string `json:"gameID"`
+}
+
+// challenge serves POST /api/challenges, starting a game against
+// another player
+func (h *handler) challenge(e echo.Context) error {
+	var req challengeRequest
+	err := e.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	opponent, err := h.games.TryLookupPlayerUUID(req.OpponentID)
+	if err != nil {
+		return err
+	} else if opponent == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "player does not exist")
+	}
+
+	gameID, err := h.games.NewGame(player(e), opponent.UUID, req.Color, store.GameSettings{
+		Variant:     req.Variant,
+		TimeControl: req.TimeControl,
+		Casual:      req.Casual,
+		Private:     req.Private,
+	})
+	if err != nil {
+		return httpError(err)
+	}
+
+	return e.JSON(http.StatusCreated, challengeResponse{gameID})
+}
@@ -0,0 +1,379 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// serveOpenAPI serves GET /api/openapi.json
+func serveOpenAPI(e echo.Context) error {
+	return e.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, []byte(openAPIDocument))
+}
+
+// openAPIDocument describes every endpoint in the package. Keep it in
+// step with Register
+const openAPIDocument = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "uttt",
+    "description": "HTTP API for ultimate tic-tac-toe. Endpoints that act as a player take a websocket session token or an API key as a bearer token.",
+    "version": "1"
+  },
+  "paths": {
+    "/api/keys": {
+      "post": {
+        "summary": "Issue an API key",
+        "description": "The key is only returned once. Keys don't expire until revoked.",
+        "security": [{"bearer": []}],
+        "responses": {
+          "201": {"description": "The new key", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIKey"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      },
+      "delete": {
+        "summary": "Revoke every API key issued to the player",
+        "security": [{"bearer": []}],
+        "responses": {
+          "204": {"description": "Revoked"},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/api/games": {
+      "get": {
+        "summary": "List the player's games, newest first",
+        "security": [{"bearer": []}],
+        "responses": {
+          "200": {"description": "The games", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/GameSummary"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/api/games/{id}": {
+      "get": {
+        "summary": "Get a game",
+        "description": "Games can be read by their players, and by anyone allowed to spectate them.",
+        "security": [{"bearer": []}],
+        "parameters": [{"$ref": "#/components/parameters/GameID"}],
+        "responses": {
+          "200": {"description": "The game", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GameState"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/games/{id}/record": {
+      "get": {
+        "summary": "Get a game's record",
+        "description": "Tags such as [X \"alice\"], one per line, then the numbered moves and the result (1-0, 0-1, 1/2-1/2 or *). A move is the subgrid, as an uppercase column A-C and a row 1-3 from the top, then the square within it in lowercase: B2a1 is the top left square of the center subgrid. Games from before moves were recorded have an Incomplete tag.",
+        "security": [{"bearer": []}],
+        "parameters": [{"$ref": "#/components/parameters/GameID"}],
+        "responses": {
+          "200": {"description": "The record", "content": {"text/plain": {"schema": {"type": "string"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/games/{id}/review": {
+      "get": {
+        "summary": "Get the engine's review of a finished game",
+        "description": "Each move is classed best, good, inaccuracy, mistake or blunder by how much less it scores than the engine's best move. Scores are from the mover's point of view. Reviews run in the background once a game finishes; until then the status is pending and there are no moves.",
+        "security": [{"bearer": []}],
+        "parameters": [{"$ref": "#/components/parameters/GameID"}],
+        "responses": {
+          "200": {"description": "The review", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GameReview"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"description": "No such game, or one that can't be reviewed", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+          "409": {"description": "The game hasn't finished", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+        }
+      }
+    },
+    "/api/games/{id}/moves": {
+      "post": {
+        "summary": "Play a move",
+        "security": [{"bearer": []}],
+        "parameters": [{"$ref": "#/components/parameters/GameID"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Coordinate"}}}},
+        "responses": {
+          "200": {"description": "The game after the move", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GameState"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"description": "The move is illegal", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+        }
+      }
+    },
+    "/api/challenges": {
+      "post": {
+        "summary": "Start a game against another player",
+        "security": [{"bearer": []}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Challenge"}}}},
+        "responses": {
+          "201": {"description": "The game was started", "content": {"application/json": {"schema": {"type": "object", "properties": {"gameID": {"type": "string"}}}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/events": {
+      "get": {
+        "summary": "Stream the player's session as Server-Sent Events",
+        "description": "For clients that can't keep a websocket open. The stream starts with a LoginSuccess, then carries the same messages as a websocket session, as JSON, in events named for their type. Events that are part of the session carry their sequence number as their ID. Actions are taken through the rest of this API. A client that reconnects starts a new session.",
+        "security": [{"bearer": []}],
+        "parameters": [
+          {"name": "access_token", "in": "query", "description": "The bearer token, for clients that can't set headers", "schema": {"type": "string"}},
+          {"name": "capabilities", "in": "query", "description": "Socket protocol capabilities to enable, separated by commas", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "The event stream", "content": {"text/event-stream": {"schema": {"type": "string"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/api/players": {
+      "get": {
+        "summary": "Look up a player by username",
+        "parameters": [{"name": "username", "in": "query", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {"description": "The player", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Player"}}}},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/players/{id}": {
+      "get": {
+        "summary": "Look up a player by ID",
+        "parameters": [{"$ref": "#/components/parameters/PlayerID"}],
+        "responses": {
+          "200": {"description": "The player", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Player"}}}},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/players/{id}/stats": {
+      "get": {
+        "summary": "Get a player's statistics",
+        "parameters": [
+          {"$ref": "#/components/parameters/PlayerID"},
+          {"name": "opponent", "in": "query", "description": "Adds the head-to-head record against this player", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "The statistics", "content": {"application/json": {"schema": {"type": "object"}}}},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/leaderboard": {
+      "get": {
+        "summary": "Get a page of a leaderboard",
+        "parameters": [
+          {"name": "by", "in": "query", "schema": {"type": "string", "enum": ["rating", "wins"], "default": "rating"}},
+          {"name": "pool", "in": "query", "description": "The rating pool, for rating leaderboards", "schema": {"type": "string"}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 0, "maximum": 100, "default": 20}}
+        ],
+        "responses": {
+          "200": {"description": "The page", "content": {"application/json": {"schema": {"type": "object"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/book": {
+      "get": {
+        "summary": "Explore the opening book",
+        "description": "The moves played from a position in the opening book, most played first, with how the games went. winRate and drawRate are from the point of view of the player making the move",
+        "parameters": [
+          {"name": "position", "in": "query", "description": "The position, in position notation", "schema": {"type": "string", "default": "9/9/9/9/9/9/9/9/9 -"}}
+        ],
+        "responses": {
+          "200": {"description": "The book moves", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BookPosition"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"description": "The server has no opening book", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+        }
+      }
+    },
+    "/api/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "responses": {"200": {"description": "The OpenAPI document", "content": {"application/json": {}}}}
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearer": {"type": "http", "scheme": "bearer", "description": "A websocket session token, or an API key from POST /api/keys"}
+    },
+    "parameters": {
+      "GameID": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+      "PlayerID": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+    },
+    "responses": {
+      "Error": {"description": "The request failed", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Unauthorized": {"description": "The bearer token is missing or invalid", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+    },
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "properties": {"message": {"type": "string"}}
+      },
+      "APIKey": {
+        "type": "object",
+        "properties": {"apiKey": {"type": "string"}}
+      },
+      "SubCoordinate": {
+        "type": "object",
+        "description": "A subgrid of the board, or a square of a subgrid. x is the column and y the row, both 1-3 from the top left",
+        "properties": {"x": {"type": "integer", "minimum": 1, "maximum": 3}, "y": {"type": "integer", "minimum": 1, "maximum": 3}}
+      },
+      "Coordinate": {
+        "type": "object",
+        "properties": {
+          "gameSquare": {"$ref": "#/components/schemas/SubCoordinate"},
+          "subgridSquare": {"$ref": "#/components/schemas/SubCoordinate"}
+        }
+      },
+      "GameSettings": {
+        "type": "object",
+        "properties": {
+          "variant": {"type": "string"},
+          "timeControl": {"type": "string", "description": "<minutes>+<increment seconds>, or empty for an untimed game"},
+          "casual": {"type": "boolean"},
+          "private": {"type": "boolean"}
+        }
+      },
+      "Challenge": {
+        "type": "object",
+        "required": ["opponentID"],
+        "properties": {
+          "opponentID": {"type": "string"},
+          "color": {"type": "string", "enum": ["x", "o", "random", "alternate"], "default": "x"},
+          "variant": {"type": "string"},
+          "timeControl": {"type": "string"},
+          "casual": {"type": "boolean"},
+          "private": {"type": "boolean"}
+        }
+      },
+      "GameSummary": {
+        "type": "object",
+        "properties": {
+          "gameID": {"type": "string"},
+          "playerX": {"type": "string"},
+          "playerO": {"type": "string"},
+          "playerXName": {"type": "string"},
+          "playerOName": {"type": "string"},
+          "victor": {"type": "string", "nullable": true, "description": "The winner's ID, or \"tie\""},
+          "finished": {"type": "boolean"},
+          "created": {"type": "string", "format": "date-time"},
+          "settings": {"$ref": "#/components/schemas/GameSettings"}
+        }
+      },
+      "Rating": {
+        "type": "object",
+        "properties": {
+          "pool": {"type": "string"},
+          "rating": {"type": "number"},
+          "deviation": {"type": "number"},
+          "volatility": {"type": "number"},
+          "games": {"type": "integer"}
+        }
+      },
+      "Player": {
+        "type": "object",
+        "properties": {
+          "username": {"type": "string"},
+          "playerID": {"type": "string"},
+          "ratings": {"type": "array", "items": {"$ref": "#/components/schemas/Rating"}}
+        }
+      },
+      "Square": {
+        "type": "object",
+        "properties": {
+          "owner": {"type": "string", "nullable": true},
+          "playable": {"type": "boolean", "description": "Whether the requesting player may play here now"},
+          "coordinate": {"$ref": "#/components/schemas/Coordinate"}
+        }
+      },
+      "Grid": {
+        "type": "object",
+        "properties": {
+          "owner": {"type": "string", "nullable": true},
+          "squares": {"type": "array", "description": "Rows of squares, top first", "items": {"type": "array", "items": {"$ref": "#/components/schemas/Square"}}}
+        }
+      },
+      "GameState": {
+        "type": "object",
+        "properties": {
+          "gameID": {"type": "string"},
+          "playerX": {"type": "string"},
+          "playerO": {"type": "string"},
+          "playerXName": {"type": "string"},
+          "playerOName": {"type": "string"},
+          "victor": {"type": "string", "nullable": true},
+          "grids": {"type": "array", "description": "Rows of subgrids, top first", "items": {"type": "array", "items": {"$ref": "#/components/schemas/Grid"}}},
+          "settings": {"$ref": "#/components/schemas/GameSettings"},
+          "rematchOf": {"type": "string", "nullable": true},
+          "rematch": {"type": "string", "nullable": true},
+          "rematchRequestedBy": {"type": "string", "nullable": true},
+          "series": {"type": "object"},
+          "playerXRating": {"allOf": [{"$ref": "#/components/schemas/Rating"}], "nullable": true},
+          "playerORating": {"allOf": [{"$ref": "#/components/schemas/Rating"}], "nullable": true},
+          "spectators": {"type": "integer"},
+          "version": {"type": "integer"}
+        }
+      },
+      "BookPosition": {
+        "type": "object",
+        "properties": {
+          "position": {"type": "string"},
+          "moves": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "move": {"$ref": "#/components/schemas/Coordinate"},
+                "notation": {"type": "string"},
+                "games": {"type": "integer"},
+                "xWins": {"type": "integer"},
+                "oWins": {"type": "integer"},
+                "draws": {"type": "integer"},
+                "winRate": {"type": "number"},
+                "drawRate": {"type": "number"}
+              }
+            }
+          }
+        }
+      },
+      "GameReview": {
+        "type": "object",
+        "properties": {
+          "gameID": {"type": "string"},
+          "status": {"type": "string", "enum": ["pending", "done"]},
+          "moves": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "number": {"type": "integer"},
+                "move": {"$ref": "#/components/schemas/Coordinate"},
+                "class": {"type": "string", "enum": ["best", "good", "inaccuracy", "mistake", "blunder"]},
+                "score": {"type": "integer"},
+                "best": {"$ref": "#/components/schemas/Coordinate"},
+                "bestScore": {"type": "integer"},
+                "pv": {"type": "array", "items": {"$ref": "#/components/schemas/Coordinate"}}
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,162 @@
+// Package book builds and reads opening books: the moves played from
+// each position early in past games, and how those games ended.
+// Positions that are the same but for turning or flipping the board are
+// stored once, under the hash of a canonical form
+package book
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// ErrUnfinished is returned when adding a game without a result
+var ErrUnfinished = errors.New("game has no result")
+
+// Book holds the moves played from each position. A book may be read
+// from concurrently, but not while games are being added to it
+type Book struct {
+	positions map[uint64][]entry
+}
+
+// entry is a move from a position, in the position's canonical form,
+// and the results of the games it was played in
+type entry struct {
+	move  uint8
+	xWins uint32
+	oWins uint32
+	draws uint32
+}
+
+// MoveStats is a move from a position, and the results of the games it
+// was played in
+type MoveStats struct {
+	Move  game.Coordinate `json:"move"`
+	Games int             `json:"games"`
+	XWins int             `json:"xWins"`
+	OWins int             `json:"oWins"`
+	Draws int             `json:"draws"`
+}
+
+// New returns an empty book
+func New() *Book {
+	return &Book{map[uint64][]entry{}}
+}
+
+// Positions returns how many positions are in the book
+func (b *Book) Positions() int {
+	return len(b.positions)
+}
+
+// Add adds the first plies moves of a finished game to the book
+func (b *Book) Add(record game.Record, plies int) error {
+	if record.Result != game.ResultXWins && record.Result != game.ResultOWins && record.Result != game.ResultTie {
+		return ErrUnfinished
+	}
+	_, err := game.Replay("X", "O", record.Moves)
+	if err != nil {
+		return err
+	}
+
+	moves := record.Moves
+	if len(moves) > plies {
+		moves = moves[:plies]
+	}
+
+	g, _ := game.NewGame("X", "O")
+	players := [2]string{"X", "O"}
+	for i, c := range moves {
+		hash, ts := positionKey(g).canonical()
+		b.add(hash, canonicalMove(ts, c), record.Result)
+		g.PlayMove(game.Move{PlayerID: players[i%2], Coordinate: c})
+	}
+	return nil
+}
+
+func (b *Book) add(hash uint64, move uint8, result string) {
+	entries := b.positions[hash]
+	i := 0
+	for ; i < len(entries) && entries[i].move != move; i++ {
+	}
+	if i == len(entries) {
+		entries = append(entries, entry{move: move})
+		b.positions[hash] = entries
+	}
+
+	switch result {
+	case game.ResultXWins:
+		entries[i].xWins++
+	case game.ResultOWins:
+		entries[i].oWins++
+	default:
+		entries[i].draws++
+	}
+}
+
+// Moves returns the moves played from a position, written in position
+// notation, most played first
+func (b *Book) Moves(position string) ([]MoveStats, error) {
+	g, err := game.ParsePosition("X", "O", position)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, ts := positionKey(g).canonical()
+	moves := []MoveStats{}
+	for _, e := range b.positions[hash] {
+		moves = append(moves, MoveStats{
+			Move:  fromCanonical(ts, e.move),
+			Games: int(e.xWins + e.oWins + e.draws),
+			XWins: int(e.xWins),
+			OWins: int(e.oWins),
+			Draws: int(e.draws),
+		})
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Games != moves[j].Games {
+			return moves[i].Games > moves[j].Games
+		}
+		return index(moves[i].Move) < index(moves[j].Move)
+	})
+	return moves, nil
+}
+
+// Pick chooses a move from the book for the player to move, at random
+// but weighted towards the moves that did best for them: each counts two
+// for every win and one for every draw. It returns false if the position
+// isn't in the book, or no move from it ever did better than lose
+func (b *Book) Pick(position string) (game.Coordinate, bool) {
+	g, err := game.ParsePosition("X", "O", position)
+	if err != nil {
+		return game.Coordinate{}, false
+	}
+
+	k := positionKey(g)
+	hash, ts := k.canonical()
+	entries := b.positions[hash]
+	weights := make([]int, len(entries))
+	total := 0
+	for i, e := range entries {
+		wins := e.xWins
+		if k.toMove() == 2 {
+			wins = e.oWins
+		}
+		weights[i] = int(2*wins + e.draws)
+		total += weights[i]
+	}
+	if total == 0 {
+		return game.Coordinate{}, false
+	}
+
+	n := rand.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return fromCanonical(ts, entries[i].move), true
+		}
+		n -= w
+	}
+	panic("unreachable")
+}
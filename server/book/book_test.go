@@ -0,0 +1,121 @@
+package book
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+func record(result string, moves ...string) game.Record {
+	r := game.Record{Result: result}
+	for _, m := range moves {
+		c, err := game.ParseCoordinate(m)
+		if err != nil {
+			panic(err)
+		}
+		r.Moves = append(r.Moves, c)
+	}
+	return r
+}
+
+// won is a game X wins
+var won = record(game.ResultXWins,
+	"A1a1", "A1b1", "B1a1", "A1a3", "A3a3", "A3b2", "B2c1", "C1c2", "C2b3", "B3a1",
+	"A1c1", "C1a1", "A1b3", "B3a3", "A3c3", "C3c1", "C1b2", "B2b2", "B2c2", "C2b2",
+	"B2c3", "C3c3", "C3b1", "B1a3", "A3c2", "C2b1", "B1c3", "C3a3", "A3c1", "C1c1",
+	"C1b3", "B3c1", "C1a3", "C2a2", "A2b3", "B3b1", "B1b2", "A2c3", "C3c2", "C2c1",
+	"C1b1",
+)
+
+func TestSymmetry(t *testing.T) {
+	b := New()
+	err := b.Add(record(game.ResultOWins, "A1a1", "A1b2"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the opposite corner of the opposite subgrid is the same opening,
+	// turned around, and is answered the same way
+	moves, err := b.Moves("9/9/9/9/9/9/9/9/8X C3c3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].Move.String() != "C3b2" || moves[0].OWins != 1 {
+		t.Errorf("got %+v", moves)
+	}
+
+	// the empty board is symmetric, so every corner is the same move
+	b.Add(record(game.ResultTie, "C3c3", "C3b2"), 2)
+	moves, _ = b.Moves(game.StartPosition)
+	if len(moves) != 1 || moves[0].Move.String() != "A1a1" || moves[0].Games != 2 {
+		t.Errorf("got %+v from the start", moves)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	b := New()
+	if err := b.Add(record(game.ResultUnfinished, "B2b2"), 10); err != ErrUnfinished {
+		t.Errorf("got %v adding an unfinished game", err)
+	}
+	if err := b.Add(record(game.ResultTie, "B2b2", "A1a1"), 10); err != game.ErrWrongSubgrid {
+		t.Errorf("got %v adding an illegal game", err)
+	}
+	if b.Positions() != 0 {
+		t.Errorf("got %v positions from rejected games", b.Positions())
+	}
+
+	b.Add(won, 3)
+	b.Add(record(game.ResultOWins, "A1a1", "A1b2"), 3)
+	if b.Positions() != 3 {
+		t.Errorf("got %v positions", b.Positions())
+	}
+
+	moves, _ := b.Moves(game.StartPosition)
+	if len(moves) != 1 || moves[0].Games != 2 || moves[0].XWins != 1 || moves[0].OWins != 1 {
+		t.Errorf("got %+v", moves)
+	}
+
+	// X won after A1b1 and lost after A1b2, so O only picks A1b2
+	for i := 0; i < 20; i++ {
+		c, ok := b.Pick("X8/9/9/9/9/9/9/9/9 A1a1")
+		if !ok || c.String() != "A1b2" {
+			t.Fatalf("picked %v, %v", c, ok)
+		}
+	}
+	if _, ok := b.Pick("9/9/9/9/4X4/9/9/9/9 B2b2"); ok {
+		t.Error("picked a move from a position not in the book")
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	b := New()
+	b.Add(won, 10)
+	b.Add(record(game.ResultTie, "B2b2", "B2a1"), 10)
+
+	buf := &bytes.Buffer{}
+	_, err := b.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != len(magic)+5+12*entrySize {
+		t.Errorf("wrote %v bytes", buf.Len())
+	}
+
+	read, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, position := range []string{game.StartPosition, "9/9/9/9/4X4/9/9/9/9 B2b2"} {
+		want, _ := b.Moves(position)
+		got, _ := read.Moves(position)
+		if len(got) != len(want) || len(got) == 0 || got[0] != want[0] {
+			t.Errorf("%v: got %+v, want %+v", position, got, want)
+		}
+	}
+
+	_, err = Read(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if err != ErrInvalidFormat {
+		t.Errorf("got %v reading a truncated book", err)
+	}
+}
@@ -0,0 +1,113 @@
+package book
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+// ErrInvalidFormat is returned when reading something that isn't a book
+var ErrInvalidFormat = errors.New("invalid book file")
+
+// magic starts every book file, followed by a format version
+const magic = "UTTTBOOK"
+
+const version = 1
+
+// entrySize is the length of an entry on disk: the position's hash, the
+// move's square as numbered in the canonical position, and the number of
+// games X won, O won and drawn after it
+const entrySize = 8 + 1 + 4 + 4 + 4
+
+// WriteTo writes the book out: the magic string and version, the number
+// of entries, then every entry sorted by position hash and move, with
+// all numbers big-endian
+func (b *Book) WriteTo(w io.Writer) (int64, error) {
+	hashes := make([]uint64, 0, len(b.positions))
+	count := 0
+	for hash, entries := range b.positions {
+		hashes = append(hashes, hash)
+		count += len(entries)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	buf := make([]byte, 0, len(magic)+5+count*entrySize)
+	buf = append(buf, magic...)
+	buf = append(buf, version)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(count))
+	for _, hash := range hashes {
+		entries := append([]entry{}, b.positions[hash]...)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].move < entries[j].move })
+		for _, e := range entries {
+			buf = binary.BigEndian.AppendUint64(buf, hash)
+			buf = append(buf, e.move)
+			buf = binary.BigEndian.AppendUint32(buf, e.xWins)
+			buf = binary.BigEndian.AppendUint32(buf, e.oWins)
+			buf = binary.BigEndian.AppendUint32(buf, e.draws)
+		}
+	}
+
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// Read reads a book written by WriteTo
+func Read(r io.Reader) (*Book, error) {
+	header := make([]byte, len(magic)+5)
+	_, err := io.ReadFull(r, header)
+	if err != nil || string(header[:len(magic)]) != magic || header[len(magic)] != version {
+		return nil, ErrInvalidFormat
+	}
+	count := binary.BigEndian.Uint32(header[len(magic)+1:])
+
+	b := New()
+	buf := make([]byte, entrySize)
+	for i := uint32(0); i < count; i++ {
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return nil, ErrInvalidFormat
+		}
+
+		hash := binary.BigEndian.Uint64(buf)
+		e := entry{
+			move:  buf[8],
+			xWins: binary.BigEndian.Uint32(buf[9:]),
+			oWins: binary.BigEndian.Uint32(buf[13:]),
+			draws: binary.BigEndian.Uint32(buf[17:]),
+		}
+		if e.move >= 81 {
+			return nil, ErrInvalidFormat
+		}
+		b.positions[hash] = append(b.positions[hash], e)
+	}
+	return b, nil
+}
+
+// Load reads a book from a file
+func Load(filename string) (*Book, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(bufio.NewReader(f))
+}
+
+// Save writes a book to a file
+func (b *Book) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.WriteTo(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,133 @@
+package book
+
+import (
+	"bytes"
+	"hash/fnv"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// key is a position as its 81 squares, row by row across the whole
+// board, each 0 if empty, 1 for X and 2 for O, followed by the index of
+// the last move's square, or noLastMove
+type key [82]byte
+
+const noLastMove = 81
+
+// index numbers a square by its row and column on the whole board
+func index(c game.Coordinate) int {
+	x := (c.GameSquare.X-1)*3 + c.SubgridSquare.X - 1
+	y := (c.GameSquare.Y-1)*3 + c.SubgridSquare.Y - 1
+	return y*9 + x
+}
+
+func coordinate(i int) game.Coordinate {
+	x, y := i%9, i/9
+	return game.NewCoordinate(x/3+1, y/3+1, x%3+1, y%3+1)
+}
+
+// symmetries are the rotations and reflections of the board. Turning
+// the whole board turns each subgrid the same way, so the square a move
+// is played in still sends the next move to the matching subgrid
+var symmetries = [8]func(x, y int) (int, int){
+	func(x, y int) (int, int) { return x, y },
+	func(x, y int) (int, int) { return 8 - y, x },
+	func(x, y int) (int, int) { return 8 - x, 8 - y },
+	func(x, y int) (int, int) { return y, 8 - x },
+	func(x, y int) (int, int) { return 8 - x, y },
+	func(x, y int) (int, int) { return x, 8 - y },
+	func(x, y int) (int, int) { return y, x },
+	func(x, y int) (int, int) { return 8 - y, 8 - x },
+}
+
+// inverses holds the symmetry undoing each of symmetries
+var inverses = [8]int{0, 3, 2, 1, 4, 5, 6, 7}
+
+// transform applies symmetry t to a square index
+func transform(t, i int) int {
+	x, y := symmetries[t](i%9, i/9)
+	return y*9 + x
+}
+
+// positionKey reads the key of a game's position
+func positionKey(g *game.Game) key {
+	_, _, state, lastTurn := g.SaveGame()
+
+	k := key{}
+	for i := 0; i < 81; i++ {
+		switch state[i] {
+		case 'X':
+			k[i] = 1
+		case 'O':
+			k[i] = 2
+		}
+	}
+	k[81] = noLastMove
+	if lastTurn != nil {
+		k[81] = byte(index(*lastTurn))
+	}
+	return k
+}
+
+func (k key) transform(t int) key {
+	out := key{}
+	for i := 0; i < 81; i++ {
+		out[transform(t, i)] = k[i]
+	}
+	out[81] = k[81]
+	if k[81] != noLastMove {
+		out[81] = byte(transform(t, int(k[81])))
+	}
+	return out
+}
+
+// canonical hashes the least of the position's symmetries, so that
+// positions that are the same but for turning the board share a hash.
+// It also returns the symmetries taking the position to that form, of
+// which there are several if the position is symmetric itself
+func (k key) canonical() (uint64, []int) {
+	least, ts := k, []int{0}
+	for i := 1; i < len(symmetries); i++ {
+		other := k.transform(i)
+		switch bytes.Compare(other[:], least[:]) {
+		case -1:
+			least, ts = other, []int{i}
+		case 0:
+			ts = append(ts, i)
+		}
+	}
+
+	h := fnv.New64a()
+	h.Write(least[:])
+	return h.Sum64(), ts
+}
+
+// canonicalMove numbers a move as played in the canonical form of its
+// position, given the symmetries taking the position there. Moves that
+// are the same in a symmetric position are numbered the same
+func canonicalMove(ts []int, m game.Coordinate) uint8 {
+	least := 81
+	for _, t := range ts {
+		if i := transform(t, index(m)); i < least {
+			least = i
+		}
+	}
+	return uint8(least)
+}
+
+// fromCanonical converts a move in the canonical form of a position
+// back to the position itself
+func fromCanonical(ts []int, m uint8) game.Coordinate {
+	return coordinate(transform(inverses[ts[0]], int(m)))
+}
+
+// toMove returns which side moves in the position
+func (k key) toMove() byte {
+	count := 0
+	for _, square := range k[:81] {
+		if square != 0 {
+			count++
+		}
+	}
+	return byte(count%2) + 1
+}
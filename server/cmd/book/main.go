@@ -0,0 +1,232 @@
+// Command book builds and inspects opening books.
+//
+//	book build [flags] -out <file>       from the server's finished games
+//	book selfplay [flags] -out <file>    from games of the built-in search
+//	book show [-position <p>] <file>     the book moves from a position
+//
+// Either way of building adds the first -plies moves of each game to
+// the book given by -in, if any, before writing it to -out
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/config"
+	"github.com/heartles/uttt/server/engine"
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "build":
+		err = build(os.Args[2:])
+	case "selfplay":
+		err = selfPlay(os.Args[2:])
+	case "show":
+		err = show(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %v build|selfplay|show [flags]\n", os.Args[0])
+	os.Exit(2)
+}
+
+// bookFlags are the flags shared by the ways of building a book
+type bookFlags struct {
+	in    *string
+	out   *string
+	plies *int
+}
+
+func addBookFlags(flags *flag.FlagSet) bookFlags {
+	return bookFlags{
+		in:    flags.String("in", "", "book to add the games to"),
+		out:   flags.String("out", "", "file to write the book to"),
+		plies: flags.Int("plies", 16, "number of moves of each game to add"),
+	}
+}
+
+// open returns the book to add to, after checking the flags
+func (f bookFlags) open(flags *flag.FlagSet) (*book.Book, error) {
+	if *f.out == "" || *f.plies < 1 || flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *f.in == "" {
+		return book.New(), nil
+	}
+	return book.Load(*f.in)
+}
+
+func build(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	db := flags.String("db", cfg.DBFilename, "the server's database")
+	bf := addBookFlags(flags)
+	flags.Parse(args)
+	b, err := bf.open(flags)
+	if err != nil {
+		return err
+	}
+
+	st, err := store.NewStore(*db)
+	if err != nil {
+		return err
+	}
+	records, err := st.FinishedRecords()
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, record := range records {
+		// games that ended some other way than on the board, such as by
+		// resigning, still count
+		if b.Add(record, *bf.plies) == nil {
+			added++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "added %d of %d games, %d positions\n", added, len(records), b.Positions())
+	return b.Save(*bf.out)
+}
+
+func selfPlay(args []string) error {
+	flags := flag.NewFlagSet("selfplay", flag.ExitOnError)
+	games := flags.Int("games", 1000, "number of games to play")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "number of games to play at once")
+	moveTime := flags.Duration("movetime", 50*time.Millisecond, "time the search has per move")
+	openingLength := flags.Int("opening", 2, "number of random moves each game starts with")
+	bf := addBookFlags(flags)
+	flags.Parse(args)
+	b, err := bf.open(flags)
+	if err != nil {
+		return err
+	}
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < *games; i++ {
+			jobs <- i
+		}
+	}()
+
+	records := make(chan game.Record)
+	wg := sync.WaitGroup{}
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				records <- playSelf(*openingLength, *moveTime)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(records)
+	}()
+
+	played := 0
+	for record := range records {
+		err = b.Add(record, *bf.plies)
+		if err != nil {
+			return err
+		}
+		played++
+		if played%100 == 0 {
+			fmt.Fprintf(os.Stderr, "played %d games, %d positions\n", played, b.Positions())
+		}
+	}
+	fmt.Fprintf(os.Stderr, "played %d games, %d positions\n", played, b.Positions())
+	return b.Save(*bf.out)
+}
+
+// playSelf plays a game of the built-in search against itself, after
+// openingLength random moves
+func playSelf(openingLength int, moveTime time.Duration) game.Record {
+	g, _ := game.NewGame("X", "O")
+	players := [2]string{"X", "O"}
+	search := &engine.Builtin{}
+	record := game.Record{}
+
+	for i := 0; !g.IsCompleted(); i++ {
+		var c game.Coordinate
+		if i < openingLength {
+			valid := g.GetValidMoves(players[i%2])
+			c = valid[rand.Intn(len(valid))].Coordinate
+		} else {
+			var err error
+			c, _, err = search.Search(g.Position(), moveTime)
+			if err != nil {
+				panic(err)
+			}
+		}
+		g.PlayMove(game.Move{PlayerID: players[i%2], Coordinate: c})
+		record.Moves = append(record.Moves, c)
+	}
+
+	switch g.GameWinner() {
+	case "X":
+		record.Result = game.ResultXWins
+	case "O":
+		record.Result = game.ResultOWins
+	default:
+		record.Result = game.ResultTie
+	}
+	return record
+}
+
+func show(args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	position := flags.String("position", game.StartPosition, "position to show the moves from")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	b, err := book.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	moves, err := b.Moves(*position)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d positions\n%v\n", b.Positions(), *position)
+	for _, m := range moves {
+		fmt.Printf("%v  %6d games  X %5.1f%%  O %5.1f%%  draw %5.1f%%\n", m.Move, m.Games,
+			percent(m.XWins, m.Games), percent(m.OWins, m.Games), percent(m.Draws, m.Games))
+	}
+	return nil
+}
+
+func percent(n, of int) float64 {
+	return 100 * float64(n) / float64(of)
+}
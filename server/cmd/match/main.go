@@ -0,0 +1,217 @@
+// Command match plays two engines against each other to compare them.
+//
+//	match [flags] <engine> <engine>
+//
+// Each engine is "builtin" for the built-in search, "builtin:<depth>"
+// to limit its depth, or the command line of an engine speaking the
+// protocol described in package engine. The built-in search plays from
+// the opening book given by -book. Games are played in pairs from
+// the same random opening, with the engines swapping sides, and several
+// at once. The games are written out as game records, and the score of
+// the first engine against the second after each game, with the Elo
+// difference and SPRT log-likelihood ratio, to stderr. Unless -sprt is
+// turned off, the match stops early once SPRT accepts either hypothesis
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/engine"
+	"github.com/heartles/uttt/server/game"
+)
+
+// job is a game for a worker to play
+type job struct {
+	round   int
+	opening []game.Coordinate
+	// swapped is set when the first engine plays O
+	swapped bool
+}
+
+// finished is a game a worker has played
+type finished struct {
+	job    job
+	record game.Record
+}
+
+func main() {
+	games := flag.Int("games", 100, "number of games to play")
+	concurrency := flag.Int("concurrency", runtime.NumCPU(), "number of games to play at once")
+	moveTime := flag.Duration("movetime", 100*time.Millisecond, "time each engine has per move")
+	openingLength := flag.Int("opening", 4, "number of random moves each pair of games starts with")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed for the random openings")
+	sprt := flag.Bool("sprt", true, "stop once SPRT accepts either hypothesis")
+	elo0 := flag.Float64("elo0", 0, "Elo difference of SPRT's null hypothesis")
+	elo1 := flag.Float64("elo1", 10, "Elo difference of SPRT's alternative hypothesis")
+	alpha := flag.Float64("alpha", 0.05, "SPRT's false positive rate")
+	beta := flag.Float64("beta", 0.05, "SPRT's false negative rate")
+	out := flag.String("out", "", "file to write the game records to, instead of stdout")
+	bookFilename := flag.String("book", "", "opening book for the built-in search to play from")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %v [flags] <engine> <engine>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 || *games < 1 || *concurrency < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	players := [2]player{{spec: flag.Arg(0)}, {spec: flag.Arg(1)}}
+	if *bookFilename != "" {
+		openings, err := book.Load(*bookFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		players[0].openings, players[1].openings = openings, openings
+	}
+
+	// make sure both engines start before playing anything
+	for _, p := range players {
+		e, err := p.start()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", p.spec, err)
+			os.Exit(1)
+		}
+		e.Close()
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	jobs := make(chan job)
+	stop := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		var openingMoves []game.Coordinate
+		for i := 0; i < *games; i++ {
+			if i%2 == 0 {
+				r := rand.New(rand.NewSource(*seed + int64(i/2)))
+				openingMoves = opening(r, *openingLength)
+			}
+			select {
+			case jobs <- job{round: i + 1, opening: openingMoves, swapped: i%2 == 1}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	results := make(chan finished)
+	wg := sync.WaitGroup{}
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work(players, jobs, results, *moveTime)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	s := score{}
+	lower, upper := sprtBounds(*alpha, *beta)
+	verdict := ""
+	for f := range results {
+		fmt.Fprintln(w, f.record)
+
+		first := [2]string{game.ResultXWins, game.ResultOWins}
+		if f.job.swapped {
+			first[0], first[1] = first[1], first[0]
+		}
+		switch f.record.Result {
+		case first[0]:
+			s.wins++
+		case first[1]:
+			s.losses++
+		default:
+			s.draws++
+		}
+
+		elo, margin := s.elo()
+		llr := s.llr(*elo0, *elo1)
+		fmt.Fprintf(os.Stderr, "Score of %v vs %v: %d - %d - %d [%.3f] %d\n",
+			players[0].spec, players[1].spec, s.wins, s.losses, s.draws, s.ratio(), s.games())
+		fmt.Fprintf(os.Stderr, "Elo difference: %.1f +/- %.1f, LLR: %.2f (%.2f, %.2f) [%g, %g]\n",
+			elo, margin, llr, lower, upper, *elo0, *elo1)
+
+		if *sprt && verdict == "" && (llr <= lower || llr >= upper) {
+			verdict = "H0 accepted"
+			if llr >= upper {
+				verdict = "H1 accepted"
+			}
+			// the games already started are still played and counted
+			close(stop)
+		}
+	}
+
+	if verdict != "" {
+		fmt.Fprintf(os.Stderr, "SPRT: %v\n", verdict)
+	}
+}
+
+// work plays games until there are none left. Engines are started for
+// the first game, and restarted after a game ends with an error, in case
+// it left them unusable
+func work(players [2]player, jobs <-chan job, results chan<- finished, moveTime time.Duration) {
+	engines := [2]engine.Searcher{}
+	defer func() {
+		for _, e := range engines {
+			if e != nil {
+				e.Close()
+			}
+		}
+	}()
+
+	for j := range jobs {
+		for i, e := range engines {
+			if e != nil {
+				continue
+			}
+			e, err := players[i].start()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", players[i].spec, err)
+				os.Exit(1)
+			}
+			engines[i] = e
+		}
+
+		// engines and names are by side, X first
+		sides := engines
+		names := [2]string{players[0].spec, players[1].spec}
+		if j.swapped {
+			sides[0], sides[1] = sides[1], sides[0]
+			names[0], names[1] = names[1], names[0]
+		}
+
+		record := play(j.round, names, sides, j.opening, moveTime)
+		for _, tag := range record.Tags {
+			if tag.Name == "Termination" {
+				for i, e := range engines {
+					e.Close()
+					engines[i] = nil
+				}
+				break
+			}
+		}
+		results <- finished{j, record}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/engine"
+	"github.com/heartles/uttt/server/game"
+)
+
+// player is one of the engines in a match, as given on the command line
+type player struct {
+	spec string
+	// openings is the book the built-in search plays from, if any
+	openings *book.Book
+}
+
+// start launches the engine: "builtin" for the built-in search, or
+// "builtin:<depth>" to limit how deep it searches, and otherwise a
+// command line for an engine process
+func (p player) start() (engine.Searcher, error) {
+	if p.spec == "builtin" {
+		return &engine.Builtin{Book: p.openings}, nil
+	}
+	if strings.HasPrefix(p.spec, "builtin:") {
+		depth, err := strconv.Atoi(strings.TrimPrefix(p.spec, "builtin:"))
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("invalid builtin depth in %q", p.spec)
+		}
+		return &engine.Builtin{Depth: depth, Book: p.openings}, nil
+	}
+
+	fields := strings.Fields(p.spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no engine command given")
+	}
+	return engine.Start(fields[0], fields[1:]...)
+}
+
+// opening plays n random moves from the start, skipping any that end
+// the game
+func opening(r *rand.Rand, n int) []game.Coordinate {
+	g, _ := game.NewGame("X", "O")
+	players := [2]string{"X", "O"}
+	moves := []game.Coordinate{}
+	for i := 0; i < n; i++ {
+		valid := g.GetValidMoves(players[i%2])
+		r.Shuffle(len(valid), func(a, b int) { valid[a], valid[b] = valid[b], valid[a] })
+		for _, m := range valid {
+			next, _ := game.Replay("X", "O", append(moves, m.Coordinate))
+			if !next.IsCompleted() {
+				g = next
+				moves = append(moves, m.Coordinate)
+				break
+			}
+		}
+		if len(moves) != i+1 {
+			break
+		}
+	}
+	return moves
+}
+
+// play plays a game between two engines from an opening, and returns
+// its record. An engine that fails or plays an illegal move loses, and
+// the error is noted in the record's Termination tag
+func play(round int, names [2]string, engines [2]engine.Searcher, openingMoves []game.Coordinate, moveTime time.Duration) game.Record {
+	record := game.Record{
+		Tags: []game.Tag{
+			{Name: "Event", Value: "Engine match"},
+			{Name: "Round", Value: strconv.Itoa(round)},
+			{Name: "Date", Value: time.Now().UTC().Format("2006.01.02")},
+			{Name: "X", Value: names[0]},
+			{Name: "O", Value: names[1]},
+		},
+		Moves: append([]game.Coordinate{}, openingMoves...),
+	}
+	results := [2]string{game.ResultXWins, game.ResultOWins}
+	players := [2]string{"X", "O"}
+
+	g, err := game.Replay("X", "O", openingMoves)
+	if err != nil {
+		panic(err)
+	}
+	record.Tags = append(record.Tags, game.Tag{Name: "Opening", Value: g.Position()})
+
+	// forfeit ends the game with a loss for the engine playing turn
+	forfeit := func(turn int, err error) {
+		record.Result = results[1-turn]
+		record.Tags = append(record.Tags,
+			game.Tag{Name: "Termination", Value: fmt.Sprintf("%v: %v", names[turn], err)})
+	}
+
+	for turn, e := range engines {
+		err := e.NewGame()
+		if err != nil {
+			forfeit(turn, err)
+			break
+		}
+	}
+
+	for turn := len(openingMoves) % 2; record.Result == "" && !g.IsCompleted(); turn = 1 - turn {
+		c, _, err := engines[turn].Search(g.Position(), moveTime)
+		if err != nil {
+			forfeit(turn, err)
+			break
+		}
+		err = g.PlayMove(game.Move{PlayerID: players[turn], Coordinate: c})
+		if err != nil {
+			forfeit(turn, fmt.Errorf("illegal move %v: %v", c, err))
+			break
+		}
+		record.Moves = append(record.Moves, c)
+	}
+
+	switch g.GameWinner() {
+	case "X":
+		record.Result = game.ResultXWins
+	case "O":
+		record.Result = game.ResultOWins
+	case game.StalematePlayer:
+		record.Result = game.ResultTie
+	}
+	record.Tags = append(record.Tags, game.Tag{Name: "Result", Value: record.Result})
+	return record
+}
@@ -0,0 +1,82 @@
+package main
+
+import "math"
+
+// score is the first engine's results in a match
+type score struct {
+	wins   int
+	draws  int
+	losses int
+}
+
+func (s score) games() int {
+	return s.wins + s.draws + s.losses
+}
+
+// ratio is the share of points won, counting draws as half a point
+func (s score) ratio() float64 {
+	return (float64(s.wins) + float64(s.draws)/2) / float64(s.games())
+}
+
+// variance is the variance of a single game's points
+func (s score) variance() float64 {
+	r := s.ratio()
+	return (float64(s.wins)*(1-r)*(1-r) + float64(s.draws)*(0.5-r)*(0.5-r) +
+		float64(s.losses)*r*r) / float64(s.games())
+}
+
+// eloFromRatio is the Elo difference at which a player is expected to
+// score r
+func eloFromRatio(r float64) float64 {
+	return -400 * math.Log10(1/r-1)
+}
+
+// ratioFromElo is the score expected of a player rated elo above their
+// opponent
+func ratioFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// elo estimates the Elo difference between the engines, and the margin
+// of its 95% confidence interval
+func (s score) elo() (float64, float64) {
+	if s.games() == 0 {
+		return 0, math.Inf(1)
+	}
+
+	r := s.ratio()
+	if r == 0 || r == 1 {
+		return eloFromRatio(r), math.Inf(1)
+	}
+	deviation := math.Sqrt(s.variance() / float64(s.games()))
+	low := eloFromRatio(math.Max(r-1.96*deviation, 0))
+	high := eloFromRatio(math.Min(r+1.96*deviation, 1))
+	return eloFromRatio(r), (high - low) / 2
+}
+
+// llr is the log-likelihood ratio of the first engine being elo1 better
+// rather than elo0 better, approximating the game results as normally
+// distributed. Games that all ended the same way have no variance to go
+// on, so one more draw is assumed for it
+func (s score) llr(elo0, elo1 float64) float64 {
+	if s.games() == 0 {
+		return 0
+	}
+	variance := s.variance()
+	if variance == 0 {
+		variance = score{s.wins, s.draws + 1, s.losses}.variance()
+	}
+	if variance == 0 {
+		return 0
+	}
+
+	s0, s1 := ratioFromElo(elo0), ratioFromElo(elo1)
+	return (s1 - s0) * (2*s.ratio() - s0 - s1) * float64(s.games()) / (2 * variance)
+}
+
+// sprtBounds returns the log-likelihood ratios below which the first
+// hypothesis is accepted and above which the second is, for false
+// positive rate alpha and false negative rate beta
+func sprtBounds(alpha, beta float64) (float64, float64) {
+	return math.Log(beta / (1 - alpha)), math.Log((1 - beta) / alpha)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func TestElo(t *testing.T) {
+	tests := []struct {
+		score       score
+		elo, margin float64
+	}{
+		{score{wins: 50, losses: 50}, 0, 68.99},
+		{score{wins: 60, draws: 20, losses: 20}, 147.19, 66.01},
+		{score{draws: 10}, 0, 0},
+	}
+
+	for _, test := range tests {
+		elo, margin := test.score.elo()
+		if !near(elo, test.elo) || !near(margin, test.margin) {
+			t.Errorf("%+v: got %.2f +/- %.2f, want %.2f +/- %.2f",
+				test.score, elo, margin, test.elo, test.margin)
+		}
+	}
+
+	if elo, margin := (score{wins: 3}).elo(); !math.IsInf(elo, 1) || !math.IsInf(margin, 1) {
+		t.Errorf("got %v +/- %v for a clean sweep", elo, margin)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	lower, upper := sprtBounds(0.05, 0.05)
+	if !near(lower, -2.94) || !near(upper, 2.94) {
+		t.Errorf("got bounds %.2f, %.2f", lower, upper)
+	}
+
+	if llr := (score{wins: 300, draws: 400, losses: 300}).llr(0, 10); llr >= 0 {
+		t.Errorf("got %.2f for an even match, want below 0", llr)
+	}
+	if llr := (score{wins: 400, draws: 400, losses: 200}).llr(0, 10); llr <= upper {
+		t.Errorf("got %.2f for a lopsided match, want above %.2f", llr, upper)
+	}
+	if llr := (score{wins: 20}).llr(0, 10); llr <= upper {
+		t.Errorf("got %.2f for a clean sweep, want above %.2f", llr, upper)
+	}
+	if llr := (score{}).llr(0, 10); llr != 0 {
+		t.Errorf("got %.2f with no games", llr)
+	}
+}
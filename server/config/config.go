@@ -3,6 +3,7 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -11,6 +12,11 @@ type Config struct {
 	Port int
 	Host string
 
+	// GRPCPort is the port the gRPC API listens on. 0, the default,
+	// disables it. gRPC is served without TLS, since AcmeTLS only
+	// covers the HTTP server, so API keys are sent in the clear
+	GRPCPort int
+
 	// if true, the server will attempt to obtain a
 	// TLS cert from Let's Encrypt. The server will run
 	// without TLS otherwise.
@@ -29,6 +35,49 @@ type Config struct {
 	CheckOrigin bool
 
 	DBFilename string
+
+	// websocket clients are pinged every PingInterval, and
+	// disconnected if nothing, pongs included, is heard from them
+	// for PongTimeout
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+
+	// WriteTimeout is how long a write to a websocket client may take
+	// before the client is considered dead
+	WriteTimeout time.Duration
+
+	// IdleTimeout ends the sessions of clients that haven't sent a
+	// message for this long. 0 disables it
+	IdleTimeout time.Duration
+
+	// Bots are engines the server plays as bot accounts
+	Bots []BotConfig
+
+	// BookFilename is the opening book served by the explorer, and
+	// played from by bots using the built-in search. Empty disables it
+	BookFilename string
+
+	// AnalysisMoveTime is how long the built-in search may analyse a
+	// position for a player. 0 disables analysis
+	AnalysisMoveTime time.Duration
+
+	// ReviewWorkers is how many finished games are reviewed by the
+	// built-in search at once, each move searched for ReviewMoveTime.
+	// 0 disables reviews
+	ReviewWorkers  int
+	ReviewMoveTime time.Duration
+}
+
+// BotConfig describes an engine to play as a bot account
+type BotConfig struct {
+	// Username is the bot's account, created if it doesn't exist
+	Username string
+	// Command and Args launch the engine. A Command of "builtin"
+	// plays the built-in search instead
+	Command string
+	Args    []string
+	// MoveTime is how long the engine may think about each move
+	MoveTime time.Duration
 }
 
 // defaultConfig defines a config suitable for local development
@@ -37,11 +86,21 @@ type Config struct {
 var defaultConfig Config = Config{
 	Port:        8080,
 	Host:        "localhost",
+	GRPCPort:    0,
 	AcmeTLS:     false,
 	VerifyUser:  false,
 	RequestLogs: true,
 	CheckOrigin: false,
 	DBFilename:  "./games.db",
+
+	PingInterval: 30 * time.Second,
+	PongTimeout:  60 * time.Second,
+	WriteTimeout: 10 * time.Second,
+	IdleTimeout:  30 * time.Minute,
+
+	AnalysisMoveTime: time.Second,
+	ReviewWorkers:    1,
+	ReviewMoveTime:   200 * time.Millisecond,
 }
 
 // Load returns the configuration for the server to
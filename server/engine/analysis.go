@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// Line is one of the moves found by an analysis, with what the search
+// expects to follow it
+type Line struct {
+	Move game.Coordinate `json:"move"`
+	// Score is from the point of view of the player to move, positive
+	// when the move favours them
+	Score int `json:"score"`
+	// WinIn is the number of moves, this one included, until the game
+	// is won if the score is a known win, or lost if negative. It is
+	// 0 while the result isn't known
+	WinIn int               `json:"winIn,omitempty"`
+	PV    []game.Coordinate `json:"pv"`
+}
+
+// Analysis is the best moves of a position, best first
+type Analysis struct {
+	Position string `json:"position"`
+	// Depth is how many moves ahead the lines were searched
+	Depth int    `json:"depth"`
+	Nodes int    `json:"nodes"`
+	Lines []Line `json:"lines"`
+}
+
+// Analyze searches the position like Search, but finds the best lines
+// moves rather than just the best one. The book isn't used, since it has
+// no scores
+func (b *Builtin) Analyze(position string, lines int, moveTime time.Duration) (*Analysis, error) {
+	pos, err := parseBoard(position)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := pos.moves()
+	if len(moves) == 0 {
+		return nil, game.ErrGameOver
+	}
+	if lines < 1 {
+		lines = 1
+	}
+
+	depth, nodes, best := b.analyze(pos, moves, lines, moveTime)
+	analysis := &Analysis{Position: position, Depth: depth, Nodes: nodes}
+	for _, l := range best {
+		line := Line{Move: l.pv[0].coordinate(), Score: l.score, PV: coordinates(l.pv)}
+		if l.score >= winScore-maxDepth {
+			line.WinIn = winScore - l.score - pos.played()
+		} else if l.score <= -winScore+maxDepth {
+			line.WinIn = -(winScore + l.score - pos.played())
+		}
+		analysis.Lines = append(analysis.Lines, line)
+	}
+	return analysis, nil
+}
+
+// analyze deepens a search for the best lines of pos's moves until
+// moveTime runs out or Depth is reached, and returns the lines of the
+// deepest search to finish, how deep that was and the nodes searched
+func (b *Builtin) analyze(pos board, moves []move, lines int, moveTime time.Duration) (int, int, []scoredLine) {
+	depth := b.Depth
+	if depth <= 0 || depth > maxDepth {
+		depth = maxDepth
+	}
+
+	s := &search{deadline: time.Now().Add(moveTime)}
+	searched := 0
+	var best []scoredLine
+	for d := 1; d <= depth; d++ {
+		found, done := s.bestLines(pos, moves, d, lines)
+		if !done {
+			break
+		}
+
+		best = found
+		searched = d
+		// the next depth searches the best moves first, so that the
+		// rest are cut off sooner
+		moves = rank(moves, best)
+
+		decided := true
+		for _, l := range best {
+			if !decisive(l.score) {
+				decided = false
+			}
+		}
+		if decided || d >= 81-pos.played() {
+			break
+		}
+	}
+	return searched, s.nodes, best
+}
+
+// decisive reports whether a score is a known win or loss
+func decisive(score int) bool {
+	return score >= winScore-maxDepth || score <= -winScore+maxDepth
+}
+
+func coordinates(moves []move) []game.Coordinate {
+	cs := []game.Coordinate{}
+	for _, m := range moves {
+		cs = append(cs, m.coordinate())
+	}
+	return cs
+}
+
+type scoredLine struct {
+	score int
+	pv    []move
+}
+
+// bestLines searches each move depth moves ahead, returning the best n
+// of them, best first. Only a move that could make the best n is
+// searched exactly; the others are cut off as soon as they can't. done
+// is false if the search ran out of time
+func (s *search) bestLines(pos board, moves []move, depth, n int) ([]scoredLine, bool) {
+	best := []scoredLine{}
+	for _, m := range moves {
+		alpha := -winScore - 1
+		if len(best) == n {
+			alpha = best[n-1].score
+		}
+
+		score, line := s.negamax(pos.play(m), depth-1, -winScore-1, -alpha, noMove)
+		score = -score
+		if s.stopped {
+			return nil, false
+		}
+		if score <= alpha {
+			continue
+		}
+
+		i := sort.Search(len(best), func(i int) bool { return best[i].score < score })
+		best = append(best, scoredLine{})
+		copy(best[i+1:], best[i:])
+		best[i] = scoredLine{score: score, pv: append([]move{m}, line...)}
+		if len(best) > n {
+			best = best[:n]
+		}
+	}
+	return best, true
+}
+
+// rank orders moves with those of best first, in the same order
+func rank(moves []move, best []scoredLine) []move {
+	ranked := make([]move, 0, len(moves))
+	first := map[move]bool{}
+	for _, l := range best {
+		ranked = append(ranked, l.pv[0])
+		first[l.pv[0]] = true
+	}
+	for _, m := range moves {
+		if !first[m] {
+			ranked = append(ranked, m)
+		}
+	}
+	return ranked
+}
@@ -0,0 +1,215 @@
+package engine
+
+import "github.com/heartles/uttt/server/game"
+
+// owner is who holds a square, subgrid or the game
+type owner int8
+
+const (
+	none owner = iota
+	x
+	o
+	tie
+)
+
+func (p owner) opponent() owner {
+	if p == x {
+		return o
+	}
+	return x
+}
+
+// move is a square as an index into board.squares: the subgrid's index
+// times 9 plus the square's, where both count row by row from the top
+// left
+type move int8
+
+// noMove is a move that is never played
+const noMove move = -1
+
+func fromCoordinate(c game.Coordinate) move {
+	grid := (c.GameSquare.Y-1)*3 + c.GameSquare.X - 1
+	square := (c.SubgridSquare.Y-1)*3 + c.SubgridSquare.X - 1
+	return move(grid*9 + square)
+}
+
+func (m move) coordinate() game.Coordinate {
+	grid, square := int(m)/9, int(m)%9
+	return game.NewCoordinate(grid%3+1, grid/3+1, square%3+1, square/3+1)
+}
+
+// lines are the indexes of each three in a row on a 3x3 grid
+var lines = [8][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8},
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8},
+	{0, 4, 8}, {2, 4, 6},
+}
+
+// board is a position in a form that is quick to search. It is small
+// enough to be copied rather than undoing moves
+type board struct {
+	squares [81]owner
+	// grids holds who won each subgrid, or tie once one is full
+	grids  [9]owner
+	winner owner
+	toMove owner
+	last   move
+	count  int
+}
+
+// parseBoard reads a position written in position notation
+func parseBoard(position string) (board, error) {
+	g, err := game.ParsePosition("X", "O", position)
+	if err != nil {
+		return board{}, err
+	}
+
+	b := board{toMove: x, last: noMove}
+	for i := range b.squares {
+		switch p, _ := g.SquareOwner(move(i).coordinate()); p {
+		case "X":
+			b.squares[i] = x
+			b.count++
+		case "O":
+			b.squares[i] = o
+			b.count++
+		}
+	}
+	if b.count%2 == 1 {
+		b.toMove = o
+	}
+	if _, _, _, lastTurn := g.SaveGame(); lastTurn != nil {
+		b.last = fromCoordinate(*lastTurn)
+	}
+
+	for grid := range b.grids {
+		b.grids[grid] = result(b.squares[grid*9 : grid*9+9])
+	}
+	b.winner = result(b.grids[:])
+	return b, nil
+}
+
+// result returns who has three in a row on a 3x3 grid, tie if nobody
+// does and it is full, and none otherwise
+func result(grid []owner) owner {
+	for _, line := range lines {
+		p := grid[line[0]]
+		if p != none && p != tie && p == grid[line[1]] && p == grid[line[2]] {
+			return p
+		}
+	}
+	for _, p := range grid {
+		if p == none {
+			return none
+		}
+	}
+	return tie
+}
+
+// played returns how many moves have been played
+func (b *board) played() int {
+	return b.count
+}
+
+// moves returns the legal moves, following the rules of game.Game: a
+// move must be in the subgrid matching the last move's square, unless
+// that subgrid is finished, in which case any open subgrid will do
+func (b *board) moves() []move {
+	if b.winner != none {
+		return nil
+	}
+
+	moves := make([]move, 0, 81)
+	add := func(grid int) {
+		for square := 0; square < 9; square++ {
+			if b.squares[grid*9+square] == none {
+				moves = append(moves, move(grid*9+square))
+			}
+		}
+	}
+
+	if b.last != noMove {
+		if next := int(b.last) % 9; b.grids[next] == none {
+			add(next)
+			return moves
+		}
+	}
+	for grid := range b.grids {
+		if b.grids[grid] == none {
+			add(grid)
+		}
+	}
+	return moves
+}
+
+// play returns the position after the player to move plays m, which
+// must be legal
+func (b board) play(m move) board {
+	grid := int(m) / 9
+	b.squares[m] = b.toMove
+	if b.grids[grid] = result(b.squares[grid*9 : grid*9+9]); b.grids[grid] != none {
+		b.winner = result(b.grids[:])
+	}
+	b.toMove = b.toMove.opponent()
+	b.last = m
+	b.count++
+	return b
+}
+
+// Evaluation weights, in the same units as winScore
+const (
+	gridWeight       = 100
+	centerGridWeight = 50
+	openPairWeight   = 10
+	gridPairWeight   = 200
+)
+
+// evaluate scores a position that isn't over from the point of view of
+// the player to move. Won subgrids count most, then subgrids that would
+// complete a line of them, then two in a row within open subgrids
+func (b *board) evaluate() int {
+	score := 0
+	for grid, p := range b.grids {
+		switch p {
+		case x:
+			score += gridWeight
+		case o:
+			score -= gridWeight
+		case none:
+			score += pairs(b.squares[grid*9:grid*9+9]) * openPairWeight
+		}
+	}
+	switch b.grids[4] {
+	case x:
+		score += centerGridWeight
+	case o:
+		score -= centerGridWeight
+	}
+	score += pairs(b.grids[:]) * gridPairWeight
+
+	if b.toMove == o {
+		return -score
+	}
+	return score
+}
+
+// pairs counts the lines of a 3x3 grid where X has two and the third is
+// still open, less those where O does
+func pairs(grid []owner) int {
+	n := 0
+	for _, line := range lines {
+		counts := [4]int{}
+		for _, i := range line {
+			counts[grid[i]]++
+		}
+		if counts[none] != 1 {
+			continue
+		}
+		if counts[x] == 2 {
+			n++
+		} else if counts[o] == 2 {
+			n--
+		}
+	}
+	return n
+}
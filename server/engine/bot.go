@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/config"
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// defaultMoveTime is how long engines think about each move when their
+// bot's config doesn't say
+const defaultMoveTime = time.Second
+
+// Bot plays an engine as a bot account, in every game started against
+// the account
+type Bot struct {
+	games  *store.GameService
+	player *store.Player
+	config config.BotConfig
+	book   *book.Book
+
+	// engine is nil until started, and after it fails, so that it is
+	// restarted for the next move
+	engine Searcher
+	// lastGame is the game the engine last searched a position of
+	lastGame string
+}
+
+// BuiltinCommand is the command of bots that play the built-in search
+const BuiltinCommand = "builtin"
+
+// NewBot returns a bot playing c's engine, creating its account if
+// needed. The engine is started once the bot has a move to play. Bots
+// playing the built-in search play from openings, if it isn't nil
+func NewBot(games *store.GameService, c config.BotConfig, openings *book.Book) (*Bot, error) {
+	player, err := games.CreateBot(c.Username)
+	if err != nil {
+		return nil, err
+	}
+	if c.MoveTime <= 0 {
+		c.MoveTime = defaultMoveTime
+	}
+
+	return &Bot{games: games, player: player, config: c, book: openings}, nil
+}
+
+// PlayerID returns the ID of the bot's account
+func (b *Bot) PlayerID() string {
+	return b.player.UUID
+}
+
+// Run plays the bot's turn in each of its games as they come up, until
+// ctx is canceled
+func (b *Bot) Run(ctx context.Context) error {
+	defer func() {
+		if b.engine != nil {
+			b.engine.Close()
+		}
+	}()
+
+	return b.games.FollowPlayerGames(ctx, b.player.UUID, func(g *store.Game) error {
+		b.play(g)
+		return nil
+	})
+}
+
+// play plays a move in the game if it is the bot's turn. If the engine
+// fails or picks an illegal move, a random legal move is played instead
+// so that the game isn't held up
+func (b *Bot) play(g *store.Game) {
+	moves := g.ValidMoves(b.player.UUID)
+	if len(moves) == 0 {
+		return
+	}
+
+	c, err := b.search(g)
+	if err != nil {
+		fmt.Printf("bot %v: %v\n", b.config.Username, err)
+		if b.engine != nil {
+			b.engine.Close()
+			b.engine = nil
+		}
+	}
+
+	move, legal := game.Move{}, false
+	for _, m := range moves {
+		if err == nil && m.Coordinate == c {
+			move, legal = m, true
+		}
+	}
+	if err == nil && !legal {
+		fmt.Printf("bot %v: engine chose illegal move %v\n", b.config.Username, c)
+	}
+	if !legal {
+		move = moves[rand.Intn(len(moves))]
+	}
+
+	err = g.PlayMove(move)
+	if err != nil {
+		fmt.Printf("bot %v: %v\n", b.config.Username, err)
+	}
+}
+
+// search asks the engine for its move in the game, starting the engine
+// if needed
+func (b *Bot) search(g *store.Game) (game.Coordinate, error) {
+	if b.engine == nil {
+		e, err := b.start()
+		if err != nil {
+			return game.Coordinate{}, err
+		}
+		b.engine = e
+		b.lastGame = ""
+	}
+
+	if b.lastGame != g.UUID() {
+		err := b.engine.NewGame()
+		if err != nil {
+			return game.Coordinate{}, err
+		}
+		b.lastGame = g.UUID()
+	}
+
+	c, _, err := b.engine.Search(g.Position(), b.config.MoveTime)
+	return c, err
+}
+
+// start starts the bot's engine
+func (b *Bot) start() (Searcher, error) {
+	if b.config.Command == BuiltinCommand {
+		return &Builtin{Book: b.book}, nil
+	}
+	return Start(b.config.Command, b.config.Args...)
+}
@@ -0,0 +1,334 @@
+// Package engine runs game engines written in any language as
+// subprocesses, and plays them as bot accounts. It also has a built-in
+// alpha-beta search, Builtin, which can stand in for an engine process.
+//
+// Engines speak a line-based protocol over stdin and stdout, modeled on
+// UCI. The server sends:
+//
+//	uttt                  start of the session
+//	isready               wait for the engine to finish what it's doing
+//	newgame               the next position is from a different game
+//	position <position>   set the position, in game.Position's notation
+//	go movetime <ms>      search the position for at most <ms>
+//	stop                  answer the current search now
+//	quit                  exit
+//
+// and the engine answers:
+//
+//	id name <name>        optional, before uttt's answer
+//	id author <author>    optional, before uttt's answer
+//	utttok                answers uttt
+//	readyok               answers isready
+//	info <fields>         optional, during a search
+//	bestmove <move>       answers go, in move notation
+//
+// info lines are made of any of "depth <n>", "score <n>" from the
+// point of view of the player to move, "nodes <n>", "time <ms>", then
+// "pv <moves>" or "string <text>", either of which takes up the rest of
+// the line. Lines the engine doesn't understand should be ignored, and
+// lines the server doesn't understand are
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// ErrTimeout is returned when an engine doesn't answer in time. An
+// engine that doesn't stop searching when told to is killed
+var ErrTimeout = errors.New("engine timed out")
+
+// ErrExited is returned when the engine process has exited
+var ErrExited = errors.New("engine exited")
+
+// ErrInvalidMove is returned when an engine's best move can't be read
+var ErrInvalidMove = errors.New("engine sent an invalid move")
+
+// handshakeTimeout is how long an engine has to answer uttt and
+// isready
+const handshakeTimeout = 10 * time.Second
+
+// stopGrace is how long past its time limit an engine has to answer
+// before it is told to stop, and then how long it has to stop before
+// it is killed
+const stopGrace = time.Second
+
+// Engine is a running engine process. Its methods must not be called
+// concurrently
+type Engine struct {
+	// Name and Author are what the engine identified itself as
+	Name   string
+	Author string
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// lines receives the engine's output, and is closed once it exits
+	lines <-chan string
+	// exited is set once the engine has been killed or has exited, and
+	// closed is closed then, so that its output is no longer read
+	exited bool
+	closed chan struct{}
+}
+
+// Info is what an engine reported about its search
+type Info struct {
+	Depth  int
+	Score  int
+	Nodes  int
+	Time   time.Duration
+	PV     []game.Coordinate
+	String string
+}
+
+// Start launches an engine and waits for it to be ready
+func Start(command string, args ...string) (*Engine, error) {
+	cmd := exec.Command(command, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(chan string)
+	e := &Engine{cmd: cmd, stdin: stdin, lines: lines, closed: make(chan struct{})}
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-e.closed:
+				return
+			}
+		}
+	}()
+
+	err = e.handshake()
+	if err != nil {
+		e.kill()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) handshake() error {
+	err := e.send("uttt")
+	if err != nil {
+		return err
+	}
+
+	deadline := time.After(handshakeTimeout)
+	err = e.readUntil(deadline, func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "id" {
+			switch fields[1] {
+			case "name":
+				e.Name = strings.Join(fields[2:], " ")
+			case "author":
+				e.Author = strings.Join(fields[2:], " ")
+			}
+		}
+		return line == "utttok"
+	})
+	if err != nil {
+		return err
+	}
+
+	return e.ready(deadline)
+}
+
+// ready waits for the engine to answer isready
+func (e *Engine) ready(deadline <-chan time.Time) error {
+	err := e.send("isready")
+	if err != nil {
+		return err
+	}
+	return e.readUntil(deadline, func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+func (e *Engine) send(format string, args ...interface{}) error {
+	if e.exited {
+		return ErrExited
+	}
+
+	_, err := fmt.Fprintf(e.stdin, format+"\n", args...)
+	if err != nil {
+		return ErrExited
+	}
+	return nil
+}
+
+// readUntil reads lines from the engine until done returns true for
+// one, or the deadline passes
+func (e *Engine) readUntil(deadline <-chan time.Time, done func(line string) bool) error {
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				e.kill()
+				return ErrExited
+			}
+			if done(strings.TrimSpace(line)) {
+				return nil
+			}
+		case <-deadline:
+			return ErrTimeout
+		}
+	}
+}
+
+// NewGame tells the engine that the next position is from a different
+// game than the last
+func (e *Engine) NewGame() error {
+	err := e.send("newgame")
+	if err != nil {
+		return err
+	}
+	return e.ready(time.After(handshakeTimeout))
+}
+
+// Search asks the engine for its best move in a position, written in
+// game.Position's notation, giving it moveTime to think. It returns the
+// move and the info lines the engine sent along the way. An engine that
+// runs over is told to stop, and killed if it still doesn't answer
+func (e *Engine) Search(position string, moveTime time.Duration) (game.Coordinate, []Info, error) {
+	err := e.send("position %v", position)
+	if err != nil {
+		return game.Coordinate{}, nil, err
+	}
+	err = e.send("go movetime %d", moveTime.Nanoseconds()/int64(time.Millisecond))
+	if err != nil {
+		return game.Coordinate{}, nil, err
+	}
+
+	infos := []Info{}
+	var bestMove string
+	read := func(line string) bool {
+		if strings.HasPrefix(line, "info ") {
+			infos = append(infos, parseInfo(line))
+		} else if strings.HasPrefix(line, "bestmove ") {
+			bestMove = strings.TrimSpace(strings.TrimPrefix(line, "bestmove "))
+			return true
+		}
+		return false
+	}
+
+	err = e.readUntil(time.After(moveTime+stopGrace), read)
+	if err == ErrTimeout {
+		err = e.send("stop")
+		if err == nil {
+			err = e.readUntil(time.After(stopGrace), read)
+		}
+		if err == ErrTimeout {
+			e.kill()
+		}
+	}
+	if err != nil {
+		return game.Coordinate{}, infos, err
+	}
+
+	move, err := game.ParseCoordinate(bestMove)
+	if err != nil {
+		return game.Coordinate{}, infos, ErrInvalidMove
+	}
+	return move, infos, nil
+}
+
+// parseInfo reads an info line. Fields that can't be read are skipped
+func parseInfo(line string) Info {
+	info := Info{}
+	fields := strings.Fields(line)[1:]
+	for i := 0; i < len(fields); i++ {
+		key := fields[i]
+		switch key {
+		case "pv":
+			for _, m := range fields[i+1:] {
+				c, err := game.ParseCoordinate(m)
+				if err != nil {
+					break
+				}
+				info.PV = append(info.PV, c)
+			}
+			return info
+		case "string":
+			info.String = strings.Join(fields[i+1:], " ")
+			return info
+		}
+
+		if i+1 == len(fields) {
+			break
+		}
+		n, err := strconv.Atoi(fields[i+1])
+		if err != nil {
+			continue
+		}
+		i++
+
+		switch key {
+		case "depth":
+			info.Depth = n
+		case "score":
+			info.Score = n
+		case "nodes":
+			info.Nodes = n
+		case "time":
+			info.Time = time.Duration(n) * time.Millisecond
+		}
+	}
+	return info
+}
+
+// exit marks the engine as gone, and stops reading its output
+func (e *Engine) exit() {
+	if !e.exited {
+		e.exited = true
+		close(e.closed)
+	}
+}
+
+// kill ends the engine process without waiting for it
+func (e *Engine) kill() {
+	e.exit()
+	e.cmd.Process.Kill()
+	go e.cmd.Wait()
+}
+
+// Close asks the engine to quit, and kills it if it doesn't
+func (e *Engine) Close() error {
+	if e.exited {
+		return nil
+	}
+
+	e.send("quit")
+	e.stdin.Close()
+	e.exit()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- e.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(stopGrace):
+		return e.cmd.Process.Kill()
+	}
+}
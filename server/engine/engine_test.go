@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heartles/uttt/server/config"
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// The test binary doubles as a fake engine, run with
+// UTTT_FAKE_ENGINE set to how it should behave: "fast" answers go
+// right away, "slow" only once told to stop, and "hang" never
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("UTTT_FAKE_ENGINE"); mode != "" {
+		fakeEngine(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func fakeEngine(mode string) {
+	position := game.StartPosition
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uttt":
+			fmt.Println("id name Fake Engine")
+			fmt.Println("utttok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			position = strings.Join(fields[1:], " ")
+		case "go":
+			if mode == "fast" {
+				answer(position)
+			}
+		case "stop":
+			if mode == "slow" {
+				answer(position)
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+// answer plays the first legal move
+func answer(position string) {
+	g, err := game.ParsePosition("x", "o", position)
+	if err != nil {
+		fmt.Println("bestmove none")
+		return
+	}
+
+	moves := append(g.GetValidMoves("x"), g.GetValidMoves("o")...)
+	fmt.Printf("info depth 1 score 5 nodes %d pv %v\n", len(moves), moves[0].Coordinate)
+	fmt.Printf("bestmove %v\n", moves[0].Coordinate)
+}
+
+func startFake(t *testing.T, mode string) *Engine {
+	t.Setenv("UTTT_FAKE_ENGINE", mode)
+	e, err := Start(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestSearch(t *testing.T) {
+	e := startFake(t, "fast")
+	if e.Name != "Fake Engine" {
+		t.Errorf("got name %q", e.Name)
+	}
+
+	move, infos, err := e.Search("9/9/9/9/4X4/9/9/9/9 B2b2", time.Second)
+	want := game.NewCoordinate(2, 2, 1, 1)
+	if err != nil || move != want {
+		t.Fatalf("got %v, %v", move, err)
+	}
+	if len(infos) != 1 || infos[0].Depth != 1 || infos[0].Score != 5 || infos[0].Nodes != 8 ||
+		!reflect.DeepEqual(infos[0].PV, []game.Coordinate{want}) {
+		t.Errorf("got info %+v", infos)
+	}
+}
+
+func TestSearchStop(t *testing.T) {
+	e := startFake(t, "slow")
+
+	_, _, err := e.Search(game.StartPosition, 10*time.Millisecond)
+	if err != nil {
+		t.Errorf("got %v from an engine that stops when told", err)
+	}
+}
+
+func TestSearchTimeout(t *testing.T) {
+	e := startFake(t, "hang")
+
+	_, _, err := e.Search(game.StartPosition, 10*time.Millisecond)
+	if err != ErrTimeout {
+		t.Fatalf("got %v from an engine that never answers", err)
+	}
+
+	_, _, err = e.Search(game.StartPosition, 10*time.Millisecond)
+	if err != ErrExited {
+		t.Errorf("got %v searching with a killed engine", err)
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		line string
+		info Info
+	}{
+		{"info depth 3 score -20 nodes 100 time 15", Info{Depth: 3, Score: -20, Nodes: 100, Time: 15 * time.Millisecond}},
+		{"info depth 2 seldepth 4 pv B2b2 B2a1", Info{Depth: 2, PV: []game.Coordinate{
+			game.NewCoordinate(2, 2, 2, 2), game.NewCoordinate(2, 2, 1, 1),
+		}}},
+		{"info string thinking hard", Info{String: "thinking hard"}},
+		{"info depth deep score", Info{}},
+	}
+
+	for _, test := range tests {
+		if info := parseInfo(test.line); !reflect.DeepEqual(info, test.info) {
+			t.Errorf("%q: got %+v, want %+v", test.line, info, test.info)
+		}
+	}
+}
+
+func TestBot(t *testing.T) {
+	games, err := store.NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := games.CreatePlayer("alice", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("UTTT_FAKE_ENGINE", "fast")
+	bot, err := NewBot(games, config.BotConfig{Username: "fakebot", Command: os.Args[0]}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isBot, err := games.IsBot(bot.PlayerID()); !isBot || err != nil {
+		t.Errorf("bot account not marked as a bot: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	gameID, err := games.NewGame(alice.UUID, bot.PlayerID(), store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = games.PlayMove(gameID, game.Move{PlayerID: alice.UUID, Coordinate: game.NewCoordinate(2, 2, 2, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		state, err := games.GameState(gameID, alice.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner := state.Grids[1][1].Squares[0][0].Owner; owner != nil {
+			if *owner != bot.PlayerID() {
+				t.Errorf("square taken by %v", *owner)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("bot didn't answer the move")
+}
@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// A move is classed by how much less it scores than the best move, in
+// the same units as winScore. Scores are capped at reviewScoreCap
+// before comparing, so that a move that still wins, only slower, isn't
+// a blunder
+const (
+	inaccuracyDrop = 60
+	mistakeDrop    = 150
+	blunderDrop    = 300
+	reviewScoreCap = 1000
+)
+
+// classify names a move from its score and the best move's
+func classify(score, best int, isBest bool) string {
+	if isBest {
+		return store.MoveBest
+	}
+
+	drop := clamp(best) - clamp(score)
+	switch {
+	case drop >= blunderDrop:
+		return store.MoveBlunder
+	case drop >= mistakeDrop:
+		return store.MoveMistake
+	case drop >= inaccuracyDrop:
+		return store.MoveInaccuracy
+	case drop > 0:
+		return store.MoveGood
+	}
+	// the move was searched a move deeper than the best one, and found
+	// to be at least as good
+	return store.MoveBest
+}
+
+func clamp(score int) int {
+	if score > reviewScoreCap {
+		return reviewScoreCap
+	} else if score < -reviewScoreCap {
+		return -reviewScoreCap
+	}
+	return score
+}
+
+// Review annotates every move of a game, played from the start, by
+// comparing it with the best move the search finds in up to moveTime.
+// A move's score is the best score of the position it leads to, from
+// the other side
+func (b *Builtin) Review(moves []game.Coordinate, moveTime time.Duration) ([]store.MoveAnnotation, error) {
+	_, err := game.Replay("x", "o", moves)
+	if err != nil {
+		return nil, err
+	}
+
+	pos, err := parseBoard(game.StartPosition)
+	if err != nil {
+		return nil, err
+	}
+	positions := []board{pos}
+	for _, c := range moves {
+		pos = pos.play(fromCoordinate(c))
+		positions = append(positions, pos)
+	}
+
+	// best holds the best line from each position, or the score of the
+	// finished game for the player who would move next
+	best := make([]scoredLine, len(positions))
+	for i, p := range positions {
+		if p.winner != none {
+			score, _ := (&search{}).negamax(p, 0, -winScore-1, winScore+1, noMove)
+			best[i] = scoredLine{score: score}
+			continue
+		}
+		_, _, lines := b.analyze(p, p.moves(), 1, moveTime)
+		best[i] = lines[0]
+	}
+
+	annotations := []store.MoveAnnotation{}
+	for i, c := range moves {
+		score := -best[i+1].score
+		bestMove := best[i].pv[0].coordinate()
+		annotations = append(annotations, store.MoveAnnotation{
+			Number:    i + 1,
+			Move:      c,
+			Class:     classify(score, best[i].score, c == bestMove),
+			Score:     score,
+			Best:      bestMove,
+			BestScore: best[i].score,
+			PV:        coordinates(best[i].pv),
+		})
+	}
+	return annotations, nil
+}
+
+// reviewQueueSize is how many finished games can be waiting for a
+// review. Games finishing while the queue is full are picked up the
+// next time the reviewer starts
+const reviewQueueSize = 256
+
+// Reviewer annotates the moves of games as they finish, with a fixed
+// number of workers so that reviews don't slow the server down
+type Reviewer struct {
+	games    *store.GameService
+	search   Builtin
+	moveTime time.Duration
+	workers  int
+	queue    chan string
+
+	// claimed holds the games being reviewed. A game finishing just
+	// before Run is both queued and in the backlog, so two workers
+	// can be handed it at once
+	mutex   sync.Mutex
+	claimed map[string]bool
+}
+
+// NewReviewer returns a reviewer that queues every game finishing from
+// now on. Nothing is reviewed until it runs
+func NewReviewer(games *store.GameService, workers int, moveTime time.Duration) *Reviewer {
+	r := &Reviewer{
+		games:    games,
+		moveTime: moveTime,
+		workers:  workers,
+		queue:    make(chan string, reviewQueueSize),
+		claimed:  map[string]bool{},
+	}
+	games.OnGameFinished(r.gameFinished)
+	return r
+}
+
+func (r *Reviewer) gameFinished(result store.GameResult) {
+	if result.Settings.Variant != store.StandardVariant {
+		return
+	}
+
+	select {
+	case r.queue <- result.GameID:
+	default:
+		fmt.Printf("reviewer: queue full, skipping %v\n", result.GameID)
+	}
+}
+
+// Run reviews queued games, along with any finished games that haven't
+// been reviewed yet, until ctx is canceled. A reviewer without workers
+// returns straight away
+func (r *Reviewer) Run(ctx context.Context) error {
+	if r.workers <= 0 {
+		return nil
+	}
+
+	unreviewed, err := r.games.UnreviewedGames()
+	if err != nil {
+		return err
+	}
+
+	// older games are handed to the workers directly, leaving the
+	// queue to games finishing now
+	backlog := make(chan string)
+	done := make(chan struct{})
+	for i := 0; i < r.workers; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for {
+				var gameID string
+				select {
+				case <-ctx.Done():
+					return
+				case gameID = <-r.queue:
+				case gameID = <-backlog:
+				}
+
+				_, err := r.review(gameID)
+				if err != nil {
+					fmt.Printf("reviewer: %v: %v\n", gameID, err)
+				}
+			}
+		}()
+	}
+
+backfill:
+	for _, gameID := range unreviewed {
+		select {
+		case <-ctx.Done():
+			break backfill
+		case backlog <- gameID:
+		}
+	}
+
+	for i := 0; i < r.workers; i++ {
+		<-done
+	}
+	return nil
+}
+
+// review annotates a game and saves its review, unless it has already
+// been reviewed or another worker is reviewing it. It returns whether
+// the game was reviewed
+func (r *Reviewer) review(gameID string) (bool, error) {
+	r.mutex.Lock()
+	if r.claimed[gameID] {
+		r.mutex.Unlock()
+		return false, nil
+	}
+	r.claimed[gameID] = true
+	r.mutex.Unlock()
+
+	defer func() {
+		r.mutex.Lock()
+		delete(r.claimed, gameID)
+		r.mutex.Unlock()
+	}()
+
+	reviewed, err := r.games.Reviewed(gameID)
+	if err != nil || reviewed {
+		return false, err
+	}
+
+	record, err := r.games.ReviewRecord(gameID)
+	if err != nil {
+		return false, err
+	}
+
+	annotations, err := r.search.Review(record.Moves, r.moveTime)
+	if err != nil {
+		return false, err
+	}
+	return true, r.games.SaveReview(gameID, annotations)
+}
@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		score, best int
+		isBest      bool
+		class       string
+	}{
+		{-500, 200, true, store.MoveBest},
+		{210, 200, false, store.MoveBest},
+		{170, 200, false, store.MoveGood},
+		{100, 200, false, store.MoveInaccuracy},
+		{0, 160, false, store.MoveMistake},
+		{-200, 150, false, store.MoveBlunder},
+		// a slower win is still a win, but missing one isn't
+		{winScore - 40, winScore - 20, false, store.MoveBest},
+		{200, winScore - 20, false, store.MoveBlunder},
+	}
+
+	for _, test := range tests {
+		if class := classify(test.score, test.best, test.isBest); class != test.class {
+			t.Errorf("%v against %v: got %v, want %v", test.score, test.best, class, test.class)
+		}
+	}
+}
+
+// randomGame plays random moves until the game is over
+func randomGame(r *rand.Rand) []game.Coordinate {
+	g, _ := game.NewGame("x", "o")
+	players := [2]string{"x", "o"}
+	moves := []game.Coordinate{}
+	for i := 0; !g.IsCompleted(); i++ {
+		valid := g.GetValidMoves(players[i%2])
+		c := valid[r.Intn(len(valid))].Coordinate
+		g.PlayMove(game.Move{PlayerID: players[i%2], Coordinate: c})
+		moves = append(moves, c)
+	}
+	return moves
+}
+
+func TestBuiltinReview(t *testing.T) {
+	moves := randomGame(rand.New(rand.NewSource(2)))
+
+	b := &Builtin{Depth: 2}
+	annotations, err := b.Review(moves, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != len(moves) {
+		t.Fatalf("got %d annotations for %d moves", len(annotations), len(moves))
+	}
+
+	blunders := 0
+	for i, a := range annotations {
+		if a.Number != i+1 || a.Move != moves[i] || len(a.PV) == 0 || a.PV[0] != a.Best {
+			t.Errorf("got annotation %+v for move %d, %v", a, i+1, moves[i])
+		}
+		if a.Move == a.Best && a.Class != store.MoveBest {
+			t.Errorf("the engine's own move %d classed %v", i+1, a.Class)
+		}
+		if a.Class == store.MoveBlunder {
+			blunders++
+		}
+	}
+	if blunders == 0 {
+		t.Error("random moves made no blunders")
+	}
+	if last := annotations[len(annotations)-1]; last.Score < winScore-maxDepth && last.Score != 0 {
+		t.Errorf("the last move scored %v, neither a win nor a tie", last.Score)
+	}
+
+	_, err = b.Review(append(moves, moves[0]), time.Second)
+	if err == nil {
+		t.Error("reviewed a move after the end of the game")
+	}
+}
+
+// playGame plays a random game to the end between two players
+func playGame(t *testing.T, games *store.GameService, r *rand.Rand, playerX, playerO string) string {
+	gameID, err := games.NewGame(playerX, playerO, store.ColorX, store.GameSettings{Casual: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	players := [2]string{playerX, playerO}
+	for i, c := range randomGame(r) {
+		_, err = games.PlayMove(gameID, game.Move{PlayerID: players[i%2], Coordinate: c})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return gameID
+}
+
+func TestReviewer(t *testing.T) {
+	games, err := store.NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, _ := games.CreatePlayer("alice", "alice")
+	bob, _ := games.CreatePlayer("bob", "bob")
+	r := rand.New(rand.NewSource(3))
+
+	// finished before there was a reviewer, so only found once it runs
+	older := playGame(t, games, r, alice.UUID, bob.UUID)
+
+	reviewer := NewReviewer(games, 2, time.Millisecond)
+	// queued, and also found by Run
+	between := playGame(t, games, r, alice.UUID, bob.UUID)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		reviewer.Run(ctx)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	newer := playGame(t, games, r, alice.UUID, bob.UUID)
+	unfinished, err := games.NewGame(alice.UUID, bob.UUID, store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = games.GameReview(unfinished, alice.UUID); err != store.ErrGameNotFinished {
+		t.Errorf("got %v reviewing an unfinished game", err)
+	}
+
+	for _, gameID := range []string{older, between, newer} {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			review, err := games.GameReview(gameID, bob.UUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if review.Status == store.ReviewDone {
+				if len(review.Moves) < 5 {
+					t.Errorf("got a review of %d moves", len(review.Moves))
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%v wasn't reviewed", gameID)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestReviewerReviewsOnce(t *testing.T) {
+	games, err := store.NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, _ := games.CreatePlayer("alice", "alice")
+	bob, _ := games.CreatePlayer("bob", "bob")
+	r := rand.New(rand.NewSource(4))
+
+	reviewer := NewReviewer(games, 1, time.Millisecond)
+	gameID := playGame(t, games, r, alice.UUID, bob.UUID)
+	for i, want := range []bool{true, false} {
+		reviewed, err := reviewer.review(gameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reviewed != want {
+			t.Errorf("review %d: got %v, want %v", i+1, reviewed, want)
+		}
+	}
+
+	// a game another worker is reviewing is left to it
+	other := playGame(t, games, r, alice.UUID, bob.UUID)
+	reviewer.claimed[other] = true
+	if reviewed, err := reviewer.review(other); reviewed || err != nil {
+		t.Errorf("got %v, %v reviewing a claimed game", reviewed, err)
+	}
+	delete(reviewer.claimed, other)
+	if reviewed, err := reviewer.review(other); !reviewed || err != nil {
+		t.Errorf("got %v, %v once the claim was released", reviewed, err)
+	}
+}
+
+func TestReviewerNoWorkers(t *testing.T) {
+	games, err := store.NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- NewReviewer(games, 0, time.Millisecond).Run(context.Background())
+	}()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run without workers didn't return")
+	}
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/game"
+)
+
+// Searcher picks moves: an engine process, or the built-in search
+type Searcher interface {
+	NewGame() error
+	Search(position string, moveTime time.Duration) (game.Coordinate, []Info, error)
+	Close() error
+}
+
+// BuiltinName is what the built-in search calls itself
+const BuiltinName = "uttt builtin"
+
+// winScore is the score of a won position. Wins further away score less,
+// so that the quickest is preferred
+const winScore = 100000
+
+// maxDepth bounds the built-in search's iterative deepening; no game
+// lasts longer than this many moves
+const maxDepth = 81
+
+// Builtin is an alpha-beta search that runs in-process. A zero Depth
+// searches until the time limit. While Book has the position, a move is
+// picked from it instead of searching
+type Builtin struct {
+	Depth int
+	Book  *book.Book
+}
+
+// NewGame does nothing; the built-in search keeps nothing between moves
+func (b *Builtin) NewGame() error {
+	return nil
+}
+
+// Close does nothing
+func (b *Builtin) Close() error {
+	return nil
+}
+
+// Search deepens an alpha-beta search of the position one move at a
+// time until moveTime runs out or Depth is reached, and returns the best
+// move of the deepest search to finish. Each finished depth is reported
+// as an Info
+func (b *Builtin) Search(position string, moveTime time.Duration) (game.Coordinate, []Info, error) {
+	if b.Book != nil {
+		if c, ok := b.Book.Pick(position); ok {
+			return c, []Info{{PV: []game.Coordinate{c}, String: "book move"}}, nil
+		}
+	}
+
+	start := time.Now()
+	pos, err := parseBoard(position)
+	if err != nil {
+		return game.Coordinate{}, nil, err
+	}
+
+	moves := pos.moves()
+	if len(moves) == 0 {
+		return game.Coordinate{}, nil, game.ErrGameOver
+	}
+
+	depth := b.Depth
+	if depth <= 0 || depth > maxDepth {
+		depth = maxDepth
+	}
+
+	s := &search{deadline: start.Add(moveTime)}
+	infos := []Info{}
+	best := moves[0]
+	for d := 1; d <= depth; d++ {
+		score, pv := s.negamax(pos, d, -winScore-1, winScore+1, best)
+		if s.stopped {
+			break
+		}
+
+		best = pv[0]
+		info := Info{Depth: d, Score: score, Nodes: s.nodes, Time: time.Since(start)}
+		for _, m := range pv {
+			info.PV = append(info.PV, m.coordinate())
+		}
+		infos = append(infos, info)
+
+		if score >= winScore-maxDepth || score <= -winScore+maxDepth || d >= 81-pos.played() {
+			// the result is known, or the game will be over by then
+			break
+		}
+	}
+	return best.coordinate(), infos, nil
+}
+
+// search holds the state of one call to Builtin.Search
+type search struct {
+	deadline time.Time
+	nodes    int
+	stopped  bool
+}
+
+// negamax returns the score of pos from the point of view of the player
+// to move, searching depth moves ahead, and its principal variation.
+// first is searched before the other moves, to cut off more of them
+func (s *search) negamax(pos board, depth, alpha, beta int, first move) (int, []move) {
+	s.nodes++
+	if s.nodes%1024 == 0 && time.Now().After(s.deadline) {
+		s.stopped = true
+	}
+	if s.stopped {
+		return 0, nil
+	}
+
+	switch pos.winner {
+	case tie:
+		return 0, nil
+	case pos.toMove:
+		return winScore - pos.played(), nil
+	case pos.toMove.opponent():
+		return -winScore + pos.played(), nil
+	}
+	if depth == 0 {
+		return pos.evaluate(), nil
+	}
+
+	moves := pos.moves()
+	for i, m := range moves {
+		if m == first {
+			moves[0], moves[i] = moves[i], moves[0]
+			break
+		}
+	}
+
+	best := -winScore - 1
+	var pv []move
+	for _, m := range moves {
+		score, line := s.negamax(pos.play(m), depth-1, -beta, -alpha, noMove)
+		score = -score
+		if s.stopped {
+			return 0, nil
+		}
+		if score > best {
+			best = score
+			pv = append([]move{m}, line...)
+		}
+		if best > alpha {
+			alpha = best
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best, pv
+}
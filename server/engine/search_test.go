@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/heartles/uttt/server/book"
+	"github.com/heartles/uttt/server/game"
+)
+
+// TestBoardMoves plays random games on both a board and a game.Game,
+// checking that they agree on the legal moves and the result
+func TestBoardMoves(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		g, _ := game.NewGame("X", "O")
+		b, err := parseBoard(game.StartPosition)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		players := [2]string{"X", "O"}
+		for i := 0; ; i++ {
+			want := []string{}
+			for _, m := range g.GetValidMoves(players[i%2]) {
+				want = append(want, m.Coordinate.String())
+			}
+			got := []string{}
+			for _, m := range b.moves() {
+				got = append(got, m.coordinate().String())
+			}
+			sort.Strings(want)
+			sort.Strings(got)
+			if len(got) != len(want) {
+				t.Fatalf("%v: got moves %v, want %v", g.Position(), got, want)
+			}
+			for j := range got {
+				if got[j] != want[j] {
+					t.Fatalf("%v: got moves %v, want %v", g.Position(), got, want)
+				}
+			}
+			if len(got) == 0 {
+				break
+			}
+
+			m := b.moves()[r.Intn(len(got))]
+			b = b.play(m)
+			g.PlayMove(game.Move{PlayerID: players[i%2], Coordinate: m.coordinate()})
+		}
+
+		winner := map[owner]string{x: "X", o: "O", tie: game.StalematePlayer}[b.winner]
+		if winner != g.GameWinner() {
+			t.Fatalf("%v: got winner %q, want %q", g.Position(), winner, g.GameWinner())
+		}
+
+		reparsed, err := parseBoard(g.Position())
+		if err != nil || reparsed != b {
+			t.Fatalf("%v: parsed differently from the played board: %v", g.Position(), err)
+		}
+	}
+}
+
+func TestBuiltinWins(t *testing.T) {
+	// X has the top left and top center subgrids, and two in a row in
+	// the top right one, which O has sent it to
+	position := "XXXXXXXX1/9/9/OO1OO1OO1/9/9/O1O6/9/9 A3c1"
+
+	b := &Builtin{Depth: 3}
+	move, infos, err := b.Search(position, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move.String() != "C1c1" {
+		t.Errorf("got %v, want the winning C1c1", move)
+	}
+	if len(infos) == 0 || infos[len(infos)-1].Score < winScore-maxDepth {
+		t.Errorf("got infos %+v, want a winning score", infos)
+	}
+}
+
+func TestBuiltinTimeLimit(t *testing.T) {
+	b := &Builtin{}
+	start := time.Now()
+	move, infos, err := b.Search(game.StartPosition, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("searched for %v", elapsed)
+	}
+	if len(infos) == 0 || infos[len(infos)-1].PV[0] != move {
+		t.Errorf("got %v, with infos %+v", move, infos)
+	}
+}
+
+func TestBuiltinBook(t *testing.T) {
+	openings := book.New()
+	err := openings.Add(game.Record{
+		Moves:  []game.Coordinate{game.NewCoordinate(2, 2, 2, 2), game.NewCoordinate(2, 2, 1, 1)},
+		Result: game.ResultOWins,
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Builtin{Book: openings}
+	move, infos, err := b.Search("9/9/9/9/4X4/9/9/9/9 B2b2", time.Second)
+	if err != nil || move.String() != "B2a1" || len(infos) != 1 || infos[0].String != "book move" {
+		t.Errorf("got %v, %+v, %v", move, infos, err)
+	}
+}
+
+func TestBuiltinAnalyze(t *testing.T) {
+	position := "XXXXXXXX1/9/9/OO1OO1OO1/9/9/O1O6/9/9 A3c1"
+	b := &Builtin{Depth: 3}
+	analysis, err := b.Analyze(position, 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Lines) != 3 || analysis.Depth != 3 {
+		t.Fatalf("got %+v", analysis)
+	}
+	if best := analysis.Lines[0]; best.Move.String() != "C1c1" || best.WinIn != 1 || best.PV[0] != best.Move {
+		t.Errorf("got best line %+v, want the winning C1c1", best)
+	}
+	for i := 1; i < len(analysis.Lines); i++ {
+		if analysis.Lines[i].Score > analysis.Lines[i-1].Score {
+			t.Errorf("lines out of order: %+v", analysis.Lines)
+		}
+	}
+
+	// the best line scores the same as the plain search
+	_, infos, err := b.Search(game.StartPosition, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err = b.Analyze(game.StartPosition, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := analysis.Lines[0].Score, infos[len(infos)-1].Score; got != want {
+		t.Errorf("got best score %v, want %v", got, want)
+	}
+}
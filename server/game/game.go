@@ -20,6 +20,10 @@ var ErrWrongTurn = errors.New("not this player's turn")
 // in the wrong subgrid
 var ErrWrongSubgrid = errors.New("incorrect subgrid")
 
+// ErrGameOver is returned by PlayMove when the game has already
+// been won or tied
+var ErrGameOver = errors.New("game is over")
+
 // ErrInvalidPlayer is returned by PlayMove when an invalid player id
 // is provided
 var ErrInvalidPlayer = errors.New("invalid player id")
@@ -53,7 +57,7 @@ type Coordinate struct {
 // Move encapsulates a player's move
 type Move struct {
 	PlayerID   string `json:"playerID"`
-	Coordinate `json:"coordinate"`
+	Coordinate `json:"coordinate" msgpack:"coordinate,noinline"`
 }
 
 // Game encapsulates a game of uttt
@@ -330,6 +334,8 @@ func (g *Game) verifyMove(player squareState, coord Coordinate) error {
 
 	if player == stateInvalid {
 		return ErrInvalidPlayer
+	} else if g.grid.state != stateInProgress {
+		return ErrGameOver
 	} else if g.lastTurn == nil {
 		// it's the first turn, x goes first
 		if player != stateX {
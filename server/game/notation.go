@@ -0,0 +1,291 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidNotation is returned when parsing a malformed move or
+// game record
+var ErrInvalidNotation = errors.New("invalid notation")
+
+// Results, as written at the end of a game record
+const (
+	ResultXWins      = "1-0"
+	ResultOWins      = "0-1"
+	ResultTie        = "1/2-1/2"
+	ResultUnfinished = "*"
+)
+
+// String writes a coordinate in move notation: the subgrid as an
+// uppercase column A-C and a row 1-3 counted from the top, then the
+// square within it in lowercase. "B2a1" is the top left square of the
+// center subgrid
+func (c Coordinate) String() string {
+	return fmt.Sprintf("%c%d%c%d",
+		'A'+c.GameSquare.X-1, c.GameSquare.Y,
+		'a'+c.SubgridSquare.X-1, c.SubgridSquare.Y)
+}
+
+// ParseCoordinate reads a coordinate written in move notation
+func ParseCoordinate(s string) (Coordinate, error) {
+	if len(s) != 4 {
+		return Coordinate{}, ErrInvalidNotation
+	}
+
+	c := NewCoordinate(int(s[0]-'A')+1, int(s[1]-'0'), int(s[2]-'a')+1, int(s[3]-'0'))
+	for _, v := range []int{c.GameSquare.X, c.GameSquare.Y, c.SubgridSquare.X, c.SubgridSquare.Y} {
+		if v < 1 || v > 3 {
+			return Coordinate{}, ErrInvalidNotation
+		}
+	}
+	return c, nil
+}
+
+// Tag is a named piece of information about a recorded game, such as
+// its players or settings
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Record is a game written out move by move. X always moves first, so
+// the moves alternate starting with X
+type Record struct {
+	Tags   []Tag
+	Moves  []Coordinate
+	Result string
+}
+
+// String writes a record as its tags, one per line, followed by the
+// numbered moves and the result:
+//
+//	[X "alice"]
+//	[O "bob"]
+//
+//	1. B2b2 B2a1 2. A1b2 ... 1-0
+func (r Record) String() string {
+	b := &strings.Builder{}
+	for _, tag := range r.Tags {
+		fmt.Fprintf(b, "[%v %v]\n", tag.Name, strconv.Quote(tag.Value))
+	}
+	if len(r.Tags) != 0 {
+		b.WriteString("\n")
+	}
+
+	tokens := []string{}
+	for i, m := range r.Moves {
+		if i%2 == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d.", i/2+1))
+		}
+		tokens = append(tokens, m.String())
+	}
+	result := r.Result
+	if result == "" {
+		result = ResultUnfinished
+	}
+	tokens = append(tokens, result)
+
+	// wrap the moves at 80 columns
+	line := 0
+	for i, token := range tokens {
+		if i != 0 && line+1+len(token) > 80 {
+			b.WriteString("\n")
+			line = 0
+		} else if i != 0 {
+			b.WriteString(" ")
+			line++
+		}
+		b.WriteString(token)
+		line += len(token)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// ParseRecord reads a record written by Record.String. The moves are
+// not checked against the rules; Replay does that
+func ParseRecord(text string) (Record, error) {
+	r := Record{}
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "[") {
+			break
+		}
+
+		space := strings.Index(line, " ")
+		if space == -1 || !strings.HasSuffix(line, "]") {
+			return Record{}, ErrInvalidNotation
+		}
+		value, err := strconv.Unquote(line[space+1 : len(line)-1])
+		if err != nil {
+			return Record{}, ErrInvalidNotation
+		}
+		r.Tags = append(r.Tags, Tag{line[1:space], value})
+	}
+
+	tokens := strings.Fields(strings.Join(lines[i:], " "))
+	for n, token := range tokens {
+		switch {
+		case strings.HasSuffix(token, "."):
+			if token != fmt.Sprintf("%d.", len(r.Moves)/2+1) || len(r.Moves)%2 != 0 {
+				return Record{}, ErrInvalidNotation
+			}
+		case token == ResultXWins || token == ResultOWins || token == ResultTie || token == ResultUnfinished:
+			if n != len(tokens)-1 {
+				return Record{}, ErrInvalidNotation
+			}
+			r.Result = token
+		default:
+			c, err := ParseCoordinate(token)
+			if err != nil {
+				return Record{}, err
+			}
+			r.Moves = append(r.Moves, c)
+		}
+	}
+
+	if r.Result == "" {
+		return Record{}, ErrInvalidNotation
+	}
+	return r, nil
+}
+
+// Replay plays a sequence of moves, starting with X, from the start of
+// a game. It returns the first illegal move's error
+func Replay(playerX, playerO string, moves []Coordinate) (*Game, error) {
+	g, err := NewGame(playerX, playerO)
+	if err != nil {
+		return nil, err
+	}
+
+	players := [2]string{playerX, playerO}
+	for i, c := range moves {
+		err = g.PlayMove(Move{PlayerID: players[i%2], Coordinate: c})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// StartPosition is the position before any move, in position notation
+const StartPosition = "9/9/9/9/9/9/9/9/9 -"
+
+// Position writes the game's position in position notation: the 9 rows
+// of the board from the top, separated by slashes, then the last move
+// in move notation, or "-" before the first move. Each row lists its
+// squares from the left as X, O, or a digit counting empty squares, so
+// the center square alone taken by X is "9/9/9/9/4X4/9/9/9/9 B2b2".
+// The player to move is X if both have played as many moves, and O
+// otherwise
+func (g *Game) Position() string {
+	_, _, state, lastTurn := g.SaveGame()
+
+	b := &strings.Builder{}
+	for row := 0; row < 9; row++ {
+		if row != 0 {
+			b.WriteString("/")
+		}
+
+		empty := 0
+		for _, square := range state[row*9 : row*9+9] {
+			if square == '_' {
+				empty++
+				continue
+			}
+			if empty != 0 {
+				b.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			b.WriteRune(square)
+		}
+		if empty != 0 {
+			b.WriteString(strconv.Itoa(empty))
+		}
+	}
+
+	b.WriteString(" ")
+	if lastTurn == nil {
+		b.WriteString("-")
+	} else {
+		b.WriteString(lastTurn.String())
+	}
+	return b.String()
+}
+
+// ParsePosition sets up a game between two players from a position
+// written in position notation
+func ParsePosition(playerX, playerO, position string) (*Game, error) {
+	fields := strings.Fields(position)
+	if len(fields) != 2 {
+		return nil, ErrInvalidNotation
+	}
+
+	rows := strings.Split(fields[0], "/")
+	if len(rows) != 9 {
+		return nil, ErrInvalidNotation
+	}
+
+	state := &strings.Builder{}
+	counts := map[rune]int{}
+	for _, row := range rows {
+		length := 0
+		for _, square := range row {
+			switch {
+			case square >= '1' && square <= '9':
+				n := int(square - '0')
+				state.WriteString(strings.Repeat("_", n))
+				length += n
+			case square == 'X' || square == 'O':
+				state.WriteRune(square)
+				counts[square]++
+				length++
+			default:
+				return nil, ErrInvalidNotation
+			}
+		}
+		if length != 9 {
+			return nil, ErrInvalidNotation
+		}
+	}
+
+	// X moves first, so it has played as many moves as O or one more
+	moved := counts['X'] - counts['O']
+	if moved != 0 && moved != 1 {
+		return nil, ErrInvalidNotation
+	}
+
+	var lastTurn *Coordinate
+	if fields[1] != "-" {
+		c, err := ParseCoordinate(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		lastTurn = &c
+	} else if counts['X'] != 0 {
+		return nil, ErrInvalidNotation
+	}
+
+	g, err := LoadGame(playerX, playerO, state.String(), lastTurn)
+	if err != nil {
+		return nil, err
+	}
+
+	// the last move has to have been played by the player who moved last
+	if lastTurn != nil {
+		last := playerX
+		if moved == 0 {
+			last = playerO
+		}
+		if owner, _ := g.SquareOwner(*lastTurn); owner != last {
+			return nil, ErrInvalidNotation
+		}
+	}
+	return g, nil
+}
@@ -1,20 +1,30 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/rand"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
 
+	"github.com/heartles/uttt/server/api"
+	"github.com/heartles/uttt/server/book"
 	"github.com/heartles/uttt/server/config"
+	"github.com/heartles/uttt/server/engine"
+	"github.com/heartles/uttt/server/rpc"
 	"github.com/heartles/uttt/server/socket"
 	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
 )
 
 // ErrValidationFailed is returned if the path provided was not valid
@@ -35,13 +45,26 @@ func ValidatePath(path string) (string, error) {
 }
 
 func main() {
+	rand.Seed(time.Now().UnixNano())
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error loading config")
 		panic(err)
 	}
 
-	server := buildServer(cfg)
+	server, grpcServer := buildServer(cfg)
+	if cfg.GRPCPort != 0 {
+		grpcAddress := fmt.Sprintf(":%v", strconv.Itoa(cfg.GRPCPort))
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("gRPC listening on %v\n", grpcAddress)
+		go grpcServer.Serve(listener)
+	}
+
 	listenAddress := fmt.Sprintf(":%v", strconv.Itoa(cfg.Port))
 	fmt.Printf("Listening on %v\n", listenAddress)
 	if cfg.AcmeTLS {
@@ -58,14 +81,39 @@ func main() {
 }
 
 // buildServer constructs an echo instance with the routes setup
-// according to the configuration given
-func buildServer(cfg *config.Config) *echo.Echo {
+// according to the configuration given, and the gRPC server that
+// shares its backend
+func buildServer(cfg *config.Config) (*echo.Echo, *grpc.Server) {
 	server := echo.New()
 	gameService, err := store.NewGameService(cfg.DBFilename)
 	if err != nil {
 		panic(err)
 	}
-	socketServer := socket.NewServer(cfg, gameService)
+	matchmaker := store.NewMatchmaker(gameService)
+	tournaments := tournament.NewService(gameService)
+	chat := store.NewChatService(gameService)
+	socketServer := socket.NewServer(cfg, gameService, matchmaker, tournaments, chat)
+
+	var openings *book.Book
+	if cfg.BookFilename != "" {
+		openings, err = book.Load(cfg.BookFilename)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	for _, c := range cfg.Bots {
+		bot, err := engine.NewBot(gameService, c, openings)
+		if err != nil {
+			panic(err)
+		}
+		go bot.Run(context.Background())
+	}
+
+	if cfg.ReviewWorkers > 0 {
+		reviewer := engine.NewReviewer(gameService, cfg.ReviewWorkers, cfg.ReviewMoveTime)
+		go reviewer.Run(context.Background())
+	}
 
 	server.Use(middleware.Recover())
 	if cfg.RequestLogs {
@@ -93,5 +141,7 @@ func buildServer(cfg *config.Config) *echo.Echo {
 		return nil
 	})
 
-	return server
+	api.Register(server, gameService, socketServer, openings)
+
+	return server, rpc.NewServer(gameService)
 }
@@ -0,0 +1,142 @@
+// Package rating implements the Glicko-2 rating system, as described
+// in Mark Glickman's "Example of the Glicko-2 system"
+// (http://www.glicko.net/glicko/glicko2.pdf)
+package rating
+
+import "math"
+
+// DefaultRating is the rating given to a player with no games
+const DefaultRating = 1500
+
+// DefaultDeviation is the rating deviation of a player with no games
+const DefaultDeviation = 350
+
+// DefaultVolatility is the volatility of a player with no games
+const DefaultVolatility = 0.06
+
+// Tau constrains how quickly volatility can change. Glickman suggests
+// values between 0.3 and 1.2
+const Tau = 0.5
+
+// glicko2Scale converts between the Glicko and Glicko-2 scales
+const glicko2Scale = 173.7178
+
+// convergence is the tolerance used when solving for the new volatility
+const convergence = 0.000001
+
+// Score values for a Result
+const (
+	Loss = 0.0
+	Tie  = 0.5
+	Win  = 1.0
+)
+
+// Rating is a player's rating, on the Glicko scale
+type Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// Default returns the rating of a new player
+func Default() Rating {
+	return Rating{DefaultRating, DefaultDeviation, DefaultVolatility}
+}
+
+// Result is the outcome of one game from a player's point of view
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+func (r Rating) mu() float64 {
+	return (r.Rating - DefaultRating) / glicko2Scale
+}
+
+func (r Rating) phi() float64 {
+	return r.Deviation / glicko2Scale
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muOpponent, phiOpponent float64) float64 {
+	return 1 / (1 + math.Exp(-g(phiOpponent)*(mu-muOpponent)))
+}
+
+// Update returns the player's rating after a rating period in which
+// they played the given games. With no results, only the deviation
+// grows to reflect the added uncertainty
+func Update(r Rating, results []Result) Rating {
+	mu, phi, sigma := r.mu(), r.phi(), r.Volatility
+
+	if len(results) == 0 {
+		phiStar := math.Sqrt(phi*phi + sigma*sigma)
+		return Rating{r.Rating, phiStar * glicko2Scale, sigma}
+	}
+
+	// step 3 and 4: estimated variance and improvement
+	vInv := 0.0
+	deltaSum := 0.0
+	for _, res := range results {
+		muJ, phiJ := res.Opponent.mu(), res.Opponent.phi()
+		e := expected(mu, muJ, phiJ)
+		gPhi := g(phiJ)
+		vInv += gPhi * gPhi * e * (1 - e)
+		deltaSum += gPhi * (res.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	// step 5: new volatility
+	sigmaPrime := newVolatility(delta, phi, v, sigma)
+
+	// step 6 and 7: new deviation and rating
+	phiStar := math.Sqrt(phi*phi + sigmaPrime*sigmaPrime)
+	phiPrime := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	muPrime := mu + phiPrime*phiPrime*deltaSum
+
+	return Rating{
+		Rating:     muPrime*glicko2Scale + DefaultRating,
+		Deviation:  phiPrime * glicko2Scale,
+		Volatility: sigmaPrime,
+	}
+}
+
+// newVolatility solves for the new volatility using the Illinois
+// algorithm, as in step 5 of the paper
+func newVolatility(delta, phi, v, sigma float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(Tau*Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*Tau) < 0 {
+			k++
+		}
+		B = a - k*Tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > convergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,63 @@
+package rating_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/heartles/uttt/server/rating"
+)
+
+func closeTo(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// the worked example from Glickman's paper
+func TestUpdateMatchesPaperExample(t *testing.T) {
+	player := rating.Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []rating.Result{
+		{rating.Rating{Rating: 1400, Deviation: 30, Volatility: 0.06}, rating.Win},
+		{rating.Rating{Rating: 1550, Deviation: 100, Volatility: 0.06}, rating.Loss},
+		{rating.Rating{Rating: 1700, Deviation: 300, Volatility: 0.06}, rating.Loss},
+	}
+
+	updated := rating.Update(player, results)
+	if !closeTo(updated.Rating, 1464.06, 0.01) {
+		t.Errorf("rating was %v, expected 1464.06", updated.Rating)
+	}
+	if !closeTo(updated.Deviation, 151.52, 0.01) {
+		t.Errorf("deviation was %v, expected 151.52", updated.Deviation)
+	}
+	if !closeTo(updated.Volatility, 0.05999, 0.00001) {
+		t.Errorf("volatility was %v, expected 0.05999", updated.Volatility)
+	}
+}
+
+func TestUpdateWithoutGamesOnlyGrowsDeviation(t *testing.T) {
+	player := rating.Rating{Rating: 1600, Deviation: 50, Volatility: 0.06}
+
+	updated := rating.Update(player, nil)
+	if updated.Rating != player.Rating || updated.Volatility != player.Volatility {
+		t.Errorf("Update changed rating or volatility: %+v", updated)
+	}
+	if updated.Deviation <= player.Deviation {
+		t.Errorf("deviation was %v, expected more than %v", updated.Deviation, player.Deviation)
+	}
+}
+
+func TestWinnerGainsLoserLoses(t *testing.T) {
+	a, b := rating.Default(), rating.Default()
+
+	newA := rating.Update(a, []rating.Result{{b, rating.Win}})
+	newB := rating.Update(b, []rating.Result{{a, rating.Loss}})
+	if newA.Rating <= a.Rating || newB.Rating >= b.Rating {
+		t.Errorf("expected winner to gain and loser to lose, got %v and %v", newA.Rating, newB.Rating)
+	}
+	if !closeTo(newA.Rating-a.Rating, b.Rating-newB.Rating, 0.000001) {
+		t.Errorf("equal players should move symmetrically, got %v and %v", newA.Rating, newB.Rating)
+	}
+
+	tieA := rating.Update(a, []rating.Result{{b, rating.Tie}})
+	if !closeTo(tieA.Rating, a.Rating, 0.000001) {
+		t.Errorf("a tie between equal players changed rating to %v", tieA.Rating)
+	}
+}
@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/heartles/uttt/server/rpc/pb"
+	"github.com/heartles/uttt/server/store"
+)
+
+type playerKey struct{}
+
+// player returns the ID of the authenticated player
+func player(ctx context.Context) string {
+	return ctx.Value(playerKey{}).(string)
+}
+
+// authenticate returns ctx with the player whose API key is in its
+// metadata
+func (s *service) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	auth := md.Get("authorization")
+	if len(auth) == 0 || !strings.HasPrefix(auth[0], "Bearer ") {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	playerID, err := s.games.APIKeyPlayer(strings.TrimPrefix(auth[0], "Bearer "))
+	if err == store.ErrInvalidAPIKey {
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	} else if err != nil {
+		return nil, statusError(err)
+	}
+
+	return context.WithValue(ctx, playerKey{}, playerID), nil
+}
+
+// authenticateUnary rejects calls without a valid API key, other than
+// Login, which is how a key is checked
+func (s *service) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod == pb.Uttt_Login_FullMethodName {
+		return handler(ctx, req)
+	}
+
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *service) authenticateStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, authenticatedStream{stream, ctx})
+}
+
+// authenticatedStream is a stream whose context carries the player
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
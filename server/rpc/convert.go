@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/rpc/pb"
+	"github.com/heartles/uttt/server/store"
+)
+
+// optional returns the value of an optional string, or "" for nil
+func optional(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func playerMessage(player *store.Player, ratings []store.Rating) *pb.Player {
+	msg := &pb.Player{
+		PlayerId: player.UUID,
+		Username: player.Username,
+	}
+	for _, r := range ratings {
+		msg.Ratings = append(msg.Ratings, &pb.Rating{
+			Pool:       r.Pool,
+			Rating:     r.Rating,
+			Deviation:  r.Deviation,
+			Volatility: r.Volatility,
+			Games:      int32(r.Games),
+		})
+	}
+	return msg
+}
+
+// coordinate converts a coordinate from a request. Missing fields are
+// left zero, which is out of bounds
+func coordinate(c *pb.Coordinate) game.Coordinate {
+	return game.Coordinate{
+		GameSquare:    subCoordinate(c.GetGameSquare()),
+		SubgridSquare: subCoordinate(c.GetSubgridSquare()),
+	}
+}
+
+func subCoordinate(c *pb.SubCoordinate) game.SubCoordinate {
+	return game.SubCoordinate{X: int(c.GetX()), Y: int(c.GetY())}
+}
+
+func coordinateMessage(c game.Coordinate) *pb.Coordinate {
+	return &pb.Coordinate{
+		GameSquare:    subCoordinateMessage(c.GameSquare),
+		SubgridSquare: subCoordinateMessage(c.SubgridSquare),
+	}
+}
+
+func subCoordinateMessage(c game.SubCoordinate) *pb.SubCoordinate {
+	return &pb.SubCoordinate{X: int32(c.X), Y: int32(c.Y)}
+}
+
+func settingsMessage(settings store.GameSettings) *pb.GameSettings {
+	return &pb.GameSettings{
+		Variant:     settings.Variant,
+		TimeControl: settings.TimeControl,
+		Casual:      settings.Casual,
+		Private:     settings.Private,
+	}
+}
+
+func gameStateMessage(state *store.GameState) *pb.GameState {
+	msg := &pb.GameState{
+		GameId:      state.GameID,
+		PlayerX:     state.PlayerX,
+		PlayerO:     state.PlayerO,
+		PlayerXName: state.PlayerXName,
+		PlayerOName: state.PlayerOName,
+		Victor:      optional(state.Victor),
+		Settings:    settingsMessage(state.Settings),
+		Spectators:  int32(state.Spectators),
+		Version:     int32(state.Version),
+	}
+
+	for _, row := range state.Grids {
+		for _, grid := range row {
+			g := &pb.Grid{Owner: optional(grid.Owner)}
+			for _, squares := range grid.Squares {
+				for _, square := range squares {
+					g.Squares = append(g.Squares, &pb.Square{
+						Owner:    optional(square.Owner),
+						Playable: square.Playable,
+					})
+				}
+			}
+			msg.Grids = append(msg.Grids, g)
+		}
+	}
+
+	return msg
+}
+
+func gameDeltaMessage(delta store.GameDelta) *pb.GameDelta {
+	msg := &pb.GameDelta{
+		GameId:     delta.GameID,
+		Version:    int32(delta.Version),
+		GridOwner:  optional(delta.GridOwner),
+		Turn:       delta.Turn,
+		Spectators: int32(delta.Spectators),
+	}
+	if delta.Move != nil {
+		msg.Move = &pb.Move{
+			PlayerId:   delta.Move.PlayerID,
+			Coordinate: coordinateMessage(delta.Move.Coordinate),
+		}
+	}
+	for _, c := range delta.PlayableGrids {
+		msg.PlayableGrids = append(msg.PlayableGrids, subCoordinateMessage(c))
+	}
+	return msg
+}
@@ -0,0 +1,1319 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.28.3
+// source: uttt.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LoginRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ApiKey        string                 `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoginRequest) Reset() {
+	*x = LoginRequest{}
+	mi := &file_uttt_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoginRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoginRequest) ProtoMessage() {}
+
+func (x *LoginRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoginRequest.ProtoReflect.Descriptor instead.
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LoginRequest) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+type Rating struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pool          string                 `protobuf:"bytes,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	Rating        float64                `protobuf:"fixed64,2,opt,name=rating,proto3" json:"rating,omitempty"`
+	Deviation     float64                `protobuf:"fixed64,3,opt,name=deviation,proto3" json:"deviation,omitempty"`
+	Volatility    float64                `protobuf:"fixed64,4,opt,name=volatility,proto3" json:"volatility,omitempty"`
+	Games         int32                  `protobuf:"varint,5,opt,name=games,proto3" json:"games,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rating) Reset() {
+	*x = Rating{}
+	mi := &file_uttt_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rating) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rating) ProtoMessage() {}
+
+func (x *Rating) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rating.ProtoReflect.Descriptor instead.
+func (*Rating) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Rating) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *Rating) GetRating() float64 {
+	if x != nil {
+		return x.Rating
+	}
+	return 0
+}
+
+func (x *Rating) GetDeviation() float64 {
+	if x != nil {
+		return x.Deviation
+	}
+	return 0
+}
+
+func (x *Rating) GetVolatility() float64 {
+	if x != nil {
+		return x.Volatility
+	}
+	return 0
+}
+
+func (x *Rating) GetGames() int32 {
+	if x != nil {
+		return x.Games
+	}
+	return 0
+}
+
+type Player struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerId      string                 `protobuf:"bytes,1,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	Username      string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Ratings       []*Rating              `protobuf:"bytes,3,rep,name=ratings,proto3" json:"ratings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Player) Reset() {
+	*x = Player{}
+	mi := &file_uttt_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Player) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Player) ProtoMessage() {}
+
+func (x *Player) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Player.ProtoReflect.Descriptor instead.
+func (*Player) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Player) GetPlayerId() string {
+	if x != nil {
+		return x.PlayerId
+	}
+	return ""
+}
+
+func (x *Player) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *Player) GetRatings() []*Rating {
+	if x != nil {
+		return x.Ratings
+	}
+	return nil
+}
+
+type LookupPlayerRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Player:
+	//
+	//	*LookupPlayerRequest_PlayerId
+	//	*LookupPlayerRequest_Username
+	Player        isLookupPlayerRequest_Player `protobuf_oneof:"player"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupPlayerRequest) Reset() {
+	*x = LookupPlayerRequest{}
+	mi := &file_uttt_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupPlayerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupPlayerRequest) ProtoMessage() {}
+
+func (x *LookupPlayerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupPlayerRequest.ProtoReflect.Descriptor instead.
+func (*LookupPlayerRequest) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LookupPlayerRequest) GetPlayer() isLookupPlayerRequest_Player {
+	if x != nil {
+		return x.Player
+	}
+	return nil
+}
+
+func (x *LookupPlayerRequest) GetPlayerId() string {
+	if x != nil {
+		if x, ok := x.Player.(*LookupPlayerRequest_PlayerId); ok {
+			return x.PlayerId
+		}
+	}
+	return ""
+}
+
+func (x *LookupPlayerRequest) GetUsername() string {
+	if x != nil {
+		if x, ok := x.Player.(*LookupPlayerRequest_Username); ok {
+			return x.Username
+		}
+	}
+	return ""
+}
+
+type isLookupPlayerRequest_Player interface {
+	isLookupPlayerRequest_Player()
+}
+
+type LookupPlayerRequest_PlayerId struct {
+	PlayerId string `protobuf:"bytes,1,opt,name=player_id,json=playerId,proto3,oneof"`
+}
+
+type LookupPlayerRequest_Username struct {
+	Username string `protobuf:"bytes,2,opt,name=username,proto3,oneof"`
+}
+
+func (*LookupPlayerRequest_PlayerId) isLookupPlayerRequest_Player() {}
+
+func (*LookupPlayerRequest_Username) isLookupPlayerRequest_Player() {}
+
+// SubCoordinate is a subgrid of the board, or a square of a subgrid. x
+// is the column and y the row, both 1-3 from the top left.
+type SubCoordinate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             int32                  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             int32                  `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubCoordinate) Reset() {
+	*x = SubCoordinate{}
+	mi := &file_uttt_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubCoordinate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubCoordinate) ProtoMessage() {}
+
+func (x *SubCoordinate) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubCoordinate.ProtoReflect.Descriptor instead.
+func (*SubCoordinate) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SubCoordinate) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *SubCoordinate) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+type Coordinate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameSquare    *SubCoordinate         `protobuf:"bytes,1,opt,name=game_square,json=gameSquare,proto3" json:"game_square,omitempty"`
+	SubgridSquare *SubCoordinate         `protobuf:"bytes,2,opt,name=subgrid_square,json=subgridSquare,proto3" json:"subgrid_square,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Coordinate) Reset() {
+	*x = Coordinate{}
+	mi := &file_uttt_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Coordinate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Coordinate) ProtoMessage() {}
+
+func (x *Coordinate) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Coordinate.ProtoReflect.Descriptor instead.
+func (*Coordinate) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Coordinate) GetGameSquare() *SubCoordinate {
+	if x != nil {
+		return x.GameSquare
+	}
+	return nil
+}
+
+func (x *Coordinate) GetSubgridSquare() *SubCoordinate {
+	if x != nil {
+		return x.SubgridSquare
+	}
+	return nil
+}
+
+type Move struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerId      string                 `protobuf:"bytes,1,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	Coordinate    *Coordinate            `protobuf:"bytes,2,opt,name=coordinate,proto3" json:"coordinate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Move) Reset() {
+	*x = Move{}
+	mi := &file_uttt_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Move) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Move) ProtoMessage() {}
+
+func (x *Move) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Move.ProtoReflect.Descriptor instead.
+func (*Move) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Move) GetPlayerId() string {
+	if x != nil {
+		return x.PlayerId
+	}
+	return ""
+}
+
+func (x *Move) GetCoordinate() *Coordinate {
+	if x != nil {
+		return x.Coordinate
+	}
+	return nil
+}
+
+type GameSettings struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Variant string                 `protobuf:"bytes,1,opt,name=variant,proto3" json:"variant,omitempty"`
+	// time_control is "<minutes>+<increment seconds>", or empty for an
+	// untimed game.
+	TimeControl   string `protobuf:"bytes,2,opt,name=time_control,json=timeControl,proto3" json:"time_control,omitempty"`
+	Casual        bool   `protobuf:"varint,3,opt,name=casual,proto3" json:"casual,omitempty"`
+	Private       bool   `protobuf:"varint,4,opt,name=private,proto3" json:"private,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameSettings) Reset() {
+	*x = GameSettings{}
+	mi := &file_uttt_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameSettings) ProtoMessage() {}
+
+func (x *GameSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameSettings.ProtoReflect.Descriptor instead.
+func (*GameSettings) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GameSettings) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *GameSettings) GetTimeControl() string {
+	if x != nil {
+		return x.TimeControl
+	}
+	return ""
+}
+
+func (x *GameSettings) GetCasual() bool {
+	if x != nil {
+		return x.Casual
+	}
+	return false
+}
+
+func (x *GameSettings) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+type Square struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// owner is the ID of the player who played here, if any.
+	Owner string `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	// playable is whether the requesting player may play here now.
+	Playable      bool `protobuf:"varint,2,opt,name=playable,proto3" json:"playable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Square) Reset() {
+	*x = Square{}
+	mi := &file_uttt_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Square) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Square) ProtoMessage() {}
+
+func (x *Square) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Square.ProtoReflect.Descriptor instead.
+func (*Square) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Square) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Square) GetPlayable() bool {
+	if x != nil {
+		return x.Playable
+	}
+	return false
+}
+
+type Grid struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// owner is the ID of the player who won the subgrid, "tie" if nobody
+	// can, or empty while it is being played.
+	Owner string `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	// squares holds the 9 squares, row by row from the top left.
+	Squares       []*Square `protobuf:"bytes,2,rep,name=squares,proto3" json:"squares,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Grid) Reset() {
+	*x = Grid{}
+	mi := &file_uttt_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Grid) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Grid) ProtoMessage() {}
+
+func (x *Grid) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Grid.ProtoReflect.Descriptor instead.
+func (*Grid) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Grid) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Grid) GetSquares() []*Square {
+	if x != nil {
+		return x.Squares
+	}
+	return nil
+}
+
+type GameState struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	GameId      string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	PlayerX     string                 `protobuf:"bytes,2,opt,name=player_x,json=playerX,proto3" json:"player_x,omitempty"`
+	PlayerO     string                 `protobuf:"bytes,3,opt,name=player_o,json=playerO,proto3" json:"player_o,omitempty"`
+	PlayerXName string                 `protobuf:"bytes,4,opt,name=player_x_name,json=playerXName,proto3" json:"player_x_name,omitempty"`
+	PlayerOName string                 `protobuf:"bytes,5,opt,name=player_o_name,json=playerOName,proto3" json:"player_o_name,omitempty"`
+	// victor is the ID of the winner, "tie", or empty while the game is
+	// being played.
+	Victor string `protobuf:"bytes,6,opt,name=victor,proto3" json:"victor,omitempty"`
+	// grids holds the 9 subgrids, row by row from the top left.
+	Grids      []*Grid       `protobuf:"bytes,7,rep,name=grids,proto3" json:"grids,omitempty"`
+	Settings   *GameSettings `protobuf:"bytes,8,opt,name=settings,proto3" json:"settings,omitempty"`
+	Spectators int32         `protobuf:"varint,9,opt,name=spectators,proto3" json:"spectators,omitempty"`
+	// version is the number of the latest change to the game. Each
+	// GameDelta applies to the state with the version before its own.
+	Version       int32 `protobuf:"varint,10,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameState) Reset() {
+	*x = GameState{}
+	mi := &file_uttt_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameState) ProtoMessage() {}
+
+func (x *GameState) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameState.ProtoReflect.Descriptor instead.
+func (*GameState) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GameState) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *GameState) GetPlayerX() string {
+	if x != nil {
+		return x.PlayerX
+	}
+	return ""
+}
+
+func (x *GameState) GetPlayerO() string {
+	if x != nil {
+		return x.PlayerO
+	}
+	return ""
+}
+
+func (x *GameState) GetPlayerXName() string {
+	if x != nil {
+		return x.PlayerXName
+	}
+	return ""
+}
+
+func (x *GameState) GetPlayerOName() string {
+	if x != nil {
+		return x.PlayerOName
+	}
+	return ""
+}
+
+func (x *GameState) GetVictor() string {
+	if x != nil {
+		return x.Victor
+	}
+	return ""
+}
+
+func (x *GameState) GetGrids() []*Grid {
+	if x != nil {
+		return x.Grids
+	}
+	return nil
+}
+
+func (x *GameState) GetSettings() *GameSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *GameState) GetSpectators() int32 {
+	if x != nil {
+		return x.Spectators
+	}
+	return 0
+}
+
+func (x *GameState) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// GameDelta is a change to a game.
+type GameDelta struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	GameId  string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Version int32                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// move is the move that was played, if any, and grid_owner the owner
+	// of its subgrid, if it has one.
+	Move      *Move  `protobuf:"bytes,3,opt,name=move,proto3" json:"move,omitempty"`
+	GridOwner string `protobuf:"bytes,4,opt,name=grid_owner,json=gridOwner,proto3" json:"grid_owner,omitempty"`
+	// After a move, turn is the player to move, and playable_grids the
+	// subgrids they may play in.
+	Turn          string           `protobuf:"bytes,5,opt,name=turn,proto3" json:"turn,omitempty"`
+	PlayableGrids []*SubCoordinate `protobuf:"bytes,6,rep,name=playable_grids,json=playableGrids,proto3" json:"playable_grids,omitempty"`
+	Spectators    int32            `protobuf:"varint,7,opt,name=spectators,proto3" json:"spectators,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameDelta) Reset() {
+	*x = GameDelta{}
+	mi := &file_uttt_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameDelta) ProtoMessage() {}
+
+func (x *GameDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameDelta.ProtoReflect.Descriptor instead.
+func (*GameDelta) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GameDelta) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *GameDelta) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *GameDelta) GetMove() *Move {
+	if x != nil {
+		return x.Move
+	}
+	return nil
+}
+
+func (x *GameDelta) GetGridOwner() string {
+	if x != nil {
+		return x.GridOwner
+	}
+	return ""
+}
+
+func (x *GameDelta) GetTurn() string {
+	if x != nil {
+		return x.Turn
+	}
+	return ""
+}
+
+func (x *GameDelta) GetPlayableGrids() []*SubCoordinate {
+	if x != nil {
+		return x.PlayableGrids
+	}
+	return nil
+}
+
+func (x *GameDelta) GetSpectators() int32 {
+	if x != nil {
+		return x.Spectators
+	}
+	return 0
+}
+
+type SubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// deltas has changes sent as GameDeltas instead of the full
+	// GameState.
+	Deltas        bool `protobuf:"varint,1,opt,name=deltas,proto3" json:"deltas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_uttt_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SubscribeRequest) GetDeltas() bool {
+	if x != nil {
+		return x.Deltas
+	}
+	return false
+}
+
+type GameEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*GameEvent_State
+	//	*GameEvent_Delta
+	Event         isGameEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameEvent) Reset() {
+	*x = GameEvent{}
+	mi := &file_uttt_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameEvent) ProtoMessage() {}
+
+func (x *GameEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameEvent.ProtoReflect.Descriptor instead.
+func (*GameEvent) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GameEvent) GetEvent() isGameEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *GameEvent) GetState() *GameState {
+	if x != nil {
+		if x, ok := x.Event.(*GameEvent_State); ok {
+			return x.State
+		}
+	}
+	return nil
+}
+
+func (x *GameEvent) GetDelta() *GameDelta {
+	if x != nil {
+		if x, ok := x.Event.(*GameEvent_Delta); ok {
+			return x.Delta
+		}
+	}
+	return nil
+}
+
+type isGameEvent_Event interface {
+	isGameEvent_Event()
+}
+
+type GameEvent_State struct {
+	State *GameState `protobuf:"bytes,1,opt,name=state,proto3,oneof"`
+}
+
+type GameEvent_Delta struct {
+	Delta *GameDelta `protobuf:"bytes,2,opt,name=delta,proto3,oneof"`
+}
+
+func (*GameEvent_State) isGameEvent_Event() {}
+
+func (*GameEvent_Delta) isGameEvent_Event() {}
+
+type PlayMoveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Coordinate    *Coordinate            `protobuf:"bytes,2,opt,name=coordinate,proto3" json:"coordinate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlayMoveRequest) Reset() {
+	*x = PlayMoveRequest{}
+	mi := &file_uttt_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlayMoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlayMoveRequest) ProtoMessage() {}
+
+func (x *PlayMoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlayMoveRequest.ProtoReflect.Descriptor instead.
+func (*PlayMoveRequest) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PlayMoveRequest) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *PlayMoveRequest) GetCoordinate() *Coordinate {
+	if x != nil {
+		return x.Coordinate
+	}
+	return nil
+}
+
+type ChallengeRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	OpponentId string                 `protobuf:"bytes,1,opt,name=opponent_id,json=opponentId,proto3" json:"opponent_id,omitempty"`
+	// color is "x", "o", "random" or "alternate". Empty plays X.
+	Color         string        `protobuf:"bytes,2,opt,name=color,proto3" json:"color,omitempty"`
+	Settings      *GameSettings `protobuf:"bytes,3,opt,name=settings,proto3" json:"settings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChallengeRequest) Reset() {
+	*x = ChallengeRequest{}
+	mi := &file_uttt_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChallengeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChallengeRequest) ProtoMessage() {}
+
+func (x *ChallengeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChallengeRequest.ProtoReflect.Descriptor instead.
+func (*ChallengeRequest) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ChallengeRequest) GetOpponentId() string {
+	if x != nil {
+		return x.OpponentId
+	}
+	return ""
+}
+
+func (x *ChallengeRequest) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *ChallengeRequest) GetSettings() *GameSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type ChallengeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChallengeResponse) Reset() {
+	*x = ChallengeResponse{}
+	mi := &file_uttt_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChallengeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChallengeResponse) ProtoMessage() {}
+
+func (x *ChallengeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_uttt_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChallengeResponse.ProtoReflect.Descriptor instead.
+func (*ChallengeResponse) Descriptor() ([]byte, []int) {
+	return file_uttt_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ChallengeResponse) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+var File_uttt_proto protoreflect.FileDescriptor
+
+const file_uttt_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"uttt.proto\x12\x04uttt\"'\n" +
+	"\fLoginRequest\x12\x17\n" +
+	"\aapi_key\x18\x01 \x01(\tR\x06apiKey\"\x88\x01\n" +
+	"\x06Rating\x12\x12\n" +
+	"\x04pool\x18\x01 \x01(\tR\x04pool\x12\x16\n" +
+	"\x06rating\x18\x02 \x01(\x01R\x06rating\x12\x1c\n" +
+	"\tdeviation\x18\x03 \x01(\x01R\tdeviation\x12\x1e\n" +
+	"\n" +
+	"volatility\x18\x04 \x01(\x01R\n" +
+	"volatility\x12\x14\n" +
+	"\x05games\x18\x05 \x01(\x05R\x05games\"i\n" +
+	"\x06Player\x12\x1b\n" +
+	"\tplayer_id\x18\x01 \x01(\tR\bplayerId\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12&\n" +
+	"\aratings\x18\x03 \x03(\v2\f.uttt.RatingR\aratings\"\\\n" +
+	"\x13LookupPlayerRequest\x12\x1d\n" +
+	"\tplayer_id\x18\x01 \x01(\tH\x00R\bplayerId\x12\x1c\n" +
+	"\busername\x18\x02 \x01(\tH\x00R\busernameB\b\n" +
+	"\x06player\"+\n" +
+	"\rSubCoordinate\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x05R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x05R\x01y\"~\n" +
+	"\n" +
+	"Coordinate\x124\n" +
+	"\vgame_square\x18\x01 \x01(\v2\x13.uttt.SubCoordinateR\n" +
+	"gameSquare\x12:\n" +
+	"\x0esubgrid_square\x18\x02 \x01(\v2\x13.uttt.SubCoordinateR\rsubgridSquare\"U\n" +
+	"\x04Move\x12\x1b\n" +
+	"\tplayer_id\x18\x01 \x01(\tR\bplayerId\x120\n" +
+	"\n" +
+	"coordinate\x18\x02 \x01(\v2\x10.uttt.CoordinateR\n" +
+	"coordinate\"}\n" +
+	"\fGameSettings\x12\x18\n" +
+	"\avariant\x18\x01 \x01(\tR\avariant\x12!\n" +
+	"\ftime_control\x18\x02 \x01(\tR\vtimeControl\x12\x16\n" +
+	"\x06casual\x18\x03 \x01(\bR\x06casual\x12\x18\n" +
+	"\aprivate\x18\x04 \x01(\bR\aprivate\":\n" +
+	"\x06Square\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12\x1a\n" +
+	"\bplayable\x18\x02 \x01(\bR\bplayable\"D\n" +
+	"\x04Grid\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12&\n" +
+	"\asquares\x18\x02 \x03(\v2\f.uttt.SquareR\asquares\"\xc6\x02\n" +
+	"\tGameState\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12\x19\n" +
+	"\bplayer_x\x18\x02 \x01(\tR\aplayerX\x12\x19\n" +
+	"\bplayer_o\x18\x03 \x01(\tR\aplayerO\x12\"\n" +
+	"\rplayer_x_name\x18\x04 \x01(\tR\vplayerXName\x12\"\n" +
+	"\rplayer_o_name\x18\x05 \x01(\tR\vplayerOName\x12\x16\n" +
+	"\x06victor\x18\x06 \x01(\tR\x06victor\x12 \n" +
+	"\x05grids\x18\a \x03(\v2\n" +
+	".uttt.GridR\x05grids\x12.\n" +
+	"\bsettings\x18\b \x01(\v2\x12.uttt.GameSettingsR\bsettings\x12\x1e\n" +
+	"\n" +
+	"spectators\x18\t \x01(\x05R\n" +
+	"spectators\x12\x18\n" +
+	"\aversion\x18\n" +
+	" \x01(\x05R\aversion\"\xed\x01\n" +
+	"\tGameDelta\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\x12\x1e\n" +
+	"\x04move\x18\x03 \x01(\v2\n" +
+	".uttt.MoveR\x04move\x12\x1d\n" +
+	"\n" +
+	"grid_owner\x18\x04 \x01(\tR\tgridOwner\x12\x12\n" +
+	"\x04turn\x18\x05 \x01(\tR\x04turn\x12:\n" +
+	"\x0eplayable_grids\x18\x06 \x03(\v2\x13.uttt.SubCoordinateR\rplayableGrids\x12\x1e\n" +
+	"\n" +
+	"spectators\x18\a \x01(\x05R\n" +
+	"spectators\"*\n" +
+	"\x10SubscribeRequest\x12\x16\n" +
+	"\x06deltas\x18\x01 \x01(\bR\x06deltas\"f\n" +
+	"\tGameEvent\x12'\n" +
+	"\x05state\x18\x01 \x01(\v2\x0f.uttt.GameStateH\x00R\x05state\x12'\n" +
+	"\x05delta\x18\x02 \x01(\v2\x0f.uttt.GameDeltaH\x00R\x05deltaB\a\n" +
+	"\x05event\"\\\n" +
+	"\x0fPlayMoveRequest\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId\x120\n" +
+	"\n" +
+	"coordinate\x18\x02 \x01(\v2\x10.uttt.CoordinateR\n" +
+	"coordinate\"y\n" +
+	"\x10ChallengeRequest\x12\x1f\n" +
+	"\vopponent_id\x18\x01 \x01(\tR\n" +
+	"opponentId\x12\x14\n" +
+	"\x05color\x18\x02 \x01(\tR\x05color\x12.\n" +
+	"\bsettings\x18\x03 \x01(\v2\x12.uttt.GameSettingsR\bsettings\",\n" +
+	"\x11ChallengeResponse\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId2\x94\x02\n" +
+	"\x04Uttt\x12)\n" +
+	"\x05Login\x12\x12.uttt.LoginRequest\x1a\f.uttt.Player\x126\n" +
+	"\tSubscribe\x12\x16.uttt.SubscribeRequest\x1a\x0f.uttt.GameEvent0\x01\x122\n" +
+	"\bPlayMove\x12\x15.uttt.PlayMoveRequest\x1a\x0f.uttt.GameState\x12<\n" +
+	"\tChallenge\x12\x16.uttt.ChallengeRequest\x1a\x17.uttt.ChallengeResponse\x127\n" +
+	"\fLookupPlayer\x12\x19.uttt.LookupPlayerRequest\x1a\f.uttt.PlayerB(Z&github.com/heartles/uttt/server/rpc/pbb\x06proto3"
+
+var (
+	file_uttt_proto_rawDescOnce sync.Once
+	file_uttt_proto_rawDescData []byte
+)
+
+func file_uttt_proto_rawDescGZIP() []byte {
+	file_uttt_proto_rawDescOnce.Do(func() {
+		file_uttt_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_uttt_proto_rawDesc), len(file_uttt_proto_rawDesc)))
+	})
+	return file_uttt_proto_rawDescData
+}
+
+var file_uttt_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_uttt_proto_goTypes = []any{
+	(*LoginRequest)(nil),        // 0: uttt.LoginRequest
+	(*Rating)(nil),              // 1: uttt.Rating
+	(*Player)(nil),              // 2: uttt.Player
+	(*LookupPlayerRequest)(nil), // 3: uttt.LookupPlayerRequest
+	(*SubCoordinate)(nil),       // 4: uttt.SubCoordinate
+	(*Coordinate)(nil),          // 5: uttt.Coordinate
+	(*Move)(nil),                // 6: uttt.Move
+	(*GameSettings)(nil),        // 7: uttt.GameSettings
+	(*Square)(nil),              // 8: uttt.Square
+	(*Grid)(nil),                // 9: uttt.Grid
+	(*GameState)(nil),           // 10: uttt.GameState
+	(*GameDelta)(nil),           // 11: uttt.GameDelta
+	(*SubscribeRequest)(nil),    // 12: uttt.SubscribeRequest
+	(*GameEvent)(nil),           // 13: uttt.GameEvent
+	(*PlayMoveRequest)(nil),     // 14: uttt.PlayMoveRequest
+	(*ChallengeRequest)(nil),    // 15: uttt.ChallengeRequest
+	(*ChallengeResponse)(nil),   // 16: uttt.ChallengeResponse
+}
+var file_uttt_proto_depIdxs = []int32{
+	1,  // 0: uttt.Player.ratings:type_name -> uttt.Rating
+	4,  // 1: uttt.Coordinate.game_square:type_name -> uttt.SubCoordinate
+	4,  // 2: uttt.Coordinate.subgrid_square:type_name -> uttt.SubCoordinate
+	5,  // 3: uttt.Move.coordinate:type_name -> uttt.Coordinate
+	8,  // 4: uttt.Grid.squares:type_name -> uttt.Square
+	9,  // 5: uttt.GameState.grids:type_name -> uttt.Grid
+	7,  // 6: uttt.GameState.settings:type_name -> uttt.GameSettings
+	6,  // 7: uttt.GameDelta.move:type_name -> uttt.Move
+	4,  // 8: uttt.GameDelta.playable_grids:type_name -> uttt.SubCoordinate
+	10, // 9: uttt.GameEvent.state:type_name -> uttt.GameState
+	11, // 10: uttt.GameEvent.delta:type_name -> uttt.GameDelta
+	5,  // 11: uttt.PlayMoveRequest.coordinate:type_name -> uttt.Coordinate
+	7,  // 12: uttt.ChallengeRequest.settings:type_name -> uttt.GameSettings
+	0,  // 13: uttt.Uttt.Login:input_type -> uttt.LoginRequest
+	12, // 14: uttt.Uttt.Subscribe:input_type -> uttt.SubscribeRequest
+	14, // 15: uttt.Uttt.PlayMove:input_type -> uttt.PlayMoveRequest
+	15, // 16: uttt.Uttt.Challenge:input_type -> uttt.ChallengeRequest
+	3,  // 17: uttt.Uttt.LookupPlayer:input_type -> uttt.LookupPlayerRequest
+	2,  // 18: uttt.Uttt.Login:output_type -> uttt.Player
+	13, // 19: uttt.Uttt.Subscribe:output_type -> uttt.GameEvent
+	10, // 20: uttt.Uttt.PlayMove:output_type -> uttt.GameState
+	16, // 21: uttt.Uttt.Challenge:output_type -> uttt.ChallengeResponse
+	2,  // 22: uttt.Uttt.LookupPlayer:output_type -> uttt.Player
+	18, // [18:23] is the sub-list for method output_type
+	13, // [13:18] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_uttt_proto_init() }
+func file_uttt_proto_init() {
+	if File_uttt_proto != nil {
+		return
+	}
+	file_uttt_proto_msgTypes[3].OneofWrappers = []any{
+		(*LookupPlayerRequest_PlayerId)(nil),
+		(*LookupPlayerRequest_Username)(nil),
+	}
+	file_uttt_proto_msgTypes[13].OneofWrappers = []any{
+		(*GameEvent_State)(nil),
+		(*GameEvent_Delta)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_uttt_proto_rawDesc), len(file_uttt_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_uttt_proto_goTypes,
+		DependencyIndexes: file_uttt_proto_depIdxs,
+		MessageInfos:      file_uttt_proto_msgTypes,
+	}.Build()
+	File_uttt_proto = out.File
+	file_uttt_proto_goTypes = nil
+	file_uttt_proto_depIdxs = nil
+}
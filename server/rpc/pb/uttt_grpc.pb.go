@@ -0,0 +1,299 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: uttt.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Uttt_Login_FullMethodName        = "/uttt.Uttt/Login"
+	Uttt_Subscribe_FullMethodName    = "/uttt.Uttt/Subscribe"
+	Uttt_PlayMove_FullMethodName     = "/uttt.Uttt/PlayMove"
+	Uttt_Challenge_FullMethodName    = "/uttt.Uttt/Challenge"
+	Uttt_LookupPlayer_FullMethodName = "/uttt.Uttt/LookupPlayer"
+)
+
+// UtttClient is the client API for Uttt service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Uttt plays ultimate tic-tac-toe, for bots and other services. Every
+// call but Login must carry an API key as "authorization: Bearer <key>"
+// metadata.
+type UtttClient interface {
+	// Login checks an API key, and returns the player it belongs to.
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*Player, error)
+	// Subscribe sends the state of each of the player's unfinished
+	// games, then every change to them and every game they start, until
+	// the call is canceled.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GameEvent], error)
+	// PlayMove plays a move as the player, and returns the game after it.
+	PlayMove(ctx context.Context, in *PlayMoveRequest, opts ...grpc.CallOption) (*GameState, error)
+	// Challenge starts a game against another player.
+	Challenge(ctx context.Context, in *ChallengeRequest, opts ...grpc.CallOption) (*ChallengeResponse, error)
+	// LookupPlayer finds a player by ID or username.
+	LookupPlayer(ctx context.Context, in *LookupPlayerRequest, opts ...grpc.CallOption) (*Player, error)
+}
+
+type utttClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUtttClient(cc grpc.ClientConnInterface) UtttClient {
+	return &utttClient{cc}
+}
+
+func (c *utttClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*Player, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Player)
+	err := c.cc.Invoke(ctx, Uttt_Login_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *utttClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GameEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Uttt_ServiceDesc.Streams[0], Uttt_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, GameEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uttt_SubscribeClient = grpc.ServerStreamingClient[GameEvent]
+
+func (c *utttClient) PlayMove(ctx context.Context, in *PlayMoveRequest, opts ...grpc.CallOption) (*GameState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GameState)
+	err := c.cc.Invoke(ctx, Uttt_PlayMove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *utttClient) Challenge(ctx context.Context, in *ChallengeRequest, opts ...grpc.CallOption) (*ChallengeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChallengeResponse)
+	err := c.cc.Invoke(ctx, Uttt_Challenge_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *utttClient) LookupPlayer(ctx context.Context, in *LookupPlayerRequest, opts ...grpc.CallOption) (*Player, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Player)
+	err := c.cc.Invoke(ctx, Uttt_LookupPlayer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UtttServer is the server API for Uttt service.
+// All implementations must embed UnimplementedUtttServer
+// for forward compatibility.
+//
+// Uttt plays ultimate tic-tac-toe, for bots and other services. Every
+// call but Login must carry an API key as "authorization: Bearer <key>"
+// metadata.
+type UtttServer interface {
+	// Login checks an API key, and returns the player it belongs to.
+	Login(context.Context, *LoginRequest) (*Player, error)
+	// Subscribe sends the state of each of the player's unfinished
+	// games, then every change to them and every game they start, until
+	// the call is canceled.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[GameEvent]) error
+	// PlayMove plays a move as the player, and returns the game after it.
+	PlayMove(context.Context, *PlayMoveRequest) (*GameState, error)
+	// Challenge starts a game against another player.
+	Challenge(context.Context, *ChallengeRequest) (*ChallengeResponse, error)
+	// LookupPlayer finds a player by ID or username.
+	LookupPlayer(context.Context, *LookupPlayerRequest) (*Player, error)
+	mustEmbedUnimplementedUtttServer()
+}
+
+// UnimplementedUtttServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUtttServer struct{}
+
+func (UnimplementedUtttServer) Login(context.Context, *LoginRequest) (*Player, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedUtttServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[GameEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedUtttServer) PlayMove(context.Context, *PlayMoveRequest) (*GameState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlayMove not implemented")
+}
+func (UnimplementedUtttServer) Challenge(context.Context, *ChallengeRequest) (*ChallengeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Challenge not implemented")
+}
+func (UnimplementedUtttServer) LookupPlayer(context.Context, *LookupPlayerRequest) (*Player, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupPlayer not implemented")
+}
+func (UnimplementedUtttServer) mustEmbedUnimplementedUtttServer() {}
+func (UnimplementedUtttServer) testEmbeddedByValue()              {}
+
+// UnsafeUtttServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UtttServer will
+// result in compilation errors.
+type UnsafeUtttServer interface {
+	mustEmbedUnimplementedUtttServer()
+}
+
+func RegisterUtttServer(s grpc.ServiceRegistrar, srv UtttServer) {
+	// If the following call pancis, it indicates UnimplementedUtttServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Uttt_ServiceDesc, srv)
+}
+
+func _Uttt_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UtttServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uttt_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UtttServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Uttt_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UtttServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, GameEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uttt_SubscribeServer = grpc.ServerStreamingServer[GameEvent]
+
+func _Uttt_PlayMove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlayMoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UtttServer).PlayMove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uttt_PlayMove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UtttServer).PlayMove(ctx, req.(*PlayMoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Uttt_Challenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UtttServer).Challenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uttt_Challenge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UtttServer).Challenge(ctx, req.(*ChallengeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Uttt_LookupPlayer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupPlayerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UtttServer).LookupPlayer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uttt_LookupPlayer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UtttServer).LookupPlayer(ctx, req.(*LookupPlayerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Uttt_ServiceDesc is the grpc.ServiceDesc for Uttt service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Uttt_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "uttt.Uttt",
+	HandlerType: (*UtttServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _Uttt_Login_Handler,
+		},
+		{
+			MethodName: "PlayMove",
+			Handler:    _Uttt_PlayMove_Handler,
+		},
+		{
+			MethodName: "Challenge",
+			Handler:    _Uttt_Challenge_Handler,
+		},
+		{
+			MethodName: "LookupPlayer",
+			Handler:    _Uttt_LookupPlayer_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Uttt_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "uttt.proto",
+}
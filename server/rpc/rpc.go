@@ -0,0 +1,137 @@
+// Package rpc serves the game over gRPC, for bots and other services.
+// The service is defined in pb/uttt.proto. Calls act as the player
+// whose API key they carry as "authorization: Bearer <key>" metadata
+package rpc
+
+//go:generate protoc -I pb --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative uttt.proto
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/rpc/pb"
+	"github.com/heartles/uttt/server/store"
+)
+
+type service struct {
+	pb.UnimplementedUtttServer
+	games *store.GameService
+}
+
+// NewServer returns a gRPC server for the Uttt service, backed by the
+// given game service
+func NewServer(games *store.GameService) *grpc.Server {
+	s := &service{games: games}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(s.authenticateUnary),
+		grpc.StreamInterceptor(s.authenticateStream),
+	)
+	pb.RegisterUtttServer(server, s)
+	return server
+}
+
+// statusCodes maps the errors a call can fail with to the code they
+// are reported with. Any other error is internal
+var statusCodes = map[error]codes.Code{
+	game.ErrSquarePlayed:      codes.FailedPrecondition,
+	game.ErrWrongTurn:         codes.FailedPrecondition,
+	game.ErrWrongSubgrid:      codes.FailedPrecondition,
+	game.ErrGameOver:          codes.FailedPrecondition,
+	game.ErrInvalidPlayer:     codes.PermissionDenied,
+	game.ErrInvalidCoordinate: codes.InvalidArgument,
+
+	store.ErrInvalidAPIKey:   codes.Unauthenticated,
+	store.ErrGameNotFound:    codes.NotFound,
+	store.ErrPrivateGame:     codes.PermissionDenied,
+	store.ErrBlocked:         codes.PermissionDenied,
+	store.ErrInvalidSettings: codes.InvalidArgument,
+	store.ErrInvalidColor:    codes.InvalidArgument,
+}
+
+// statusError converts an error from the game or store packages into
+// a gRPC status. The details of internal errors are only logged
+func statusError(err error) error {
+	code, ok := statusCodes[err]
+	if !ok {
+		fmt.Println(err)
+		return status.Error(codes.Internal, "error processing call")
+	}
+	return status.Error(code, err.Error())
+}
+
+func (s *service) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Player, error) {
+	playerID, err := s.games.APIKeyPlayer(req.ApiKey)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return s.lookup(s.games.TryLookupPlayerUUID(playerID))
+}
+
+func (s *service) LookupPlayer(ctx context.Context, req *pb.LookupPlayerRequest) (*pb.Player, error) {
+	switch p := req.Player.(type) {
+	case *pb.LookupPlayerRequest_PlayerId:
+		return s.lookup(s.games.TryLookupPlayerUUID(p.PlayerId))
+	case *pb.LookupPlayerRequest_Username:
+		return s.lookup(s.games.TryLookupPlayerUsername(p.Username))
+	}
+	return nil, status.Error(codes.InvalidArgument, "must specify either username or player ID")
+}
+
+func (s *service) lookup(player *store.Player, err error) (*pb.Player, error) {
+	if err != nil {
+		return nil, statusError(err)
+	} else if player == nil {
+		return nil, status.Error(codes.NotFound, "player does not exist")
+	}
+
+	ratings, err := s.games.PlayerRatings(player.UUID)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return playerMessage(player, ratings), nil
+}
+
+func (s *service) PlayMove(ctx context.Context, req *pb.PlayMoveRequest) (*pb.GameState, error) {
+	state, err := s.games.PlayMove(req.GameId, game.Move{
+		PlayerID:   player(ctx),
+		Coordinate: coordinate(req.Coordinate),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return gameStateMessage(state), nil
+}
+
+func (s *service) Challenge(ctx context.Context, req *pb.ChallengeRequest) (*pb.ChallengeResponse, error) {
+	opponent, err := s.games.TryLookupPlayerUUID(req.OpponentId)
+	if err != nil {
+		return nil, statusError(err)
+	} else if opponent == nil {
+		return nil, status.Error(codes.NotFound, "player does not exist")
+	}
+
+	settings := store.GameSettings{}
+	if req.Settings != nil {
+		settings = store.GameSettings{
+			Variant:     req.Settings.Variant,
+			TimeControl: req.Settings.TimeControl,
+			Casual:      req.Settings.Casual,
+			Private:     req.Settings.Private,
+		}
+	}
+
+	gameID, err := s.games.NewGame(player(ctx), opponent.UUID, store.ColorChoice(req.Color), settings)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.ChallengeResponse{GameId: gameID}, nil
+}
@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/heartles/uttt/server/rpc/pb"
+	"github.com/heartles/uttt/server/store"
+)
+
+// harness runs the service in-process, over an in-memory connection
+type harness struct {
+	games  *store.GameService
+	client pb.UtttClient
+}
+
+func newHarness(t *testing.T) *harness {
+	games, err := store.NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(games)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &harness{games, pb.NewUtttClient(conn)}
+}
+
+// player creates a player, and returns their ID and an API key
+func (h *harness) player(t *testing.T, name string) (string, string) {
+	player, err := h.games.CreatePlayer(name, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := h.games.CreateAPIKey(player.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return player.UUID, key
+}
+
+// as returns a context for calls made with the given API key
+func as(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+}
+
+func move(gx, gy, sx, sy int32) *pb.Coordinate {
+	return &pb.Coordinate{
+		GameSquare:    &pb.SubCoordinate{X: gx, Y: gy},
+		SubgridSquare: &pb.SubCoordinate{X: sx, Y: sy},
+	}
+}
+
+func TestLogin(t *testing.T) {
+	h := newHarness(t)
+	aliceID, key := h.player(t, "alice")
+
+	player, err := h.client.Login(context.Background(), &pb.LoginRequest{ApiKey: key})
+	if err != nil || player.PlayerId != aliceID || player.Username != "alice" {
+		t.Errorf("got %v, %v", player, err)
+	}
+
+	_, err = h.client.Login(context.Background(), &pb.LoginRequest{ApiKey: "nope"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("got %v logging in with a bad key", err)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	h := newHarness(t)
+	h.player(t, "alice")
+
+	req := &pb.LookupPlayerRequest{Player: &pb.LookupPlayerRequest_Username{Username: "alice"}}
+	for _, ctx := range []context.Context{context.Background(), as("nope")} {
+		_, err := h.client.LookupPlayer(ctx, req)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("got %v without a valid key", err)
+		}
+	}
+}
+
+func TestLookupPlayer(t *testing.T) {
+	h := newHarness(t)
+	aliceID, key := h.player(t, "alice")
+
+	byName, err := h.client.LookupPlayer(as(key), &pb.LookupPlayerRequest{
+		Player: &pb.LookupPlayerRequest_Username{Username: "alice"},
+	})
+	if err != nil || byName.PlayerId != aliceID {
+		t.Errorf("by username: got %v, %v", byName, err)
+	}
+
+	byID, err := h.client.LookupPlayer(as(key), &pb.LookupPlayerRequest{
+		Player: &pb.LookupPlayerRequest_PlayerId{PlayerId: aliceID},
+	})
+	if err != nil || byID.Username != "alice" {
+		t.Errorf("by ID: got %v, %v", byID, err)
+	}
+
+	_, err = h.client.LookupPlayer(as(key), &pb.LookupPlayerRequest{
+		Player: &pb.LookupPlayerRequest_Username{Username: "nobody"},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("got %v looking up a missing player", err)
+	}
+}
+
+func TestChallengeAndPlay(t *testing.T) {
+	h := newHarness(t)
+	aliceID, aliceKey := h.player(t, "alice")
+	bobID, bobKey := h.player(t, "bob")
+
+	ctx, cancel := context.WithTimeout(as(bobKey), 5*time.Second)
+	defer cancel()
+	events, err := h.client.Subscribe(ctx, &pb.SubscribeRequest{Deltas: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	challenge, err := h.client.Challenge(as(aliceKey), &pb.ChallengeRequest{OpponentId: bobID, Color: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := events.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := event.GetState()
+	if state.GetGameId() != challenge.GameId || state.PlayerX != aliceID || len(state.Grids) != 9 {
+		t.Fatalf("got %v for the new game", event)
+	}
+
+	_, err = h.client.PlayMove(as(bobKey), &pb.PlayMoveRequest{GameId: challenge.GameId, Coordinate: move(2, 2, 2, 2)})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("got %v playing out of turn", err)
+	}
+
+	after, err := h.client.PlayMove(as(aliceKey), &pb.PlayMoveRequest{GameId: challenge.GameId, Coordinate: move(2, 2, 1, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner := after.Grids[4].Squares[0].Owner; owner != aliceID {
+		t.Errorf("square owned by %q after the move", owner)
+	}
+
+	event, err = events.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	delta := event.GetDelta()
+	if delta.GetMove().GetPlayerId() != aliceID || delta.Turn != bobID || len(delta.PlayableGrids) != 1 ||
+		delta.PlayableGrids[0].X != 1 || delta.PlayableGrids[0].Y != 1 {
+		t.Errorf("got %v after the move", event)
+	}
+}
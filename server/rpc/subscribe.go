@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"google.golang.org/grpc/status"
+
+	"github.com/heartles/uttt/server/rpc/pb"
+	"github.com/heartles/uttt/server/store"
+)
+
+// subscription follows a player's games for a Subscribe call
+type subscription struct {
+	games    *store.GameService
+	stream   pb.Uttt_SubscribeServer
+	playerID string
+	deltas   bool
+	// versions holds the version of each game the client was last
+	// sent, so that it can be sent just the changes since
+	versions map[string]int
+}
+
+// send brings the client up to date with a game, with deltas if it
+// asked for them and they are still available, and the full state
+// otherwise
+func (sub *subscription) send(g *store.Game) error {
+	if version, ok := sub.versions[g.UUID()]; ok && sub.deltas {
+		deltas, current, ok := g.DeltasSince(version)
+		if ok {
+			for _, d := range deltas {
+				err := sub.stream.Send(&pb.GameEvent{
+					Event: &pb.GameEvent_Delta{Delta: gameDeltaMessage(d)},
+				})
+				if err != nil {
+					return err
+				}
+			}
+			sub.versions[g.UUID()] = current
+			return nil
+		}
+	}
+
+	state, err := g.GetGameState(sub.playerID)
+	if err != nil {
+		return statusError(err)
+	}
+
+	sub.versions[g.UUID()] = state.Version
+	return sub.stream.Send(&pb.GameEvent{
+		Event: &pb.GameEvent_State{State: gameStateMessage(state)},
+	})
+}
+
+func (s *service) Subscribe(req *pb.SubscribeRequest, stream pb.Uttt_SubscribeServer) error {
+	ctx := stream.Context()
+	sub := &subscription{
+		games:    s.games,
+		stream:   stream,
+		playerID: player(ctx),
+		deltas:   req.Deltas,
+		versions: map[string]int{},
+	}
+
+	err := s.games.FollowPlayerGames(ctx, sub.playerID, sub.send)
+	if _, ok := status.FromError(err); !ok {
+		// send's errors are already statuses, so this one is from
+		// opening the player's games
+		return statusError(err)
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package socket
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/heartles/uttt/server/engine"
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// analysisLines is how many moves an Analyze returns unless it asks for
+// more, up to maxAnalysisLines
+const (
+	analysisLines    = 3
+	maxAnalysisLines = 5
+)
+
+// analysisRateLimit analyses may be asked for by a player in any
+// analysisRateWindow
+const (
+	analysisRateLimit  = 5
+	analysisRateWindow = time.Minute
+)
+
+// analysisResult is a finished analysis, to be sent as the response to
+// the request that asked for it
+type analysisResult struct {
+	requestID int
+	response  interface{}
+	err       error
+}
+
+// analyzer runs the analyses asked for by every session. Searches take
+// a while, so they are run outside of the sessions' message loops, and
+// no more at once than there are CPUs
+type analyzer struct {
+	search   engine.Builtin
+	moveTime time.Duration
+	slots    chan struct{}
+
+	mutex sync.Mutex
+	// recent holds the times of each player's latest analyses, for rate
+	// limiting
+	recent map[string][]time.Time
+}
+
+func newAnalyzer(moveTime time.Duration) *analyzer {
+	return &analyzer{
+		moveTime: moveTime,
+		slots:    make(chan struct{}, runtime.NumCPU()),
+		recent:   map[string][]time.Time{},
+	}
+}
+
+// allow records an analysis by playerID, unless they have already asked
+// for analysisRateLimit within analysisRateWindow
+func (a *analyzer) allow(playerID string, now time.Time) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	recent := []time.Time{}
+	for _, t := range a.recent[playerID] {
+		if now.Sub(t) < analysisRateWindow {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= analysisRateLimit {
+		a.recent[playerID] = recent
+		return false
+	}
+	a.recent[playerID] = append(recent, now)
+	return true
+}
+
+// analyze searches a position once a slot is free
+func (a *analyzer) analyze(position string, lines int) (*engine.Analysis, error) {
+	a.slots <- struct{}{}
+	defer func() { <-a.slots }()
+	return a.search.Analyze(position, lines, a.moveTime)
+}
+
+// startAnalysis checks an Analyze and starts its search. The response is
+// sent to the session's analysisCh once the search is done, since only
+// the message loop may write to the client
+func (s *Server) startAnalysis(conn *clientConn, requestID int, request *Analyze) error {
+	if s.analyzer.moveTime <= 0 {
+		return requestFailed(CodeAnalysisDisabled, "analysis is disabled")
+	}
+	if (request.GameID == "") == (request.Position == "") {
+		return requestFailed(CodeInvalidRequest, "must specify either gameID or position")
+	}
+	if request.Lines < 0 || request.Lines > maxAnalysisLines {
+		return requestFailed(CodeInvalidRequest, "invalid number of lines")
+	}
+	if conn.analyzing {
+		return requestFailed(CodeAnalysisRateLimited, "an analysis is already running")
+	}
+
+	position, err := s.analysisPosition(conn, request)
+	if err != nil {
+		return err
+	}
+
+	if !s.analyzer.allow(conn.playerID, time.Now()) {
+		return requestFailed(CodeAnalysisRateLimited, "too many analyses")
+	}
+
+	lines := request.Lines
+	if lines == 0 {
+		lines = analysisLines
+	}
+
+	conn.analyzing = true
+	go func() {
+		result := analysisResult{requestID: requestID}
+		analysis, err := s.analyzer.analyze(position, lines)
+		if err != nil {
+			result.err = requestError(err)
+		} else {
+			result.response = Analyze{
+				GameID:   request.GameID,
+				Position: position,
+				Lines:    lines,
+				Analysis: analysis,
+			}
+		}
+		conn.analysisCh <- result
+	}()
+	return nil
+}
+
+// analysisPosition returns the position an Analyze asks about
+func (s *Server) analysisPosition(conn *clientConn, request *Analyze) (string, error) {
+	if request.GameID != "" {
+		position, err := s.games.AnalysisPosition(request.GameID, conn.playerID)
+		if err != nil {
+			return "", requestError(err)
+		}
+		return position, nil
+	}
+
+	// any position could be the one on the board, so none are analysed
+	// while the sender is playing a rated game, in any of their sessions
+	playing, err := s.games.PlayingRated(conn.playerID)
+	if err != nil {
+		return "", err
+	} else if playing {
+		return "", requestError(store.ErrAnalysisDisabled)
+	}
+
+	g, err := game.ParsePosition("x", "o", request.Position)
+	if err != nil {
+		return "", requestFailed(CodeInvalidRequest, "invalid position")
+	}
+	if g.IsCompleted() {
+		return "", requestError(game.ErrGameOver)
+	}
+	return g.Position(), nil
+}
@@ -0,0 +1,140 @@
+package socket
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// Subprotocols a client can ask for when connecting, to choose how
+// messages are encoded. Without one, messages are JSON
+const (
+	SubprotocolJSON    = "uttt.json"
+	SubprotocolMsgpack = "uttt.msgpack"
+)
+
+// subprotocols are the subprotocols the server accepts, in order of
+// preference
+var subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}
+
+// codec encodes and decodes the messages sent over a socket. Both
+// codecs share one schema: the messages in messages.go, with fields
+// named by their json tags
+type codec interface {
+	// frameType is the websocket message type messages are sent as
+	frameType() int
+	encode(w io.Writer, v interface{}) error
+	// decodeRequest reads an IncomingSocketMessage. The payload is
+	// decoded by the returned function once its type is known. If
+	// strict, unknown fields are an error
+	decodeRequest(r io.Reader, strict bool) (typ string, requestID int, payload func(interface{}) error, err error)
+}
+
+// codecFor returns the codec negotiated for a socket
+func codecFor(socket *websocket.Conn) codec {
+	if socket.Subprotocol() == SubprotocolMsgpack {
+		return msgpackCodec{}
+	}
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) frameType() int {
+	return websocket.TextMessage
+}
+
+func (jsonCodec) encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) decodeRequest(r io.Reader, strict bool) (string, int, func(interface{}) error, error) {
+	decoder := json.NewDecoder(r)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	var message IncomingSocketMessage
+	err := decoder.Decode(&message)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	payload := func(v interface{}) error {
+		decoder := json.NewDecoder(bytes.NewReader(message.Payload))
+		if strict {
+			decoder.DisallowUnknownFields()
+		}
+		return decoder.Decode(v)
+	}
+	return message.Type, message.RequestID, payload, nil
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) frameType() int {
+	return websocket.BinaryMessage
+}
+
+func (msgpackCodec) encode(w io.Writer, v interface{}) error {
+	return msgpack.NewEncoder(w).UseJSONTag(true).SortMapKeys(true).Encode(v)
+}
+
+func (msgpackCodec) decoder(r io.Reader, strict bool) *msgpack.Decoder {
+	decoder := msgpack.NewDecoder(r).UseJSONTag(true)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder
+}
+
+// msgpackRequest is an IncomingSocketMessage encoded with MessagePack
+type msgpackRequest struct {
+	Type      string         `json:"messageType"`
+	Payload   msgpackPayload `json:"payload"`
+	RequestID int            `json:"requestID"`
+}
+
+// msgpackPayload holds a request's payload until its type is known
+type msgpackPayload struct {
+	raw []byte
+}
+
+func (p *msgpackPayload) DecodeMsgpack(d *msgpack.Decoder) error {
+	v, err := d.DecodeInterface()
+	if err != nil {
+		return err
+	}
+
+	p.raw, err = msgpack.Marshal(v)
+	return err
+}
+
+func (c msgpackCodec) decodeRequest(r io.Reader, strict bool) (string, int, func(interface{}) error, error) {
+	var message msgpackRequest
+	err := c.decoder(r, strict).Decode(&message)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	payload := func(v interface{}) error {
+		return c.decoder(bytes.NewReader(message.Payload.raw), strict).Decode(v)
+	}
+	return message.Type, message.RequestID, payload, nil
+}
+
+// EncodeMsgpack leaves out the standings of a request, as JSON does.
+// msgpack would otherwise encode the fields of the nil Standings as nil
+func (t TournamentStandings) EncodeMsgpack(e *msgpack.Encoder) error {
+	if t.Standings == nil {
+		return e.Encode(struct {
+			TournamentID string `json:"tournamentID"`
+		}{t.TournamentID})
+	}
+
+	type standings TournamentStandings
+	return e.Encode(standings(t))
+}
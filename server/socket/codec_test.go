@@ -0,0 +1,376 @@
+package socket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/heartles/uttt/server/engine"
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func num(f float64) *float64 {
+	return &f
+}
+
+func sampleGameState() store.GameState {
+	state := store.GameState{
+		GameID:             "game",
+		PlayerX:            "x",
+		PlayerO:            "o",
+		PlayerXName:        "alice",
+		PlayerOName:        "bob",
+		Victor:             str("x"),
+		Settings:           store.GameSettings{Variant: "standard", TimeControl: "5+3", Casual: true, Private: true},
+		RematchOf:          str("previous"),
+		Rematch:            str("next"),
+		RematchRequestedBy: str("o"),
+		Series:             store.SeriesScore{Games: 3, Ties: 1, Wins: map[string]int{"x": 2}},
+		PlayerXRating:      &store.Rating{Pool: "standard", Rating: 1500, Deviation: 350, Volatility: 0.06, Games: 1},
+		Spectators:         2,
+		Version:            9,
+		PlayerXPresence:    "online",
+		PlayerOPresence:    "away",
+	}
+	for i := range state.Grids {
+		for j := range state.Grids[i] {
+			state.Grids[i][j].Owner = str("o")
+			for k := range state.Grids[i][j].Squares {
+				for l := range state.Grids[i][j].Squares[k] {
+					state.Grids[i][j].Squares[k][l] = store.SquareState{
+						Owner:    str("x"),
+						Playable: true,
+						Coordinate: game.Coordinate{
+							GameSquare:    game.SubCoordinate{X: j + 1, Y: i + 1},
+							SubgridSquare: game.SubCoordinate{X: l + 1, Y: k + 1},
+						},
+					}
+				}
+			}
+		}
+	}
+	return state
+}
+
+var sampleMove = game.Move{
+	PlayerID: "x",
+	Coordinate: game.Coordinate{
+		GameSquare:    game.SubCoordinate{X: 1, Y: 2},
+		SubgridSquare: game.SubCoordinate{X: 3, Y: 1},
+	},
+}
+
+var sampleChat = store.ChatMessage{
+	Room:       "game",
+	GameID:     "game",
+	SenderID:   "x",
+	SenderName: "alice",
+	Body:       "gg",
+	Sent:       time.Unix(1600000000, 0),
+}
+
+var sampleFriends = []store.Friend{{PlayerID: "o", Username: "bob"}}
+
+var samplePresence = Presence{PlayerID: "o", Username: "bob", Status: "online"}
+
+var sampleSideStats = store.SideStats{Games: 4, Wins: 2, Losses: 1, Ties: 1, WinRate: 0.5}
+
+// sampleMessages has at least one value, with every field set, for each
+// message in messages.go and each payload from other packages
+var sampleMessages = []interface{}{
+	Hello{ProtocolVersion: ProtocolVersion, Capabilities: []string{CapabilityGameDeltas}},
+	LoginRequest{LoginID: "alice", APIKey: "key", SessionToken: "token", LastSequence: 12},
+	NewGame{OpponentID: "o", Color: store.ColorRandom, Variant: "standard", TimeControl: "5+3", Casual: true, Private: true},
+	Spectate{GameID: "game"},
+	StopSpectating{GameID: "game"},
+	GameSnapshot{GameID: "game"},
+	Analyze{
+		GameID:   "game",
+		Position: "9/9/9/9/4X4/9/9/9/9 B2b2",
+		Lines:    1,
+		Analysis: &engine.Analysis{
+			Position: "9/9/9/9/4X4/9/9/9/9 B2b2",
+			Depth:    4,
+			Nodes:    300,
+			Lines:    []engine.Line{{Move: sampleMove.Coordinate, Score: -25, WinIn: -3, PV: []game.Coordinate{sampleMove.Coordinate}}},
+		},
+	},
+	GameReview{
+		GameID: "game",
+		Review: &store.GameReview{
+			GameID: "game",
+			Status: store.ReviewDone,
+			Moves: []store.MoveAnnotation{{
+				Number: 1, Move: sampleMove.Coordinate, Class: store.MoveInaccuracy, Score: -40,
+				Best: sampleMove.Coordinate, BestScore: 30, PV: []game.Coordinate{sampleMove.Coordinate},
+			}},
+		},
+	},
+	JoinChat{Room: "game", GameID: "game"},
+	LeaveChat{Room: "game", GameID: "game"},
+	SendChat{Room: "game", GameID: "game", Body: "gg"},
+	ChatHistory{Room: "game", GameID: "game", Messages: []store.ChatMessage{sampleChat}},
+	SetPresence{Status: "away"},
+	samplePresence,
+	OnlinePlayers{Players: []Presence{samplePresence}},
+	FriendsList{Friends: []Presence{samplePresence}, Incoming: sampleFriends, Outgoing: sampleFriends, Blocked: sampleFriends},
+	FriendRequest{PlayerID: "o"},
+	AcceptFriend{PlayerID: "o"},
+	DeclineFriend{PlayerID: "o"},
+	RemoveFriend{PlayerID: "o"},
+	BlockPlayer{PlayerID: "o"},
+	UnblockPlayer{PlayerID: "o"},
+	PlayerStats{
+		PlayerID:   "x",
+		OpponentID: "o",
+		Stats: &store.PlayerStats{
+			PlayerID:         "x",
+			SideStats:        sampleSideStats,
+			AsX:              sampleSideStats,
+			AsO:              sampleSideStats,
+			LongestWinStreak: 2,
+			CurrentWinStreak: 1,
+			AverageMoves:     40.5,
+		},
+		HeadToHead: &store.HeadToHead{PlayerID: "x", OpponentID: "o", SideStats: sampleSideStats},
+	},
+	Leaderboard{
+		By:     "rating",
+		Pool:   "standard",
+		Offset: 10,
+		Limit:  20,
+		Page: &store.LeaderboardPage{
+			By:      "rating",
+			Pool:    "standard",
+			Offset:  10,
+			Limit:   20,
+			Total:   31,
+			Entries: []store.LeaderboardEntry{{Rank: 11, PlayerID: "x", Username: "alice", Rating: num(1612.5), Wins: 3, Games: 5}},
+		},
+	},
+	CreateTournament{Name: "open", Format: "swiss", Rounds: 5, Variant: "standard", TimeControl: "5+3", Casual: true},
+	JoinTournament{TournamentID: "tournament"},
+	StartTournament{TournamentID: "tournament"},
+	TournamentStandings{TournamentID: "tournament"},
+	TournamentStandings{
+		TournamentID: "tournament",
+		Standings: &tournament.Standings{
+			Tournament: &store.Tournament{
+				ID:           "tournament",
+				Name:         "open",
+				Format:       "swiss",
+				CreatedBy:    "x",
+				Status:       "running",
+				Settings:     store.GameSettings{Variant: "standard"},
+				Rounds:       5,
+				CurrentRound: 2,
+			},
+			Standings: []tournament.Standing{{
+				Rank: 1, PlayerID: "x", Seed: 2, Score: 1.5, Wins: 1, Ties: 1, Byes: 1,
+				Buchholz: 2.5, SonnebornBerger: 1.25,
+			}},
+			Games: []store.TournamentGame{{Round: 1, Board: 1, GameID: "game", PlayerX: "x", PlayerO: "o", Victor: "x"}},
+		},
+	},
+	JoinQueue{Variant: "standard", TimeControl: "5+3", Casual: true, RatingRange: 200},
+	LeaveQueue{},
+	MatchFound{GameID: "game", OpponentID: "o", OpponentName: "bob"},
+	PlayMove{GameID: "game", Coordinate: sampleMove.Coordinate},
+	Rematch{GameID: "game"},
+	DeclineRematch{GameID: "game"},
+	LoginSuccess{Username: "alice", PlayerID: "x", Games: []store.GameState{sampleGameState()}, SessionToken: "token", Resumed: true},
+	UserLookup{Username: "alice", PlayerID: "x", Ratings: []store.Rating{{Pool: "standard", Rating: 1500, Deviation: 350, Volatility: 0.06, Games: 1}}},
+	RatingHistory{PlayerID: "x", Pool: "standard", History: []store.RatingChange{{GameID: "game", Rating: 1520, Deviation: 300, Volatility: 0.06, Recorded: time.Unix(1600000000, 0)}}},
+	Ack{},
+	ErrorMessage{Code: CodeWrongTurn, Message: "not your turn", Recoverable: true},
+
+	sampleGameState(),
+	store.GameDelta{
+		GameID:        "game",
+		Version:       3,
+		Move:          &sampleMove,
+		GridOwner:     str("x"),
+		Turn:          "o",
+		PlayableGrids: []game.SubCoordinate{{X: 3, Y: 1}},
+		Spectators:    1,
+	},
+	sampleChat,
+}
+
+// TestSampleMessages checks that every message in messages.go has a
+// sample, so that new messages are round-tripped too
+func TestSampleMessages(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "messages.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sampled := map[string]bool{}
+	for _, m := range sampleMessages {
+		sampled[reflect.TypeOf(m).Name()] = true
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			name := spec.(*ast.TypeSpec).Name.Name
+			if name == "IncomingSocketMessage" || name == "OutgoingSocketMessage" {
+				continue
+			}
+			if !sampled[name] {
+				t.Errorf("no sample for %v", name)
+			}
+		}
+	}
+}
+
+// roundTrip sends a message through a codec the way the server does,
+// and decodes it the way a client would
+func roundTrip(c codec, message interface{}) (interface{}, error) {
+	typ := reflect.TypeOf(message)
+
+	b := &bytes.Buffer{}
+	err := c.encode(b, OutgoingSocketMessage{
+		Type:      typ.Name(),
+		Payload:   message,
+		RequestID: 5,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name, requestID, payload, err := c.decodeRequest(b, true)
+	if err != nil {
+		return nil, err
+	}
+	if name != typ.Name() || requestID != 5 {
+		return nil, fmt.Errorf("decoded a %v with request ID %v", name, requestID)
+	}
+
+	decoded := reflect.New(typ)
+	err = payload(decoded.Interface())
+	return decoded.Elem().Interface(), err
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codecs := map[string]codec{
+		SubprotocolJSON:    jsonCodec{},
+		SubprotocolMsgpack: msgpackCodec{},
+	}
+
+	for protocol, c := range codecs {
+		for _, message := range sampleMessages {
+			decoded, err := roundTrip(c, message)
+			if err != nil {
+				t.Errorf("%v: %T: %v", protocol, message, err)
+				continue
+			}
+
+			// compare through JSON, which ignores time zones
+			want, _ := json.Marshal(message)
+			got, _ := json.Marshal(decoded)
+			if !bytes.Equal(want, got) {
+				t.Errorf("%v: %T round-tripped as\n%s\nwant\n%s", protocol, message, got, want)
+			}
+		}
+	}
+}
+
+// TestCodecSchema checks that both codecs name every field the same
+func TestCodecSchema(t *testing.T) {
+	for _, message := range sampleMessages {
+		b := &bytes.Buffer{}
+		err := msgpackCodec{}.encode(b, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fromMsgpack interface{}
+		err = msgpackCodec{}.decoder(b, true).Decode(&fromMsgpack)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fromJSON interface{}
+		j, _ := json.Marshal(message)
+		_ = json.Unmarshal(j, &fromJSON)
+
+		// msgpack keeps integer types, so compare through JSON
+		got, _ := json.Marshal(fromMsgpack)
+		want, _ := json.Marshal(fromJSON)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%T has a different msgpack schema\n%s\nwant\n%s", message, got, want)
+		}
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	for _, c := range []codec{jsonCodec{}, msgpackCodec{}} {
+		for _, message := range sampleMessages {
+			typ := reflect.TypeOf(message)
+			if valueFromType(typ.Name()) == nil {
+				continue
+			}
+
+			b := &bytes.Buffer{}
+			err := c.encode(b, map[string]interface{}{
+				"messageType": typ.Name(),
+				"payload":     message,
+				"requestID":   8,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := parseMessage(c, protocol{strict: true}, b)
+			if err != nil {
+				t.Errorf("%T: %v", message, err)
+				continue
+			}
+			if req.id != 8 || reflect.TypeOf(req.payload) != reflect.PtrTo(typ) {
+				t.Errorf("%T parsed as %+v", message, req)
+			}
+		}
+	}
+}
+
+// TestParseRequestUnknownField checks that only strict protocols reject
+// requests with fields they don't know
+func TestParseRequestUnknownField(t *testing.T) {
+	for _, c := range []codec{jsonCodec{}, msgpackCodec{}} {
+		for _, strict := range []bool{true, false} {
+			b := &bytes.Buffer{}
+			err := c.encode(b, map[string]interface{}{
+				"messageType": "Spectate",
+				"payload":     map[string]string{"gameID": "game", "game": "game"},
+				"requestID":   1,
+				"priority":    2,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := parseMessage(c, protocol{strict: strict}, b)
+			if strict && err != errMalformedRequest {
+				t.Errorf("%T: got %v, want errMalformedRequest", c, err)
+			} else if !strict && (err != nil || req.payload.(*Spectate).GameID != "game") {
+				t.Errorf("%T: parsed as %+v, %v", c, req, err)
+			}
+		}
+	}
+}
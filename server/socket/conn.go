@@ -1,86 +1,240 @@
 package socket
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"fmt"
 	"io"
-	"reflect"
+	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/heartles/uttt/server/store"
 )
 
 var errMalformedRequest = errors.New("malformed request")
-var errInvalidRequestType = errors.New("invalid request type")
 
+// clientConn is a logged in session. It can outlive its transport for
+// a while, so that a client that reconnects can pick up where it left
+// off
 type clientConn struct {
-	socket    *websocket.Conn
+	// transport is nil while the client is disconnected
+	transport transport
 	playerID  string
+	username  string
+	// status is the session's presence, StatusOnline or StatusAway, and
+	// connected is whether it has a transport. Both are guarded by the
+	// server's session mutex
+	status    string
+	connected bool
 	openGames []store.NewGameNotification
 	cancelCtx func()
-	// todo: fix request id responses on server end
-	currRequestID int
+
+	// addedGames and removedGames are changes to openGames waiting
+	// for the current game listener to stop
+	addedGames   []store.NewGameNotification
+	removedGames []string
+	// spectating holds the IDs of the games the player is watching
+	spectating map[string]bool
+	// protocol is the version of the socket protocol the client speaks,
+	// and capabilities the optional features it enabled
+	protocol     protocol
+	capabilities map[string]bool
+	// versions holds the version of each open game the client was
+	// last sent, so that it can be sent just the changes since
+	versions map[string]int
+	// chatCh receives messages from every chat room the player is in
+	chatCh chan store.ChatMessage
+	// events receives messages pushed to the player by other sessions
+	events chan interface{}
+
+	// token identifies the session to a reconnecting client
+	token string
+	// sequence is the sequence number of the last message sent, and
+	// replay holds the most recent messages for resuming
+	sequence uint64
+	replay   []OutgoingSocketMessage
+	// attachCh receives sockets from clients resuming the session
+	attachCh chan resumeRequest
+	// done is closed once the session's message loop has exited
+	done chan struct{}
+	// ended is set once the session has been closed on purpose, so
+	// that it isn't kept around for resuming
+	ended bool
+
+	// lastMessage is when the client last sent a message, for idle
+	// disconnects
+	lastMessage time.Time
+
+	// matchCh is set while the player is waiting in the matchmaking queue
+	matchCh <-chan store.MatchFound
+
+	// analyzing is set while an analysis the client asked for is
+	// running, and analysisCh receives it once done
+	analyzing  bool
+	analysisCh chan analysisResult
+}
+
+// request is a message from the client. Its response has to carry the
+// same ID
+type request struct {
+	id      int
+	payload interface{}
+	// err is set if the message couldn't be read
+	err error
+}
+
+// findGame returns the open game with the given ID, or nil if the
+// player isn't playing in or watching it
+func (conn *clientConn) findGame(gameID string) *store.Game {
+	for _, games := range [][]store.NewGameNotification{conn.openGames, conn.addedGames} {
+		for _, n := range games {
+			if n.Game.UUID() == gameID {
+				return n.Game
+			}
+		}
+	}
+	return nil
+}
+
+// findPlayedGame returns the open game with the given ID, or nil if the
+// player isn't playing in it. Games being watched are read-only
+func (conn *clientConn) findPlayedGame(gameID string) *store.Game {
+	if conn.spectating[gameID] {
+		return nil
+	}
+	return conn.findGame(gameID)
+}
+
+// watchGame adds a game to the set being listened to. The current
+// listener is stopped so that it can be restarted with the new game
+func (conn *clientConn) watchGame(n store.NewGameNotification) {
+	conn.addedGames = append(conn.addedGames, n)
+	conn.cancelCtx()
+}
+
+// unwatchGame removes a game from the set being listened to, closing
+// it once the current listener has stopped
+func (conn *clientConn) unwatchGame(gameID string) {
+	conn.removedGames = append(conn.removedGames, gameID)
+	conn.cancelCtx()
 }
 
-func (conn *clientConn) malformedRequest() error {
-	return conn.sendError("malformed request", false)
+func (conn *clientConn) gamesChanged() bool {
+	return len(conn.addedGames) != 0 || len(conn.removedGames) != 0
 }
 
-func (conn *clientConn) sendError(message string, recoverable bool) error {
-	err := conn.sendMessage(ErrorMessage{
+// listenForGameUpdates applies any pending changes to openGames and
+// starts listening to them. The previous listener must have stopped
+func (conn *clientConn) listenForGameUpdates(games *store.GameService) <-chan int {
+	for _, gameID := range conn.removedGames {
+		for idx, n := range conn.openGames {
+			if n.Game.UUID() == gameID {
+				n.Game.Close(n.UpdateCh)
+				conn.openGames = append(conn.openGames[:idx], conn.openGames[idx+1:]...)
+				break
+			}
+		}
+	}
+	conn.openGames = append(conn.openGames, conn.addedGames...)
+	conn.addedGames = nil
+	conn.removedGames = nil
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	conn.cancelCtx = cancelCtx
+	return games.ListenAny(conn.openGames, ctx)
+}
+
+// malformedRequest answers a request that couldn't be read. If not even
+// its ID could be read, requestID is 0 and the error is unsolicited
+func (conn *clientConn) malformedRequest(requestID int) error {
+	return conn.respondError(requestID, ErrorMessage{
+		Code:    CodeMalformedRequest,
+		Message: "malformed request",
+	})
+}
+
+// sendError sends an error that doesn't answer any request
+func (conn *clientConn) sendError(code ErrorCode, message string, recoverable bool) error {
+	return conn.respondError(0, ErrorMessage{
+		Code:        code,
 		Message:     message,
 		Recoverable: recoverable,
 	})
-	if !recoverable {
-		conn.socket.Close()
-	}
-	return err
 }
 
-func (conn *clientConn) nextMessageSync() (interface{}, error) {
-	_, reader, err := conn.socket.NextReader()
-	if err != nil {
-		return nil, err
+// respondError answers a request with an error. Errors other than an
+// ErrorMessage are internal, so the client is only told the request
+// failed
+func (conn *clientConn) respondError(requestID int, err error) error {
+	msg, ok := err.(ErrorMessage)
+	if !ok {
+		fmt.Println(err)
+		msg = ErrorMessage{
+			Code:        CodeInternal,
+			Message:     "error processing command",
+			Recoverable: true,
+		}
 	}
 
-	return conn.parseMessage(reader)
+	sendErr := conn.send(requestID, msg)
+	if !msg.Recoverable {
+		conn.ended = true
+		if conn.transport != nil {
+			conn.transport.close()
+		}
+	}
+	return sendErr
 }
 
+// sendMessage pushes a message the client didn't ask for
 func (conn *clientConn) sendMessage(payload interface{}) error {
-	return conn.socket.WriteJSON(OutgoingSocketMessage{
-		Type:      reflect.TypeOf(payload).Name(),
-		Payload:   payload,
-		RequestID: conn.currRequestID,
-	})
+	return conn.send(0, payload)
 }
 
-func (conn *clientConn) parseMessage(r io.Reader) (interface{}, error) {
-	decoder := json.NewDecoder(r)
-	decoder.DisallowUnknownFields()
+// respond answers a request
+func (conn *clientConn) respond(requestID int, payload interface{}) error {
+	return conn.send(requestID, payload)
+}
 
-	var message IncomingSocketMessage
-	err := decoder.Decode(&message)
-	if err != nil {
-		// TODO: log this
-		return nil, errMalformedRequest
-	}
-	conn.currRequestID = message.RequestID
+// send sends a message as the next event in the session, as the
+// response to requestID, or unsolicited if requestID is 0. If the
+// client is disconnected, it is only kept for replay
+func (conn *clientConn) send(requestID int, payload interface{}) error {
+	msg := conn.record(conn.protocol.envelope(requestID, payload))
 
-	decodedMessage := valueFromType(message.Type)
-	if decodedMessage == nil {
-		return nil, errInvalidRequestType
+	if conn.transport == nil {
+		return nil
 	}
+	return conn.transport.write(msg)
+}
 
-	err = json.Unmarshal(message.Payload, decodedMessage)
+// sendUnsequenced answers a request with a message that isn't part of
+// the session's event stream, and so is never replayed
+func (conn *clientConn) sendUnsequenced(requestID int, payload interface{}) error {
+	return conn.transport.write(conn.protocol.envelope(requestID, payload))
+}
+
+// parseMessage reads a request in the protocol's form. A request of an
+// unknown type has a nil payload, so that it can be answered with an
+// error
+func parseMessage(c codec, p protocol, r io.Reader) (request, error) {
+	typ, requestID, payload, err := c.decodeRequest(r, p.strict)
 	if err != nil {
-		return nil, errMalformedRequest
+		// TODO: log this
+		return request{}, errMalformedRequest
 	}
 
-	return decodedMessage, nil
+	req := request{id: requestID}
+	req.payload, err = p.decode(typ, payload)
+	if err != nil {
+		return req, errMalformedRequest
+	}
+	return req, nil
 }
 
 func valueFromType(typ string) interface{} {
 	switch typ {
+	case "Hello":
+		return &Hello{}
 	case "LoginRequest":
 		return &LoginRequest{}
 	case "NewGame":
@@ -89,6 +243,62 @@ func valueFromType(typ string) interface{} {
 		return &UserLookup{}
 	case "PlayMove":
 		return &PlayMove{}
+	case "Rematch":
+		return &Rematch{}
+	case "DeclineRematch":
+		return &DeclineRematch{}
+	case "RatingHistory":
+		return &RatingHistory{}
+	case "PlayerStats":
+		return &PlayerStats{}
+	case "Leaderboard":
+		return &Leaderboard{}
+	case "CreateTournament":
+		return &CreateTournament{}
+	case "JoinTournament":
+		return &JoinTournament{}
+	case "StartTournament":
+		return &StartTournament{}
+	case "TournamentStandings":
+		return &TournamentStandings{}
+	case "JoinQueue":
+		return &JoinQueue{}
+	case "LeaveQueue":
+		return &LeaveQueue{}
+	case "JoinChat":
+		return &JoinChat{}
+	case "LeaveChat":
+		return &LeaveChat{}
+	case "SendChat":
+		return &SendChat{}
+	case "SetPresence":
+		return &SetPresence{}
+	case "OnlinePlayers":
+		return &OnlinePlayers{}
+	case "FriendsList":
+		return &FriendsList{}
+	case "FriendRequest":
+		return &FriendRequest{}
+	case "AcceptFriend":
+		return &AcceptFriend{}
+	case "DeclineFriend":
+		return &DeclineFriend{}
+	case "RemoveFriend":
+		return &RemoveFriend{}
+	case "BlockPlayer":
+		return &BlockPlayer{}
+	case "UnblockPlayer":
+		return &UnblockPlayer{}
+	case "Spectate":
+		return &Spectate{}
+	case "StopSpectating":
+		return &StopSpectating{}
+	case "Analyze":
+		return &Analyze{}
+	case "GameReview":
+		return &GameReview{}
+	case "GameSnapshot":
+		return &GameSnapshot{}
 	}
 
 	return nil
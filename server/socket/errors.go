@@ -0,0 +1,154 @@
+package socket
+
+import (
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
+)
+
+// ErrorCode identifies the reason a request failed. Codes are stable,
+// so clients can react to them and show their own text, while the
+// accompanying message is only meant for developers
+type ErrorCode string
+
+// Protocol and session errors
+const (
+	// CodeInternal is a failure on the server's end. Retrying may help
+	CodeInternal ErrorCode = "internal"
+	// CodeMalformedRequest is a message that couldn't be decoded. The
+	// socket is closed
+	CodeMalformedRequest ErrorCode = "malformed_request"
+	// CodeUnknownMessageType is a request of a type the server doesn't
+	// handle
+	CodeUnknownMessageType ErrorCode = "unknown_message_type"
+	// CodeInvalidRequest is a request with missing or invalid fields
+	CodeInvalidRequest ErrorCode = "invalid_request"
+	// CodeInvalidLogin is a LoginRequest that was refused. The socket
+	// is closed
+	CodeInvalidLogin ErrorCode = "invalid_login"
+	// CodeUnsupportedVersion is a Hello asking for a protocol version
+	// the server doesn't speak. The socket is closed
+	CodeUnsupportedVersion ErrorCode = "unsupported_protocol_version"
+	// CodeSessionExpired is a LoginRequest with an unknown session
+	// token. The client has to log in again
+	CodeSessionExpired ErrorCode = "session_expired"
+	// CodeSessionNotResumable is a LoginRequest whose LastSequence is
+	// no longer in the replay buffer. The client has to log in again
+	CodeSessionNotResumable ErrorCode = "session_not_resumable"
+	// CodeIdleTimeout is pushed before the socket is closed for
+	// inactivity
+	CodeIdleTimeout ErrorCode = "idle_timeout"
+	// CodePlayerNotFound is a request naming a player who doesn't exist
+	CodePlayerNotFound ErrorCode = "player_not_found"
+)
+
+// Move errors, returned for PlayMove
+const (
+	CodeSquarePlayed      ErrorCode = "square_played"
+	CodeWrongTurn         ErrorCode = "wrong_turn"
+	CodeWrongSubgrid      ErrorCode = "wrong_subgrid"
+	CodeGameOver          ErrorCode = "game_over"
+	CodeNotPlayer         ErrorCode = "not_player"
+	CodeInvalidCoordinate ErrorCode = "invalid_coordinate"
+	CodeInvalidGameState  ErrorCode = "invalid_game_state"
+)
+
+// Game, chat, social and matchmaking errors
+const (
+	CodeGameNotFound       ErrorCode = "game_not_found"
+	CodePrivateGame        ErrorCode = "private_game"
+	CodeGameNotFinished    ErrorCode = "game_not_finished"
+	CodeInvalidSettings    ErrorCode = "invalid_settings"
+	CodeInvalidColor       ErrorCode = "invalid_color"
+	CodeInvalidRatingRange ErrorCode = "invalid_rating_range"
+	CodeInvalidLeaderboard ErrorCode = "invalid_leaderboard"
+	CodeInvalidChatRoom    ErrorCode = "invalid_chat_room"
+	CodeChatNotAllowed     ErrorCode = "chat_not_allowed"
+	CodeInvalidChatMessage ErrorCode = "invalid_chat_message"
+	CodeChatRateLimited    ErrorCode = "chat_rate_limited"
+	CodeBlocked            ErrorCode = "blocked"
+	CodeInvalidFriend      ErrorCode = "invalid_friend"
+	CodeNoFriendRequest    ErrorCode = "no_friend_request"
+)
+
+// Analysis and review errors
+const (
+	// CodeAnalysisDisabled is an Analyze of a rated game in progress,
+	// or of a position while the sender is playing one
+	CodeAnalysisDisabled ErrorCode = "analysis_disabled"
+	// CodeAnalysisRateLimited is an Analyze sent too soon after others
+	CodeAnalysisRateLimited ErrorCode = "analysis_rate_limited"
+	// CodeNotReviewable is a GameReview of a game that won't be
+	// reviewed
+	CodeNotReviewable ErrorCode = "not_reviewable"
+)
+
+// Tournament errors
+const (
+	CodeInvalidTournamentFormat ErrorCode = "invalid_tournament_format"
+	CodeTournamentNotFound      ErrorCode = "tournament_not_found"
+	CodeNotRegistering          ErrorCode = "not_registering"
+	CodeNotOrganizer            ErrorCode = "not_organizer"
+	CodeTooFewPlayers           ErrorCode = "too_few_players"
+)
+
+// errorCodes maps the errors a request can fail with to their codes.
+// Any other error is internal
+var errorCodes = map[error]ErrorCode{
+	game.ErrSquarePlayed:      CodeSquarePlayed,
+	game.ErrWrongTurn:         CodeWrongTurn,
+	game.ErrWrongSubgrid:      CodeWrongSubgrid,
+	game.ErrGameOver:          CodeGameOver,
+	game.ErrInvalidPlayer:     CodeNotPlayer,
+	game.ErrInvalidCoordinate: CodeInvalidCoordinate,
+	game.ErrInvalidInput:      CodeInvalidGameState,
+	game.ErrInvalidLastMove:   CodeInvalidGameState,
+
+	store.ErrGameNotFound:       CodeGameNotFound,
+	store.ErrPrivateGame:        CodePrivateGame,
+	store.ErrGameNotFinished:    CodeGameNotFinished,
+	store.ErrInvalidSettings:    CodeInvalidSettings,
+	store.ErrInvalidColor:       CodeInvalidColor,
+	store.ErrInvalidRatingRange: CodeInvalidRatingRange,
+	store.ErrInvalidLeaderboard: CodeInvalidLeaderboard,
+	store.ErrInvalidChatRoom:    CodeInvalidChatRoom,
+	store.ErrChatNotAllowed:     CodeChatNotAllowed,
+	store.ErrInvalidChatMessage: CodeInvalidChatMessage,
+	store.ErrChatRateLimited:    CodeChatRateLimited,
+	store.ErrBlocked:            CodeBlocked,
+	store.ErrInvalidFriend:      CodeInvalidFriend,
+	store.ErrNoFriendRequest:    CodeNoFriendRequest,
+	store.ErrAnalysisDisabled:   CodeAnalysisDisabled,
+	store.ErrNotReviewable:      CodeNotReviewable,
+
+	tournament.ErrInvalidFormat:  CodeInvalidTournamentFormat,
+	tournament.ErrNotFound:       CodeTournamentNotFound,
+	tournament.ErrNotRegistering: CodeNotRegistering,
+	tournament.ErrNotOrganizer:   CodeNotOrganizer,
+	tournament.ErrTooFewPlayers:  CodeTooFewPlayers,
+}
+
+// requestError converts an error from the game, store or tournament
+// packages into a recoverable ErrorMessage. Errors without a code are
+// returned unchanged, and reported to the client as internal
+func requestError(err error) error {
+	code, ok := errorCodes[err]
+	if !ok {
+		return err
+	}
+
+	return ErrorMessage{
+		Code:        code,
+		Message:     err.Error(),
+		Recoverable: true,
+	}
+}
+
+// requestFailed is a recoverable error response
+func requestFailed(code ErrorCode, message string) error {
+	return ErrorMessage{
+		Code:        code,
+		Message:     message,
+		Recoverable: true,
+	}
+}
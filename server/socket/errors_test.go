@@ -0,0 +1,76 @@
+package socket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
+)
+
+func TestRequestErrorCodes(t *testing.T) {
+	// the codes are part of the protocol, so they are spelled out here
+	// rather than taken from the constants
+	codes := []struct {
+		err  error
+		code ErrorCode
+	}{
+		{game.ErrSquarePlayed, "square_played"},
+		{game.ErrWrongTurn, "wrong_turn"},
+		{game.ErrWrongSubgrid, "wrong_subgrid"},
+		{game.ErrGameOver, "game_over"},
+		{game.ErrInvalidPlayer, "not_player"},
+		{game.ErrInvalidCoordinate, "invalid_coordinate"},
+		{game.ErrInvalidInput, "invalid_game_state"},
+		{game.ErrInvalidLastMove, "invalid_game_state"},
+
+		{store.ErrGameNotFound, "game_not_found"},
+		{store.ErrPrivateGame, "private_game"},
+		{store.ErrGameNotFinished, "game_not_finished"},
+		{store.ErrInvalidSettings, "invalid_settings"},
+		{store.ErrInvalidColor, "invalid_color"},
+		{store.ErrInvalidRatingRange, "invalid_rating_range"},
+		{store.ErrInvalidLeaderboard, "invalid_leaderboard"},
+		{store.ErrInvalidChatRoom, "invalid_chat_room"},
+		{store.ErrChatNotAllowed, "chat_not_allowed"},
+		{store.ErrInvalidChatMessage, "invalid_chat_message"},
+		{store.ErrChatRateLimited, "chat_rate_limited"},
+		{store.ErrBlocked, "blocked"},
+		{store.ErrInvalidFriend, "invalid_friend"},
+		{store.ErrNoFriendRequest, "no_friend_request"},
+		{store.ErrAnalysisDisabled, "analysis_disabled"},
+		{store.ErrNotReviewable, "not_reviewable"},
+
+		{tournament.ErrInvalidFormat, "invalid_tournament_format"},
+		{tournament.ErrNotFound, "tournament_not_found"},
+		{tournament.ErrNotRegistering, "not_registering"},
+		{tournament.ErrNotOrganizer, "not_organizer"},
+		{tournament.ErrTooFewPlayers, "too_few_players"},
+	}
+	if len(codes) != len(errorCodes) {
+		t.Errorf("%d errors have codes, but %d are tested", len(errorCodes), len(codes))
+	}
+
+	for _, c := range codes {
+		err := requestError(c.err)
+		msg, ok := err.(ErrorMessage)
+		if !ok {
+			t.Errorf("%q: got %#v, want an ErrorMessage", c.err, err)
+			continue
+		}
+		want := ErrorMessage{Code: c.code, Message: c.err.Error(), Recoverable: true}
+		if msg != want {
+			t.Errorf("%q: got %+v, want %+v", c.err, msg, want)
+		}
+	}
+
+	if err := requestError(nil); err != nil {
+		t.Errorf("got %#v for no error", err)
+	}
+	// anything else is internal, and is left for the caller to report
+	other := errors.New("disk on fire")
+	if err := requestError(other); err != other {
+		t.Errorf("got %#v for an unknown error", err)
+	}
+}
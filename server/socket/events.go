@@ -0,0 +1,131 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// eventStream sends messages to a client as Server-Sent Events, for
+// clients that can't keep a websocket open. The client can't send
+// requests over it, so it acts through the HTTP API instead
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	// gone is closed when the client disconnects, and closed when the
+	// server ends the stream
+	gone      <-chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// listen never returns a request, but the channel is still closed once
+// the client disconnects, so that the session ends
+func (t *eventStream) listen(p protocol, done <-chan struct{}) <-chan request {
+	ch := make(chan request)
+	go func() {
+		defer close(ch)
+		select {
+		case <-t.gone:
+		case <-t.closed:
+		case <-done:
+		}
+	}()
+	return ch
+}
+
+func (t *eventStream) readsRequests() bool {
+	return false
+}
+
+// write sends a message as an event named for its type. Messages in
+// the session's event stream carry their sequence number as the
+// event's ID
+func (t *eventStream) write(msg OutgoingSocketMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if msg.Sequence != 0 {
+		_, err = fmt.Fprintf(t.w, "id: %d\n", msg.Sequence)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(t.w, "event: %v\ndata: %s\n\n", msg.Type, data)
+	if err != nil {
+		return err
+	}
+
+	t.flusher.Flush()
+	return nil
+}
+
+// ping sends a comment, which clients ignore, to keep proxies from
+// closing the stream for being idle
+func (t *eventStream) ping() {
+	fmt.Fprint(t.w, ": ping\n\n")
+	t.flusher.Flush()
+}
+
+func (t *eventStream) close() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+}
+
+// StreamEvents serves a session for playerID as a stream of
+// Server-Sent Events. It starts with a LoginSuccess, then sends the
+// same messages a websocket session would, encoded as JSON. The
+// capabilities query parameter lists the capabilities to enable,
+// separated by commas. Streams can't be resumed; a client that
+// reconnects starts a new session and is sent every open game in full
+func (s *Server) StreamEvents(w http.ResponseWriter, r *http.Request, playerID string) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming is not supported by %T", w)
+	}
+
+	player, err := s.games.TryLookupPlayerUUID(playerID)
+	if err != nil {
+		return err
+	} else if player == nil {
+		return fmt.Errorf("player %v does not exist", playerID)
+	}
+
+	t := &eventStream{
+		w:       w,
+		flusher: flusher,
+		gone:    r.Context().Done(),
+		closed:  make(chan struct{}),
+	}
+
+	conn := newSession(t)
+	conn.playerID = player.UUID
+	conn.username = player.Username
+	conn.protocol = protocols[ProtocolVersion]
+	asked := []string{}
+	if capabilities := r.URL.Query().Get("capabilities"); capabilities != "" {
+		asked = strings.Split(capabilities, ",")
+	}
+	conn.capabilities = conn.protocol.enable(asked)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	err = conn.sendUnsequenced(0, LoginSuccess{
+		Username:     player.Username,
+		PlayerID:     player.UUID,
+		SessionToken: conn.token,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.runMessageLoop(conn)
+	return nil
+}
@@ -0,0 +1,39 @@
+package socket
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventStreamWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream := &eventStream{w: w, flusher: w, closed: make(chan struct{})}
+
+	stream.write(protocol{}.envelope(0, Ack{}))
+	msg := protocol{}.envelope(0, Presence{PlayerID: "p", Username: "alice", Status: StatusAway})
+	msg.Sequence = 7
+	stream.write(msg)
+
+	want := "event: Ack\n" +
+		`data: {"messageType":"Ack","payload":{},"unsolicited":true}` + "\n\n" +
+		"id: 7\n" +
+		"event: Presence\n" +
+		`data: {"messageType":"Presence","payload":{"playerID":"p","username":"alice","status":"away"},"unsolicited":true,"sequence":7}` + "\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+	if !w.Flushed {
+		t.Error("events weren't flushed")
+	}
+}
+
+func TestEventStreamClose(t *testing.T) {
+	stream := &eventStream{gone: make(chan struct{}), closed: make(chan struct{})}
+	requests := stream.listen(protocol{}, make(chan struct{}))
+
+	stream.close()
+	stream.close()
+	if _, ok := <-requests; ok {
+		t.Error("got a request from an event stream")
+	}
+}
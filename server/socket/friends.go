@@ -0,0 +1,66 @@
+package socket
+
+import "fmt"
+
+// friendsList builds a player's FriendsList, with the presence of
+// each friend
+func (s *Server) friendsList(playerID string) (*FriendsList, error) {
+	list, err := s.games.FriendsList(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	friends := []Presence{}
+	for _, f := range list.Friends {
+		friends = append(friends, Presence{
+			PlayerID: f.PlayerID,
+			Username: f.Username,
+			Status:   s.presence(f.PlayerID),
+		})
+	}
+
+	return &FriendsList{
+		Friends:  friends,
+		Incoming: list.Incoming,
+		Outgoing: list.Outgoing,
+		Blocked:  list.Blocked,
+	}, nil
+}
+
+func (s *Server) handleFriendsList(conn *clientConn) (interface{}, error) {
+	list, err := s.friendsList(conn.playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return *list, nil
+}
+
+// pushFriendsList sends a player's FriendsList to each of their
+// sessions
+func (s *Server) pushFriendsList(playerID string) {
+	list, err := s.friendsList(playerID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+	for conn := range s.sessions[playerID] {
+		conn.push(*list)
+	}
+}
+
+// handleFriendAction applies a change to the relationship between the
+// sender and another player, and sends both their new friends lists
+func (s *Server) handleFriendAction(conn *clientConn, otherID string, action func(playerID, otherID string) error) error {
+	err := action(conn.playerID, otherID)
+	if err != nil {
+		return requestError(err)
+	}
+
+	s.pushFriendsList(conn.playerID)
+	s.pushFriendsList(otherID)
+	return nil
+}
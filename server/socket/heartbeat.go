@@ -0,0 +1,31 @@
+package socket
+
+import (
+	"time"
+)
+
+// startHeartbeat returns channels that fire when the session should
+// ping its client, and when it should check whether the client has
+// gone idle. Either is nil if disabled in the config
+func (s *Server) startHeartbeat(conn *clientConn) (ping, idle <-chan time.Time, stop func()) {
+	tickers := []*time.Ticker{}
+	if s.config.PingInterval > 0 {
+		t := time.NewTicker(s.config.PingInterval)
+		tickers = append(tickers, t)
+		ping = t.C
+	}
+	if s.config.IdleTimeout > 0 {
+		// checking a few times per timeout keeps disconnects close
+		// to on time without a timer reset on every message
+		t := time.NewTicker(s.config.IdleTimeout / 4)
+		tickers = append(tickers, t)
+		idle = t.C
+	}
+
+	conn.lastMessage = time.Now()
+	return ping, idle, func() {
+		for _, t := range tickers {
+			t.Stop()
+		}
+	}
+}
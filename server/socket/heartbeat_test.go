@@ -0,0 +1,91 @@
+package socket
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/heartles/uttt/server/config"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	ts := startServerConfig(t, &config.Config{IdleTimeout: 200 * time.Millisecond, WriteTimeout: time.Second})
+	c := ts.login(t, "alice")
+
+	// requests keep the session going past the timeout
+	start := time.Now()
+	for time.Since(start) < 400*time.Millisecond {
+		if r := c.request("LeaveQueue", LeaveQueue{}); r.Type != "Ack" {
+			t.Fatalf("got %v %s while active", r.Type, r.Payload)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	r := c.awaitPush("ErrorMessage")
+	if r.errorCode() != CodeIdleTimeout {
+		t.Fatalf("got %s", r.Payload)
+	}
+	if idle := time.Since(start); idle < 400*time.Millisecond {
+		t.Errorf("disconnected after %v", idle)
+	}
+
+	c.ws.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := c.ws.ReadMessage(); err == nil || isTimeout(err) {
+		t.Errorf("got %v, want the socket closed", err)
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// readUntil reads from c until the deadline, answering pings if pong
+// is set, and returns the number of pings and the error the reads
+// ended with
+func readUntil(c *testClient, deadline time.Time, pong bool) (int, error) {
+	pings := 0
+	c.ws.SetPingHandler(func(data string) error {
+		pings++
+		if pong {
+			return c.ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		}
+		return nil
+	})
+
+	c.ws.SetReadDeadline(deadline)
+	for {
+		if _, _, err := c.ws.ReadMessage(); err != nil {
+			return pings, err
+		}
+	}
+}
+
+func TestPongExtendsReadDeadline(t *testing.T) {
+	ts := startServerConfig(t, &config.Config{
+		PingInterval: 50 * time.Millisecond,
+		PongTimeout:  200 * time.Millisecond,
+		WriteTimeout: time.Second,
+	})
+
+	// without sending anything but pongs, the client is still there
+	// well after the pong timeout
+	c := ts.login(t, "alice")
+	pings, err := readUntil(c, time.Now().Add(600*time.Millisecond), true)
+	if !isTimeout(err) || pings < 5 {
+		t.Errorf("got %v after %d pings, want the socket kept open", err, pings)
+	}
+
+	// a client that stops answering is dropped
+	start := time.Now()
+	c = ts.login(t, "bob")
+	_, err = readUntil(c, start.Add(2*time.Second), false)
+	if err == nil || isTimeout(err) {
+		t.Errorf("got %v, want the socket closed", err)
+	}
+	if dropped := time.Since(start); dropped < 200*time.Millisecond {
+		t.Errorf("dropped after %v", dropped)
+	}
+}
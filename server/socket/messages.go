@@ -3,10 +3,17 @@ package socket
 import (
 	"encoding/json"
 
+	"github.com/heartles/uttt/server/engine"
 	"github.com/heartles/uttt/server/game"
 	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
 )
 
+// IncomingSocketMessage is a request from the client. Every request is
+// answered with exactly one message carrying its RequestID: either the
+// response named in the request's documentation, an Ack if it has
+// none, or an ErrorMessage. RequestID should be unique within the
+// session and must not be 0
 type IncomingSocketMessage struct {
 	Type      string          `json:"messageType"`
 	Payload   json.RawMessage `json:"payload"`
@@ -14,39 +21,331 @@ type IncomingSocketMessage struct {
 }
 
 type OutgoingSocketMessage struct {
-	Type      string      `json:"messageType"`
-	Payload   interface{} `json:"payload"`
-	RequestID int         `json:"requestID"`
+	Type    string      `json:"messageType"`
+	Payload interface{} `json:"payload"`
+
+	// RequestID is the ID of the request this message answers.
+	// Messages the server sends on its own, such as game updates, are
+	// Unsolicited and have no RequestID
+	RequestID   int  `json:"requestID,omitempty"`
+	Unsolicited bool `json:"unsolicited,omitempty"`
+
+	// Sequence numbers every message in a session, starting at 1. It
+	// is 0 for messages outside the session's event stream, such as
+	// LoginSuccess
+	Sequence uint64 `json:"sequence,omitempty"`
+}
+
+// Hello is the first message a client sends, before its LoginRequest,
+// to choose the protocol version it speaks and the capabilities it
+// wants. The response is a Hello with the version and the capabilities
+// that were enabled. An unsupported version is answered with an
+// ErrorMessage, and the socket is closed. Clients that don't send a
+// Hello speak version 1
+type Hello struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Capabilities    []string `json:"capabilities,omitempty"`
 }
 
+// LoginRequest starts a session, or resumes one if SessionToken is set
 type LoginRequest struct {
 	LoginID string `json:"loginID"`
+	// APIKey logs in as the player the key was issued to, instead of
+	// LoginID
+	APIKey string `json:"apiKey,omitempty"`
+
+	// SessionToken is the token from an earlier LoginSuccess, and
+	// LastSequence the sequence number of the last message received
+	// on it. Every message after that one is sent again
+	SessionToken string `json:"sessionToken,omitempty"`
+	LastSequence uint64 `json:"lastSequence,omitempty"`
 }
 
 type NewGame struct {
 	OpponentID string `json:"opponentID"`
+
+	// Color is the side the requester wants to play: "x", "o",
+	// "random" or "alternate". Defaults to "x"
+	Color store.ColorChoice `json:"color,omitempty"`
+
+	Variant     string `json:"variant,omitempty"`
+	TimeControl string `json:"timeControl,omitempty"`
+	Casual      bool   `json:"casual,omitempty"`
+
+	// Private turns off spectating for the game
+	Private bool `json:"private,omitempty"`
+}
+
+// Spectate subscribes the sender to a game they aren't playing in. The
+// response is the game's GameState, which is then pushed every time it
+// changes
+type Spectate struct {
+	GameID string `json:"gameID"`
+}
+
+// StopSpectating ends a Spectate subscription
+type StopSpectating struct {
+	GameID string `json:"gameID"`
+}
+
+// GameSnapshot requests the full GameState of a game the sender is
+// playing in or watching. Changes to open games are pushed as
+// GameDeltas, so a client that finds a gap in their versions can use
+// this to catch up
+type GameSnapshot struct {
+	GameID string `json:"gameID"`
+}
+
+// Analyze requests, and is answered with, the built-in engine's best
+// moves in a game the sender can look at, or in a Position given in
+// position notation. Lines is how many moves to return, up to 5, and 3
+// if unset. Rated games can't be analysed until they have finished, nor
+// can positions be while the sender is playing one
+type Analyze struct {
+	GameID   string           `json:"gameID,omitempty"`
+	Position string           `json:"position,omitempty"`
+	Lines    int              `json:"lines,omitempty"`
+	Analysis *engine.Analysis `json:"analysis,omitempty"`
+}
+
+// GameReview requests, and is answered with, the engine's review of a
+// finished game the sender can look at. Games are reviewed in the
+// background once they finish, so the review may still be pending
+type GameReview struct {
+	GameID string            `json:"gameID"`
+	Review *store.GameReview `json:"review,omitempty"`
+}
+
+// JoinChat adds the sender to a chat room: "lobby", or "game" or
+// "spectators" with a GameID. The response is a ChatHistory, and every
+// message sent to the room afterwards is pushed as a ChatMessage
+type JoinChat struct {
+	Room   string `json:"room"`
+	GameID string `json:"gameID,omitempty"`
+}
+
+// LeaveChat stops the sender receiving messages from a chat room
+type LeaveChat struct {
+	Room   string `json:"room"`
+	GameID string `json:"gameID,omitempty"`
 }
 
+// SendChat sends a message to a chat room. The sender doesn't need to
+// have joined the room, but only receives the message if they have
+type SendChat struct {
+	Room   string `json:"room"`
+	GameID string `json:"gameID,omitempty"`
+	Body   string `json:"body"`
+}
+
+// ChatHistory is the recent history of a chat room, oldest first
+type ChatHistory struct {
+	Room     string              `json:"room"`
+	GameID   string              `json:"gameID,omitempty"`
+	Messages []store.ChatMessage `json:"messages"`
+}
+
+// SetPresence marks the sender's session "online" or "away", e.g.
+// when the tab is hidden. Sessions start out online
+type SetPresence struct {
+	Status string `json:"status"`
+}
+
+// Presence is pushed when a friend or an opponent in an unfinished
+// game comes online, goes away or disconnects
+type Presence struct {
+	PlayerID string `json:"playerID"`
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
+
+// OnlinePlayers requests, and is answered with, everyone who is
+// currently connected
+type OnlinePlayers struct {
+	Players []Presence `json:"players,omitempty"`
+}
+
+// FriendsList requests, and is answered with, the sender's friends
+// and their presence, friend requests and blocked players. It is also
+// pushed whenever any of them change
+type FriendsList struct {
+	Friends  []Presence     `json:"friends,omitempty"`
+	Incoming []store.Friend `json:"incoming,omitempty"`
+	Outgoing []store.Friend `json:"outgoing,omitempty"`
+	Blocked  []store.Friend `json:"blocked,omitempty"`
+}
+
+// FriendRequest asks a player to be the sender's friend, or accepts
+// their request if they have already sent one
+type FriendRequest struct {
+	PlayerID string `json:"playerID"`
+}
+
+// AcceptFriend accepts a friend request
+type AcceptFriend struct {
+	PlayerID string `json:"playerID"`
+}
+
+// DeclineFriend declines a friend request
+type DeclineFriend struct {
+	PlayerID string `json:"playerID"`
+}
+
+// RemoveFriend ends a friendship or withdraws a friend request
+type RemoveFriend struct {
+	PlayerID string `json:"playerID"`
+}
+
+// BlockPlayer stops a player from challenging, chatting with or
+// spectating the sender, and removes them as a friend
+type BlockPlayer struct {
+	PlayerID string `json:"playerID"`
+}
+
+// UnblockPlayer lifts a block
+type UnblockPlayer struct {
+	PlayerID string `json:"playerID"`
+}
+
+// PlayerStats requests, and is answered with, a player's statistics.
+// If OpponentID is set, the answer includes the player's head-to-head
+// record against them
+type PlayerStats struct {
+	PlayerID   string             `json:"playerID"`
+	OpponentID string             `json:"opponentID,omitempty"`
+	Stats      *store.PlayerStats `json:"stats,omitempty"`
+	HeadToHead *store.HeadToHead  `json:"headToHead,omitempty"`
+}
+
+// Leaderboard requests, and is answered with, one page of a
+// leaderboard. By is "rating" (which needs a Pool) or "wins"
+type Leaderboard struct {
+	By     string                 `json:"by"`
+	Pool   string                 `json:"pool,omitempty"`
+	Offset int                    `json:"offset"`
+	Limit  int                    `json:"limit"`
+	Page   *store.LeaderboardPage `json:"page,omitempty"`
+}
+
+// CreateTournament sets up a tournament organized by the sender. The
+// response is a TournamentStandings for the new tournament
+type CreateTournament struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+
+	// Rounds is only used by Swiss tournaments. If 0, enough rounds
+	// are played to separate a winner
+	Rounds int `json:"rounds,omitempty"`
+
+	Variant     string `json:"variant,omitempty"`
+	TimeControl string `json:"timeControl,omitempty"`
+	Casual      bool   `json:"casual,omitempty"`
+}
+
+// JoinTournament registers the sender in a tournament
+type JoinTournament struct {
+	TournamentID string `json:"tournamentID"`
+}
+
+// StartTournament starts a tournament the sender organized
+type StartTournament struct {
+	TournamentID string `json:"tournamentID"`
+}
+
+// TournamentStandings requests, and is answered with, the standings
+// and games of a tournament
+type TournamentStandings struct {
+	TournamentID string `json:"tournamentID"`
+	*tournament.Standings
+}
+
+// JoinQueue puts the player in the matchmaking queue. A MatchFound is
+// sent once an opponent is found
+type JoinQueue struct {
+	Variant     string `json:"variant,omitempty"`
+	TimeControl string `json:"timeControl,omitempty"`
+	Casual      bool   `json:"casual,omitempty"`
+
+	// RatingRange is how far from the player's rating an opponent
+	// may initially be. It widens the longer the player waits
+	RatingRange int `json:"ratingRange,omitempty"`
+}
+
+// LeaveQueue takes the player out of the matchmaking queue
+type LeaveQueue struct{}
+
+// MatchFound tells a queued player that their game has started
+type MatchFound struct {
+	GameID       string `json:"gameID"`
+	OpponentID   string `json:"opponentID"`
+	OpponentName string `json:"opponentName"`
+}
+
+// PlayMove plays a move in one of the player's games. The move is
+// always played as the sender. Version 1 sends the coordinate as part
+// of a game.Move, under "move"
 type PlayMove struct {
-	GameID string    `json:"gameID"`
-	Move   game.Move `json:"move"`
+	GameID     string          `json:"gameID"`
+	Coordinate game.Coordinate `json:"coordinate"`
+}
+
+// Rematch asks for a rematch of a finished game. The rematch starts
+// once both players have sent it
+type Rematch struct {
+	GameID string `json:"gameID"`
+}
+
+// DeclineRematch turns down a rematch the opponent asked for, or
+// withdraws the sender's own request
+type DeclineRematch struct {
+	GameID string `json:"gameID"`
 }
 
 type LoginSuccess struct {
 	Username string            `json:"username"`
 	PlayerID string            `json:"playerID"`
 	Games    []store.GameState `json:"games"`
+
+	// SessionToken can be used to resume the session after the
+	// socket drops
+	SessionToken string `json:"sessionToken"`
+	// Resumed is set when an existing session was resumed
+	Resumed bool `json:"resumed,omitempty"`
 }
 
 type UserLookup struct {
 	Username string `json:"username"`
 	PlayerID string `json:"playerID"`
+
+	// Ratings is filled in on responses, with one entry per pool
+	// the player has played rated games in
+	Ratings []store.Rating `json:"ratings,omitempty"`
 }
 
+// RatingHistory requests, and is answered with, a player's rating
+// after each of their rated games in a pool
+type RatingHistory struct {
+	PlayerID string               `json:"playerID"`
+	Pool     string               `json:"pool"`
+	History  []store.RatingChange `json:"history,omitempty"`
+}
+
+// Ack answers a request that has no other response, once it has
+// succeeded
+type Ack struct{}
+
+// ErrorMessage answers a request that failed. It is also pushed,
+// unsolicited, before the server closes the socket
 type ErrorMessage struct {
-	Message string `json:"message"`
+	// Code is one of the ErrorCode constants
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
 
 	// if Recoverable is false, then the websocket is closed after this
 	// message is sent
 	Recoverable bool `json:"recoverable"`
 }
+
+func (e ErrorMessage) Error() string {
+	return e.Message
+}
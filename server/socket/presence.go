@@ -0,0 +1,184 @@
+package socket
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Presence statuses. A player is online if any of their sessions is,
+// away if they are connected but every session is away, and offline
+// with no sessions
+const (
+	StatusOnline  = "online"
+	StatusAway    = "away"
+	StatusOffline = "offline"
+)
+
+// eventBufferSize is how many pushed messages can be waiting to be
+// sent to a connection before further messages are dropped
+const eventBufferSize = 32
+
+// push queues a message for the connection's message loop to send. It
+// never blocks, dropping the message if the connection has fallen
+// behind
+func (conn *clientConn) push(msg interface{}) {
+	select {
+	case conn.events <- msg:
+	default:
+	}
+}
+
+// presenceLocked returns a player's presence status. The session
+// mutex must be held
+func (s *Server) presenceLocked(playerID string) string {
+	status := StatusOffline
+	for conn := range s.sessions[playerID] {
+		if !conn.connected {
+			continue
+		}
+		if conn.status == StatusOnline {
+			return StatusOnline
+		}
+		status = StatusAway
+	}
+	return status
+}
+
+func (s *Server) presence(playerID string) string {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+	return s.presenceLocked(playerID)
+}
+
+// updateSessions applies a change to a player's sessions, and tells
+// everyone watching the player if their status changed as a result
+func (s *Server) updateSessions(conn *clientConn, change func()) {
+	s.sessionMutex.Lock()
+	before := s.presenceLocked(conn.playerID)
+	change()
+	after := s.presenceLocked(conn.playerID)
+	s.sessionMutex.Unlock()
+
+	if before != after {
+		s.broadcastPresence(conn.playerID, conn.username, after)
+	}
+}
+
+func (s *Server) addSession(conn *clientConn) {
+	s.updateSessions(conn, func() {
+		if s.sessions[conn.playerID] == nil {
+			s.sessions[conn.playerID] = map[*clientConn]bool{}
+		}
+		s.sessions[conn.playerID][conn] = true
+		s.resumable[conn.token] = conn
+		conn.connected = true
+	})
+}
+
+func (s *Server) removeSession(conn *clientConn) {
+	s.updateSessions(conn, func() {
+		delete(s.sessions[conn.playerID], conn)
+		if len(s.sessions[conn.playerID]) == 0 {
+			delete(s.sessions, conn.playerID)
+		}
+		delete(s.resumable, conn.token)
+	})
+}
+
+// setConnected records whether a session has a socket. Disconnected
+// sessions don't count towards the player's presence
+func (s *Server) setConnected(conn *clientConn, connected bool) {
+	s.updateSessions(conn, func() {
+		conn.connected = connected
+	})
+}
+
+func (s *Server) setStatus(conn *clientConn, status string) {
+	s.updateSessions(conn, func() {
+		conn.status = status
+	})
+}
+
+// presenceWatchers returns the players who are told when playerID's
+// status changes: their friends and opponents in unfinished games,
+// unless either has blocked the other
+func (s *Server) presenceWatchers(playerID string) ([]string, error) {
+	opponents, err := s.games.ActiveOpponents(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	friends, err := s.games.Friends(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	watchers := []string{}
+	seen := map[string]bool{}
+	for _, f := range friends {
+		seen[f.PlayerID] = true
+		watchers = append(watchers, f.PlayerID)
+	}
+	for _, o := range opponents {
+		if seen[o] {
+			continue
+		}
+
+		blocked, err := s.games.IsBlocked(playerID, o)
+		if err != nil {
+			return nil, err
+		} else if !blocked {
+			watchers = append(watchers, o)
+		}
+	}
+
+	return watchers, nil
+}
+
+func (s *Server) broadcastPresence(playerID, username, status string) {
+	watchers, err := s.presenceWatchers(playerID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	msg := Presence{
+		PlayerID: playerID,
+		Username: username,
+		Status:   status,
+	}
+
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+	for _, watcher := range watchers {
+		for conn := range s.sessions[watcher] {
+			conn.push(msg)
+		}
+	}
+}
+
+// onlinePlayers returns the presence of every connected player,
+// ordered by username
+func (s *Server) onlinePlayers() []Presence {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+
+	players := []Presence{}
+	for playerID, conns := range s.sessions {
+		username := ""
+		for conn := range conns {
+			username = conn.username
+			break
+		}
+		players = append(players, Presence{
+			PlayerID: playerID,
+			Username: username,
+			Status:   s.presenceLocked(playerID),
+		})
+	}
+
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Username < players[j].Username
+	})
+	return players
+}
@@ -0,0 +1,75 @@
+package socket
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// awaitPresence returns the status in the next Presence pushed to c
+func (c *testClient) awaitPresence(playerID string) string {
+	c.t.Helper()
+	var p Presence
+	json.Unmarshal(c.awaitPush("Presence").Payload, &p)
+	if p.PlayerID != playerID {
+		c.t.Fatalf("got the presence of %v, want %v", p.PlayerID, playerID)
+	}
+	return p.Status
+}
+
+func TestPresence(t *testing.T) {
+	ts := startServer(t)
+	alice, _ := ts.games.CreatePlayer("alice", "alice")
+	bob, _ := ts.games.CreatePlayer("bob", "bob")
+	if err := ts.games.SendFriendRequest(alice.UUID, bob.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.games.AcceptFriendRequest(bob.UUID, alice.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	bobsSocket := ts.login(t, "bob")
+	first := ts.login(t, "alice")
+	if status := bobsSocket.awaitPresence(alice.UUID); status != StatusOnline {
+		t.Errorf("got %v logging in", status)
+	}
+
+	if r := first.request("SetPresence", SetPresence{Status: StatusAway}); r.Type != "Ack" {
+		t.Fatalf("got %v %s", r.Type, r.Payload)
+	}
+	if status := bobsSocket.awaitPresence(alice.UUID); status != StatusAway {
+		t.Errorf("got %v going away", status)
+	}
+
+	if r := first.request("SetPresence", SetPresence{Status: "busy"}); r.errorCode() != CodeInvalidRequest {
+		t.Errorf("got %v %s for an unknown status", r.Type, r.Payload)
+	}
+
+	// one online session is enough to be online
+	second := ts.login(t, "alice")
+	if status := bobsSocket.awaitPresence(alice.UUID); status != StatusOnline {
+		t.Errorf("got %v with a second session", status)
+	}
+
+	// and losing the other one doesn't change that, so the next
+	// change bob hears about is the second session going away
+	first.ws.Close()
+	if r := second.request("SetPresence", SetPresence{Status: StatusAway}); r.Type != "Ack" {
+		t.Fatalf("got %v %s", r.Type, r.Payload)
+	}
+	if status := bobsSocket.awaitPresence(alice.UUID); status != StatusAway {
+		t.Errorf("got %v with the second session away", status)
+	}
+
+	r := bobsSocket.request("OnlinePlayers", OnlinePlayers{})
+	var online OnlinePlayers
+	json.Unmarshal(r.Payload, &online)
+	if len(online.Players) != 2 || online.Players[0].PlayerID != alice.UUID || online.Players[0].Status != StatusAway {
+		t.Errorf("got online players %s", r.Payload)
+	}
+
+	// sessions waiting to be resumed don't count
+	second.ws.Close()
+	if status := bobsSocket.awaitPresence(alice.UUID); status != StatusOffline {
+		t.Errorf("got %v with every socket closed", status)
+	}
+}
@@ -0,0 +1,178 @@
+package socket
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// ProtocolVersion is the newest version of the socket protocol. Clients
+// that don't send a Hello speak version 1
+const ProtocolVersion = 2
+
+// Capabilities are optional features a client can ask for in its Hello
+const (
+	// CapabilityGameDeltas has changes to open games pushed as
+	// GameDeltas. Without it, the full GameState is pushed every time
+	CapabilityGameDeltas = "gameDeltas"
+)
+
+// protocol describes one version of the socket protocol: how strictly
+// its requests are read, which capabilities it has, and how messages
+// whose form has changed since are translated
+type protocol struct {
+	version int
+	// strict versions reject requests with fields they don't know.
+	// Later versions ignore them, so that a client can send fields that
+	// only newer servers understand
+	strict bool
+	// capabilities are the ones a client may ask for
+	capabilities []string
+	// adapters translate the message types whose form differs from
+	// the one in messages.go, by message type
+	adapters map[string]adapter
+}
+
+// adapter translates one message type between the form a protocol
+// version uses and the current one
+type adapter struct {
+	// decode reads a request sent in the version's form, and returns
+	// it in the current one
+	decode func(payload func(interface{}) error) (interface{}, error)
+	// encode converts a message to the version's form before it is sent
+	encode func(payload interface{}) interface{}
+}
+
+// protocols holds every supported version. The zero protocol is spoken
+// until the client's version is known, and reads requests leniently so
+// that a Hello from any version can be understood
+var protocols = map[int]protocol{
+	1: {
+		version: 1,
+		strict:  true,
+		adapters: map[string]adapter{
+			"PlayMove": {decode: decodePlayMoveV1},
+		},
+	},
+	2: {
+		version:      2,
+		capabilities: []string{CapabilityGameDeltas},
+	},
+}
+
+// defaultProtocol is spoken by clients that don't send a Hello
+var defaultProtocol = protocols[1]
+
+// playMoveV1 is PlayMove as version 1 sends it, with the player to
+// play as in the move. Moves are always played as the sender, so
+// version 2 only sends the coordinate
+type playMoveV1 struct {
+	GameID string    `json:"gameID"`
+	Move   game.Move `json:"move"`
+}
+
+func decodePlayMoveV1(payload func(interface{}) error) (interface{}, error) {
+	var v1 playMoveV1
+	err := payload(&v1)
+	if err != nil {
+		return nil, err
+	}
+	return &PlayMove{GameID: v1.GameID, Coordinate: v1.Move.Coordinate}, nil
+}
+
+// decode reads a request's payload, in the version's form for its type
+func (p protocol) decode(typ string, payload func(interface{}) error) (interface{}, error) {
+	if a, ok := p.adapters[typ]; ok && a.decode != nil {
+		return a.decode(payload)
+	}
+
+	v := valueFromType(typ)
+	if v == nil {
+		return nil, nil
+	}
+	err := payload(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// envelope wraps a message to send, in the version's form for its type
+func (p protocol) envelope(requestID int, payload interface{}) OutgoingSocketMessage {
+	typ := reflect.TypeOf(payload).Name()
+	if a, ok := p.adapters[typ]; ok && a.encode != nil {
+		payload = a.encode(payload)
+	}
+
+	return OutgoingSocketMessage{
+		Type:        typ,
+		Payload:     payload,
+		RequestID:   requestID,
+		Unsolicited: requestID == 0,
+	}
+}
+
+// negotiate picks the protocol asked for by a Hello, and the
+// capabilities to enable
+func negotiate(hello *Hello) (protocol, map[string]bool, error) {
+	p, ok := protocols[hello.ProtocolVersion]
+	if !ok {
+		return protocol{}, nil, ErrorMessage{
+			Code: CodeUnsupportedVersion,
+			Message: fmt.Sprintf("unsupported protocol version %v, the server speaks versions 1 to %v",
+				hello.ProtocolVersion, ProtocolVersion),
+		}
+	}
+	return p, p.enable(hello.Capabilities), nil
+}
+
+// enable returns the capabilities enabled for a client that asked for
+// the given ones. Capabilities the version doesn't have are left out,
+// so that clients can ask for features only newer servers have
+func (p protocol) enable(asked []string) map[string]bool {
+	enabled := map[string]bool{}
+	for _, c := range asked {
+		for _, supported := range p.capabilities {
+			if c == supported {
+				enabled[c] = true
+			}
+		}
+	}
+	return enabled
+}
+
+// handshake answers a Hello, switching the session to the protocol it
+// asks for. The socket is closed if the version isn't supported
+func (conn *clientConn) handshake(requestID int, hello *Hello) error {
+	p, capabilities, err := negotiate(hello)
+	if err != nil {
+		conn.respondError(requestID, err)
+		return err
+	}
+
+	conn.protocol = p
+	conn.capabilities = capabilities
+
+	response := Hello{ProtocolVersion: p.version}
+	for c := range capabilities {
+		response.Capabilities = append(response.Capabilities, c)
+	}
+	sort.Strings(response.Capabilities)
+	return conn.sendUnsequenced(requestID, response)
+}
+
+// sameCapabilities reports whether two handshakes enabled the same
+// capabilities
+func sameCapabilities(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for c := range a {
+		if !b[c] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package socket
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		hello        Hello
+		version      int
+		capabilities map[string]bool
+		err          ErrorCode
+	}{
+		{Hello{ProtocolVersion: 1}, 1, map[string]bool{}, ""},
+		{Hello{ProtocolVersion: 1, Capabilities: []string{CapabilityGameDeltas}}, 1, map[string]bool{}, ""},
+		{Hello{ProtocolVersion: 2}, 2, map[string]bool{}, ""},
+		{Hello{ProtocolVersion: 2, Capabilities: []string{CapabilityGameDeltas, "telepathy"}}, 2, map[string]bool{CapabilityGameDeltas: true}, ""},
+		{Hello{ProtocolVersion: 0}, 0, nil, CodeUnsupportedVersion},
+		{Hello{ProtocolVersion: ProtocolVersion + 1}, 0, nil, CodeUnsupportedVersion},
+	}
+
+	for _, test := range tests {
+		p, capabilities, err := negotiate(&test.hello)
+		if test.err != "" {
+			if msg, ok := err.(ErrorMessage); !ok || msg.Code != test.err || msg.Recoverable {
+				t.Errorf("%+v: got error %#v, want %v", test.hello, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil || p.version != test.version || !reflect.DeepEqual(capabilities, test.capabilities) {
+			t.Errorf("%+v: got version %v with %v, %v", test.hello, p.version, capabilities, err)
+		}
+	}
+}
+
+func TestProtocolAdapters(t *testing.T) {
+	c := game.NewCoordinate(2, 2, 1, 3)
+	forms := map[int]interface{}{
+		1: playMoveV1{GameID: "game", Move: game.Move{PlayerID: "o", Coordinate: c}},
+		2: PlayMove{GameID: "game", Coordinate: c},
+	}
+
+	for version, form := range forms {
+		for _, codec := range []codec{jsonCodec{}, msgpackCodec{}} {
+			b := &bytes.Buffer{}
+			err := codec.encode(b, map[string]interface{}{
+				"messageType": "PlayMove",
+				"payload":     form,
+				"requestID":   1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := parseMessage(codec, protocols[version], b)
+			if err != nil {
+				t.Errorf("version %v, %T: %v", version, codec, err)
+			} else if m, ok := req.payload.(*PlayMove); !ok || *m != (PlayMove{GameID: "game", Coordinate: c}) {
+				t.Errorf("version %v, %T: parsed as %#v", version, codec, req.payload)
+			}
+		}
+	}
+
+	// messages are sent in the version's form
+	p := protocol{adapters: map[string]adapter{
+		"Ack": {encode: func(interface{}) interface{} { return "ok" }},
+	}}
+	if msg := p.envelope(3, Ack{}); msg.Type != "Ack" || msg.Payload != "ok" || msg.RequestID != 3 {
+		t.Errorf("got %+v", msg)
+	}
+	if msg := p.envelope(0, Rematch{GameID: "game"}); msg.Payload != (Rematch{GameID: "game"}) || !msg.Unsolicited {
+		t.Errorf("got %+v", msg)
+	}
+}
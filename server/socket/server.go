@@ -1,22 +1,42 @@
 package socket
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/heartles/uttt/server/config"
+	"github.com/heartles/uttt/server/game"
 	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
 )
 
 type Server struct {
-	config   *config.Config
-	upgrader websocket.Upgrader
-	games    *store.GameService
+	config      *config.Config
+	upgrader    websocket.Upgrader
+	games       *store.GameService
+	matchmaker  *store.Matchmaker
+	tournaments *tournament.Service
+	chat        *store.ChatService
+	analyzer    *analyzer
+
+	// sessions holds every connection of each logged in player, since
+	// a player can have several tabs open
+	sessions map[string]map[*clientConn]bool
+	// resumable maps session tokens to sessions
+	resumable    map[string]*clientConn
+	sessionMutex sync.Mutex
 }
 
-func NewServer(c *config.Config, gameSvc *store.GameService) *Server {
+// chatBufferSize is how many chat messages can be waiting to be sent
+// to a connection before further messages are dropped
+const chatBufferSize = 32
+
+func NewServer(c *config.Config, gameSvc *store.GameService, matchmaker *store.Matchmaker, tournaments *tournament.Service, chat *store.ChatService) *Server {
 	checkOriginFunc := func(*http.Request) bool {
 		return true
 	}
@@ -31,8 +51,16 @@ func NewServer(c *config.Config, gameSvc *store.GameService) *Server {
 			ReadBufferSize:  512,
 			WriteBufferSize: 1024,
 			CheckOrigin:     checkOriginFunc,
+			Subprotocols:    subprotocols,
 		},
 		gameSvc,
+		matchmaker,
+		tournaments,
+		chat,
+		newAnalyzer(c.AnalysisMoveTime),
+		map[string]map[*clientConn]bool{},
+		map[string]*clientConn{},
+		sync.Mutex{},
 	}
 }
 
@@ -41,105 +69,292 @@ func (s *Server) Handle(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
+	t := newWebsocketTransport(socket, s.config)
 
-	conn, err := s.login(socket)
+	conn, err := s.login(t)
 	if err != nil {
 		fmt.Println(err)
-		socket.Close()
+		t.close()
+		return
+	} else if conn == nil {
+		// an existing session's message loop has taken over the socket
 		return
 	}
 
 	s.runMessageLoop(conn)
 }
 
-func (s *Server) login(socket *websocket.Conn) (*clientConn, error) {
-	conn := clientConn{
-		socket: socket,
+// newSession returns a session, not yet logged in, whose messages are
+// carried by t
+func newSession(t transport) *clientConn {
+	return &clientConn{
+		transport:  t,
+		spectating: map[string]bool{},
+		versions:   map[string]int{},
+		chatCh:     make(chan store.ChatMessage, chatBufferSize),
+		events:     make(chan interface{}, eventBufferSize),
+		analysisCh: make(chan analysisResult, 1),
+		status:     StatusOnline,
+		token:      uuid.New().String(),
+		attachCh:   make(chan resumeRequest),
+		done:       make(chan struct{}),
 	}
+}
 
-	req, err := conn.nextMessageSync()
-	request, ok := req.(*LoginRequest)
+// login reads the Hello, if any, and the LoginRequest from a new
+// socket. It returns the new session, or nil if the request resumed an
+// existing one
+func (s *Server) login(t *websocketTransport) (*clientConn, error) {
+	conn := newSession(t)
+
+	req, err := t.next(conn.protocol)
 	if err != nil {
 		return nil, err
-	} else if !ok {
-		return nil, fmt.Errorf("wrong type recieved: %#v", req)
 	}
 
-	loginID := request.LoginID
+	if hello, ok := req.payload.(*Hello); ok {
+		err = conn.handshake(req.id, hello)
+		if err != nil {
+			return nil, err
+		}
 
-	if s.config.VerifyUser {
-		panic("not implemented")
+		req, err = t.next(conn.protocol)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		conn.protocol = defaultProtocol
+		conn.capabilities = defaultProtocol.enable(nil)
+	}
+
+	request, ok := req.payload.(*LoginRequest)
+	if !ok {
+		return nil, fmt.Errorf("wrong type recieved: %#v", req.payload)
 	}
 
-	player, err := s.games.CreatePlayer(loginID, loginID)
+	if request.SessionToken != "" {
+		return nil, s.resumeSession(conn, req.id, request)
+	}
+
+	player, err := s.loginPlayer(request)
 	if err != nil {
-		conn.sendError("invalid login", false)
+		conn.respondError(req.id, ErrorMessage{
+			Code:    CodeInvalidLogin,
+			Message: "invalid login",
+		})
 		return nil, err
 	}
 
 	conn.playerID = player.UUID
-	conn.sendMessage(LoginSuccess{
-		Username: player.Username,
-		PlayerID: player.UUID,
+	conn.username = player.Username
+	conn.sendUnsequenced(req.id, LoginSuccess{
+		Username:     player.Username,
+		PlayerID:     player.UUID,
+		SessionToken: conn.token,
 	})
 
-	return &conn, nil
+	return conn, nil
+}
+
+// loginPlayer returns the player a LoginRequest logs in as, creating
+// them if they log in by name for the first time. Bot accounts are
+// played by the server, so nobody can log in as one
+func (s *Server) loginPlayer(request *LoginRequest) (*store.Player, error) {
+	player, err := s.findLoginPlayer(request)
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := s.games.IsBot(player.UUID)
+	if err != nil {
+		return nil, err
+	} else if bot {
+		return nil, errBotLogin
+	}
+	return player, nil
+}
+
+var errBotLogin = errors.New("can't log in as a bot")
+
+func (s *Server) findLoginPlayer(request *LoginRequest) (*store.Player, error) {
+	if request.APIKey != "" {
+		playerID, err := s.games.APIKeyPlayer(request.APIKey)
+		if err != nil {
+			return nil, err
+		}
+
+		player, err := s.games.TryLookupPlayerUUID(playerID)
+		if err == nil && player == nil {
+			err = store.ErrInvalidAPIKey
+		}
+		return player, err
+	}
+
+	loginID := request.LoginID
+
+	if s.config.VerifyUser {
+		panic("not implemented")
+	}
+
+	return s.games.CreatePlayer(loginID, loginID)
 }
 
 func (s *Server) runMessageLoop(conn *clientConn) {
-	incomingMsgs := s.listenForMessages(conn)
+	incomingMsgs := conn.transport.listen(conn.protocol, conn.done)
 
 	openGames, newGameCh, err := s.games.OpenGamesForPlayer(conn.playerID)
 	if err != nil {
 		panic(err)
 	}
-	defer s.games.CloseGames(openGames)
+	conn.openGames = openGames
+	defer s.games.CloseNewGameCh(conn.playerID, newGameCh)
+	defer func() {
+		conn.cancelCtx()
+		s.games.CloseGames(conn.openGames)
+		s.games.CloseGames(conn.addedGames)
+	}()
+	defer func() { s.matchmaker.Leave(conn.matchCh) }()
+	defer s.chat.LeaveAll(conn.chatCh)
+
+	s.addSession(conn)
+	defer s.removeSession(conn)
+	defer close(conn.done)
+	defer func() {
+		if conn.transport != nil {
+			conn.transport.close()
+		}
+	}()
+
+	pingCh, idleCh, stopHeartbeat := s.startHeartbeat(conn)
+	defer stopHeartbeat()
 
 	for _, g := range openGames {
 		s.handleGameUpdate(conn, g.Game)
 	}
-	var newestGame store.NewGameNotification
-	defer newestGame.Game.Close(newestGame.UpdateCh)
-
-	ctx, cancelCtx := context.WithCancel(context.TODO())
-	conn.cancelCtx = cancelCtx
 
-	openGamesCh := s.games.ListenAny(openGames, ctx)
+	openGamesCh := conn.listenForGameUpdates(s.games)
+	var resumeTimeout <-chan time.Time
 loop:
 	for {
 		select {
-		case msg, ok := <-incomingMsgs:
+		case req, ok := <-incomingMsgs:
 			if !ok {
-				// socket closed, end message loop
-				break loop
+				if conn.ended || !conn.transport.readsRequests() {
+					break loop
+				}
+
+				// the socket dropped, or went quiet for longer than
+				// the pong timeout. Keep the session running for a
+				// while in case the client reconnects
+				conn.transport.close()
+				conn.transport = nil
+				incomingMsgs = nil
+				resumeTimeout = time.After(sessionResumeTimeout)
+				s.setConnected(conn, false)
+				break
 			}
 
-			s.handleMessage(conn, msg, openGames)
+			conn.lastMessage = time.Now()
+			if req.err != nil {
+				conn.malformedRequest(req.id)
+				break loop
+			}
+			s.handleMessage(conn, req)
+			break
+		case <-pingCh:
+			if conn.transport != nil {
+				conn.transport.ping()
+			}
+			break
+		case <-idleCh:
+			if conn.transport != nil && !conn.transport.readsRequests() {
+				break
+			}
+			if time.Since(conn.lastMessage) >= s.config.IdleTimeout {
+				conn.sendError(CodeIdleTimeout, "disconnected for inactivity", false)
+				break loop
+			}
 			break
+		case req := <-conn.attachCh:
+			if s.resume(conn, req) {
+				incomingMsgs = conn.transport.listen(conn.protocol, conn.done)
+				resumeTimeout = nil
+			}
+			break
+		case <-resumeTimeout:
+			break loop
 
-		// either a game update or we need to update our new game
+		// either a game update or the set of games has changed
 		case gameIdx, ok := <-openGamesCh:
 			if !ok {
-				if newestGame.Game != nil {
-					openGames = append(openGames, newestGame)
-				}
-				ctx, cancelCtx = context.WithCancel(context.TODO())
-				openGamesCh = s.games.ListenAny(openGames, ctx)
-				newestGame = store.NewGameNotification{} // zero out old struct
+				openGamesCh = conn.listenForGameUpdates(s.games)
 				break
 			}
-			s.handleGameUpdate(conn, openGames[gameIdx].Game)
+			s.handleGameUpdate(conn, conn.openGames[gameIdx].Game)
 			break
-		case newestGame = <-newGameCh:
+		case newestGame := <-newGameCh:
 			s.handleGameUpdate(conn, newestGame.Game)
-			cancelCtx()
+			conn.watchGame(newestGame)
+			break
+		case event := <-conn.events:
+			conn.sendMessage(event)
+			break
+		case chatMsg := <-conn.chatCh:
+			conn.sendMessage(chatMsg)
 			break
+		case result := <-conn.analysisCh:
+			conn.analyzing = false
+			if result.err != nil {
+				conn.respondError(result.requestID, result.err)
+			} else {
+				conn.respond(result.requestID, result.response)
+			}
+			break
+		case match, ok := <-conn.matchCh:
+			conn.matchCh = nil
+			if ok {
+				s.handleMatchFound(conn, match)
+			}
+			break
+		}
+
+		// with no games open there's no listener to stop, so a
+		// change to the set of games is picked up here instead
+		if openGamesCh == nil && conn.gamesChanged() {
+			openGamesCh = conn.listenForGameUpdates(s.games)
 		}
 	}
 }
 
-func (s *Server) handleGameUpdate(conn *clientConn, g *store.Game) {
+// gameState returns the full state of a game, to be sent to the client
+func (s *Server) gameState(conn *clientConn, g *store.Game) (*store.GameState, error) {
 	state, err := g.GetGameState(conn.playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	state.PlayerXPresence = s.presence(state.PlayerX)
+	state.PlayerOPresence = s.presence(state.PlayerO)
+	conn.versions[g.UUID()] = state.Version
+	return state, nil
+}
+
+// handleGameUpdate brings the client up to date with a game. It is sent
+// the changes since the version it last saw as GameDeltas if possible
+// and it has CapabilityGameDeltas, and the full GameState otherwise
+func (s *Server) handleGameUpdate(conn *clientConn, g *store.Game) {
+	if version, ok := conn.versions[g.UUID()]; ok && conn.capabilities[CapabilityGameDeltas] {
+		deltas, current, ok := g.DeltasSince(version)
+		if ok {
+			for _, d := range deltas {
+				conn.sendMessage(d)
+			}
+			conn.versions[g.UUID()] = current
+			return
+		}
+	}
+
+	state, err := s.gameState(conn, g)
 	if err != nil {
 		panic(err)
 	}
@@ -147,79 +362,337 @@ func (s *Server) handleGameUpdate(conn *clientConn, g *store.Game) {
 	conn.sendMessage(*state)
 }
 
-func (s *Server) handleMessage(conn *clientConn, msg interface{}, games []store.NewGameNotification) {
+// handleMessage answers a request with its response, an Ack, or an
+// ErrorMessage. An Analyze is only answered once its search is done
+func (s *Server) handleMessage(conn *clientConn, req request) {
+	if analyze, ok := req.payload.(*Analyze); ok {
+		// the search is answered once it finishes
+		err := s.startAnalysis(conn, req.id, analyze)
+		if err != nil {
+			conn.respondError(req.id, err)
+		}
+		return
+	}
+
+	response, err := s.handleRequest(conn, req.payload)
+	if err != nil {
+		conn.respondError(req.id, err)
+	} else if response == nil {
+		conn.respond(req.id, Ack{})
+	} else {
+		conn.respond(req.id, response)
+	}
+}
+
+// handleRequest carries out a request. It returns the response, or nil
+// if the request has none
+func (s *Server) handleRequest(conn *clientConn, msg interface{}) (interface{}, error) {
 	switch v := msg.(type) {
+	case *Hello:
+		return nil, requestFailed(CodeInvalidRequest, "Hello must be sent before the LoginRequest")
 	case *NewGame:
-		s.handleNewGame(conn, v)
-		break
+		return nil, s.handleNewGame(conn, v)
 	case *PlayMove:
-		idx := 0
-		for idx = range games {
-			if games[idx].Game.UUID() == v.GameID {
-				err := games[idx].Game.PlayMove(v.Move)
-				if err != nil {
-					conn.sendError(err.Error(), false)
-				}
-			}
+		g := conn.findPlayedGame(v.GameID)
+		if g == nil {
+			return nil, requestError(store.ErrGameNotFound)
 		}
-		break
-	case *UserLookup:
-		username := v.Username
-		playerID := v.PlayerID
-		if username != "" {
-			s.handleLookupByUsername(conn, username)
-		} else if playerID != "" {
-			panic("not impl")
-		} else {
-			conn.sendError("Must specify either username or playerID", true)
+
+		err := g.PlayMove(game.Move{PlayerID: conn.playerID, Coordinate: v.Coordinate})
+		if err != nil {
+			return nil, requestError(err)
+		}
+		return nil, nil
+	case *Rematch:
+		g := conn.findPlayedGame(v.GameID)
+		if g == nil {
+			return nil, requestError(store.ErrGameNotFound)
+		}
+		return nil, s.handleRematch(conn, g)
+	case *DeclineRematch:
+		g := conn.findPlayedGame(v.GameID)
+		if g == nil {
+			return nil, requestError(store.ErrGameNotFound)
+		}
+		return nil, requestError(s.games.DeclineRematch(g, conn.playerID))
+	case *JoinChat:
+		return s.handleJoinChat(conn, v)
+	case *LeaveChat:
+		s.chat.Leave(v.Room, v.GameID, conn.chatCh)
+		return nil, nil
+	case *SendChat:
+		err := s.chat.Send(v.Room, v.GameID, conn.playerID, v.Body)
+		if err != nil {
+			return nil, requestError(err)
+		}
+		return nil, nil
+	case *SetPresence:
+		if v.Status != StatusOnline && v.Status != StatusAway {
+			return nil, requestFailed(CodeInvalidRequest, "invalid presence status")
+		}
+		s.setStatus(conn, v.Status)
+		return nil, nil
+	case *OnlinePlayers:
+		return OnlinePlayers{Players: s.onlinePlayers()}, nil
+	case *FriendsList:
+		return s.handleFriendsList(conn)
+	case *FriendRequest:
+		return nil, s.handleFriendAction(conn, v.PlayerID, s.games.SendFriendRequest)
+	case *AcceptFriend:
+		return nil, s.handleFriendAction(conn, v.PlayerID, s.games.AcceptFriendRequest)
+	case *DeclineFriend:
+		return nil, s.handleFriendAction(conn, v.PlayerID, s.games.DeclineFriendRequest)
+	case *RemoveFriend:
+		return nil, s.handleFriendAction(conn, v.PlayerID, s.games.RemoveFriend)
+	case *BlockPlayer:
+		return nil, s.handleFriendAction(conn, v.PlayerID, s.games.BlockPlayer)
+	case *UnblockPlayer:
+		return nil, s.handleFriendAction(conn, v.PlayerID, s.games.UnblockPlayer)
+	case *Spectate:
+		return s.handleSpectate(conn, v.GameID)
+	case *StopSpectating:
+		if conn.spectating[v.GameID] {
+			delete(conn.spectating, v.GameID)
+			delete(conn.versions, v.GameID)
+			conn.unwatchGame(v.GameID)
+		}
+		return nil, nil
+	case *GameSnapshot:
+		g := conn.findGame(v.GameID)
+		if g == nil {
+			return nil, requestError(store.ErrGameNotFound)
 		}
 
-	default:
-		fmt.Printf("Unknown message type %+v\n", msg)
+		state, err := s.gameState(conn, g)
+		if err != nil {
+			return nil, err
+		}
+		return *state, nil
+	case *GameReview:
+		review, err := s.games.GameReview(v.GameID, conn.playerID)
+		if err != nil {
+			return nil, requestError(err)
+		}
+		return GameReview{GameID: v.GameID, Review: review}, nil
+	case *RatingHistory:
+		return s.handleRatingHistory(conn, v)
+	case *PlayerStats:
+		return s.handlePlayerStats(conn, v)
+	case *Leaderboard:
+		return s.handleLeaderboard(conn, v)
+	case *CreateTournament:
+		return s.handleCreateTournament(conn, v)
+	case *JoinTournament:
+		err := s.tournaments.Register(v.TournamentID, conn.playerID)
+		if err != nil {
+			return nil, requestError(err)
+		}
+		return nil, nil
+	case *StartTournament:
+		err := s.tournaments.Start(v.TournamentID, conn.playerID)
+		if err != nil {
+			return nil, requestError(err)
+		}
+		return nil, nil
+	case *TournamentStandings:
+		return s.handleTournamentStandings(conn, v.TournamentID)
+	case *JoinQueue:
+		return nil, s.handleJoinQueue(conn, v)
+	case *LeaveQueue:
+		s.matchmaker.Leave(conn.matchCh)
+		conn.matchCh = nil
+		return nil, nil
+	case *UserLookup:
+		if v.Username != "" {
+			return s.handleLookup(s.games.TryLookupPlayerUsername(v.Username))
+		} else if v.PlayerID != "" {
+			return s.handleLookup(s.games.TryLookupPlayerUUID(v.PlayerID))
+		}
+		return nil, requestFailed(CodeInvalidRequest, "Must specify either username or playerID")
 	}
+
+	fmt.Printf("Unknown message type %+v\n", msg)
+	return nil, requestFailed(CodeUnknownMessageType, "unknown message type")
 }
 
-func (s *Server) handleLookupByUsername(conn *clientConn, username string) {
-	fullplayer, err := s.games.TryLookupPlayerUsername(username)
+func (s *Server) handleLookup(fullplayer *store.Player, err error) (interface{}, error) {
 	if err != nil {
-		conn.sendError("Could not lookup user", true)
-		return
+		return nil, err
 	}
 
 	if fullplayer == nil {
-		conn.sendError("User does not exist", true)
-		return
+		return nil, requestFailed(CodePlayerNotFound, "User does not exist")
 	}
 
-	err = conn.sendMessage(UserLookup{
+	ratings, err := s.games.PlayerRatings(fullplayer.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return UserLookup{
 		Username: fullplayer.Username,
 		PlayerID: fullplayer.UUID,
-	})
+		Ratings:  ratings,
+	}, nil
+}
+
+func (s *Server) handleRatingHistory(conn *clientConn, payload *RatingHistory) (interface{}, error) {
+	history, err := s.games.RatingHistory(payload.PlayerID, payload.Pool)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+
+	return RatingHistory{
+		PlayerID: payload.PlayerID,
+		Pool:     payload.Pool,
+		History:  history,
+	}, nil
 }
 
-func (s *Server) handleNewGame(conn *clientConn, payload *NewGame) {
-	err := s.games.NewGame(conn.playerID, payload.OpponentID)
+func (s *Server) handlePlayerStats(conn *clientConn, payload *PlayerStats) (interface{}, error) {
+	stats, err := s.games.PlayerStats(payload.PlayerID)
 	if err != nil {
-		conn.sendError("error processing command", true)
+		return nil, err
+	}
+
+	response := PlayerStats{
+		PlayerID:   payload.PlayerID,
+		OpponentID: payload.OpponentID,
+		Stats:      stats,
 	}
+	if payload.OpponentID != "" {
+		response.HeadToHead, err = s.games.HeadToHead(payload.PlayerID, payload.OpponentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
 }
 
-func (s *Server) listenForMessages(conn *clientConn) <-chan interface{} {
-	ch := make(chan interface{})
-	go func() {
-		defer recover() // TODO: Log errored socket
-		defer close(ch)
-		for {
-			msg, err := conn.nextMessageSync()
-			if err != nil {
-				break // TODO: Log errored socket
-			}
+func (s *Server) handleLeaderboard(conn *clientConn, payload *Leaderboard) (interface{}, error) {
+	page, err := s.games.Leaderboard(payload.By, payload.Pool, payload.Offset, payload.Limit)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	return Leaderboard{
+		By:     page.By,
+		Pool:   page.Pool,
+		Offset: page.Offset,
+		Limit:  page.Limit,
+		Page:   page,
+	}, nil
+}
 
-			ch <- msg
+func (s *Server) handleCreateTournament(conn *clientConn, payload *CreateTournament) (interface{}, error) {
+	settings := store.GameSettings{
+		Variant:     payload.Variant,
+		TimeControl: payload.TimeControl,
+		Casual:      payload.Casual,
+	}
+	id, err := s.tournaments.Create(conn.playerID, payload.Name, payload.Format, settings, payload.Rounds)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	return s.handleTournamentStandings(conn, id)
+}
+
+func (s *Server) handleTournamentStandings(conn *clientConn, tournamentID string) (interface{}, error) {
+	standings, err := s.tournaments.Standings(tournamentID)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	return TournamentStandings{
+		TournamentID: tournamentID,
+		Standings:    standings,
+	}, nil
+}
+
+func (s *Server) handleNewGame(conn *clientConn, payload *NewGame) error {
+	settings := store.GameSettings{
+		Variant:     payload.Variant,
+		TimeControl: payload.TimeControl,
+		Casual:      payload.Casual,
+		Private:     payload.Private,
+	}
+	_, err := s.games.NewGame(conn.playerID, payload.OpponentID, payload.Color, settings)
+	return requestError(err)
+}
+
+func (s *Server) handleSpectate(conn *clientConn, gameID string) (interface{}, error) {
+	g := conn.findGame(gameID)
+	if g != nil {
+		// already playing in or watching this game
+		state, err := s.gameState(conn, g)
+		if err != nil {
+			return nil, err
 		}
-	}()
-	return ch
+		return *state, nil
+	}
+
+	notif, err := s.games.SpectateGame(gameID, conn.playerID)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	conn.spectating[gameID] = true
+	conn.watchGame(notif)
+
+	state, err := s.gameState(conn, notif.Game)
+	if err != nil {
+		return nil, err
+	}
+	return *state, nil
+}
+
+func (s *Server) handleJoinChat(conn *clientConn, payload *JoinChat) (interface{}, error) {
+	history, err := s.chat.Join(payload.Room, payload.GameID, conn.playerID, conn.chatCh)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	return ChatHistory{
+		Room:     payload.Room,
+		GameID:   payload.GameID,
+		Messages: history,
+	}, nil
+}
+
+func (s *Server) handleRematch(conn *clientConn, g *store.Game) error {
+	_, err := s.games.RequestRematch(g, conn.playerID)
+	return requestError(err)
+}
+
+func (s *Server) handleJoinQueue(conn *clientConn, payload *JoinQueue) error {
+	matchCh, err := s.matchmaker.Enqueue(conn.playerID, store.MatchPreferences{
+		GameSettings: store.GameSettings{
+			Variant:     payload.Variant,
+			TimeControl: payload.TimeControl,
+			Casual:      payload.Casual,
+		},
+		RatingRange: payload.RatingRange,
+	})
+	if err != nil {
+		return requestError(err)
+	}
+
+	conn.matchCh = matchCh
+	return nil
+}
+
+func (s *Server) handleMatchFound(conn *clientConn, match store.MatchFound) {
+	opponent, err := s.games.TryLookupPlayerUUID(match.OpponentID)
+	if err != nil || opponent == nil {
+		conn.sendError(CodeInternal, "could not look up opponent", true)
+		return
+	}
+
+	conn.sendMessage(MatchFound{
+		GameID:       match.GameID,
+		OpponentID:   opponent.UUID,
+		OpponentName: opponent.Username,
+	})
 }
@@ -0,0 +1,338 @@
+package socket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/heartles/uttt/server/config"
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
+)
+
+// testServer runs a socket server on a fresh database
+type testServer struct {
+	games *store.GameService
+	url   string
+}
+
+func startServer(t *testing.T) *testServer {
+	return startServerConfig(t, &config.Config{PingInterval: time.Minute, PongTimeout: time.Minute, WriteTimeout: time.Second})
+}
+
+// startServerConfig runs a socket server with the given heartbeat and
+// timeouts
+func startServerConfig(t *testing.T, c *config.Config) *testServer {
+	games, err := store.NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matchmaker := store.NewMatchmaker(games)
+	t.Cleanup(matchmaker.Close)
+	s := NewServer(c, games, matchmaker, tournament.NewService(games), store.NewChatService(games))
+	server := httptest.NewServer(http.HandlerFunc(s.Handle))
+	t.Cleanup(server.Close)
+
+	return &testServer{games: games, url: "ws" + strings.TrimPrefix(server.URL, "http")}
+}
+
+// testClient is a logged in socket
+type testClient struct {
+	t    *testing.T
+	ws   *websocket.Conn
+	next int
+}
+
+func (ts *testServer) login(t *testing.T, loginID string) *testClient {
+	ws, _, err := websocket.DefaultDialer.Dial(ts.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	c := &testClient{t: t, ws: ws}
+	c.request("LoginRequest", LoginRequest{LoginID: loginID})
+	return c
+}
+
+// loginHello logs in after a handshake asking for the given version and
+// capabilities
+func (ts *testServer) loginHello(t *testing.T, loginID string, hello Hello) *testClient {
+	ws, _, err := websocket.DefaultDialer.Dial(ts.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	c := &testClient{t: t, ws: ws}
+	if r := c.request("Hello", hello); r.Type != "Hello" {
+		t.Fatalf("got %v %s", r.Type, r.Payload)
+	}
+	c.request("LoginRequest", LoginRequest{LoginID: loginID})
+	return c
+}
+
+// sendRaw sends a request with the given payload, and returns its ID
+func (c *testClient) sendRaw(typ, payload string) int {
+	c.next++
+	msg := fmt.Sprintf(`{"messageType":%q,"requestID":%d,"payload":%v}`, typ, c.next, payload)
+	err := c.ws.WriteMessage(websocket.TextMessage, []byte(msg))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.next
+}
+
+// request sends a request and waits for its response
+func (c *testClient) request(typ string, payload interface{}) response {
+	b, _ := json.Marshal(payload)
+	return c.await(c.sendRaw(typ, string(b)))
+}
+
+type response struct {
+	Type    string          `json:"messageType"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// await skips pushed messages until the response to requestID
+func (c *testClient) await(requestID int) response {
+	c.ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, b, err := c.ws.ReadMessage()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+
+		var msg struct {
+			response
+			RequestID int `json:"requestID"`
+		}
+		err = json.Unmarshal(b, &msg)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		if msg.RequestID == requestID {
+			return msg.response
+		}
+	}
+}
+
+// awaitPush skips messages until one of the given types is pushed
+func (c *testClient) awaitPush(types ...string) response {
+	c.ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, b, err := c.ws.ReadMessage()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+
+		var msg response
+		err = json.Unmarshal(b, &msg)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		for _, typ := range types {
+			if msg.Type == typ {
+				return msg
+			}
+		}
+	}
+}
+
+// errorCode returns the code of an ErrorMessage response, or "" for any
+// other response
+func (r response) errorCode() ErrorCode {
+	if r.Type != "ErrorMessage" {
+		return ""
+	}
+	var msg ErrorMessage
+	json.Unmarshal(r.Payload, &msg)
+	return msg.Code
+}
+
+func TestSpectatorsCantPlay(t *testing.T) {
+	ts := startServer(t)
+	alice, _ := ts.games.CreatePlayer("alice", "alice")
+	bob, _ := ts.games.CreatePlayer("bob", "bob")
+	ts.games.CreatePlayer("carol", "carol")
+	gameID, err := ts.games.NewGame(alice.UUID, bob.UUID, store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carol := ts.login(t, "carol")
+	if r := carol.request("Spectate", Spectate{GameID: gameID}); r.Type != "GameState" {
+		t.Fatalf("got %v spectating", r.Type)
+	}
+
+	// as either player, or as themselves
+	for _, playerID := range []string{alice.UUID, bob.UUID, ""} {
+		r := carol.request("PlayMove", playMoveV1{GameID: gameID, Move: game.Move{
+			PlayerID:   playerID,
+			Coordinate: game.NewCoordinate(2, 2, 2, 2),
+		}})
+		if r.errorCode() != CodeGameNotFound {
+			t.Errorf("a spectator playing as %q got %v %s", playerID, r.Type, r.Payload)
+		}
+	}
+	if r := carol.request("Rematch", Rematch{GameID: gameID}); r.errorCode() != CodeGameNotFound {
+		t.Errorf("a spectator asking for a rematch got %v %s", r.Type, r.Payload)
+	}
+
+	// moves sent as the other player are played as the sender
+	alicesSocket := ts.login(t, "alice")
+	r := alicesSocket.request("PlayMove", playMoveV1{GameID: gameID, Move: game.Move{
+		PlayerID:   bob.UUID,
+		Coordinate: game.NewCoordinate(2, 2, 2, 2),
+	}})
+	if r.Type != "Ack" {
+		t.Fatalf("got %v %s", r.Type, r.Payload)
+	}
+	state, err := ts.games.GameState(gameID, alice.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner := state.Grids[1][1].Squares[1][1].Owner; owner == nil || *owner != alice.UUID {
+		t.Errorf("the move was played as %v", owner)
+	}
+}
+
+func TestIllegalMoves(t *testing.T) {
+	ts := startServer(t)
+	alice, _ := ts.games.CreatePlayer("alice", "alice")
+	bob, _ := ts.games.CreatePlayer("bob", "bob")
+	gameID, err := ts.games.NewGame(alice.UUID, bob.UUID, store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alicesSocket := ts.loginHello(t, "alice", Hello{ProtocolVersion: 2})
+	bobsSocket := ts.loginHello(t, "bob", Hello{ProtocolVersion: 2})
+	play := func(c *testClient, coord game.Coordinate) response {
+		return c.request("PlayMove", PlayMove{GameID: gameID, Coordinate: coord})
+	}
+	illegal := func(c *testClient, coord game.Coordinate, code ErrorCode) {
+		t.Helper()
+		r := play(c, coord)
+		var msg ErrorMessage
+		json.Unmarshal(r.Payload, &msg)
+		if r.Type != "ErrorMessage" || msg.Code != code || !msg.Recoverable {
+			t.Errorf("got %v %s, want a recoverable %v", r.Type, r.Payload, code)
+		}
+	}
+
+	// each illegal move is answered, and the socket stays open for the
+	// next one
+	illegal(bobsSocket, game.NewCoordinate(2, 2, 2, 2), CodeWrongTurn)
+	if r := play(alicesSocket, game.NewCoordinate(2, 2, 2, 2)); r.Type != "Ack" {
+		t.Fatalf("got %v %s", r.Type, r.Payload)
+	}
+	illegal(alicesSocket, game.NewCoordinate(2, 2, 1, 1), CodeWrongTurn)
+	illegal(bobsSocket, game.NewCoordinate(2, 2, 2, 2), CodeSquarePlayed)
+	illegal(bobsSocket, game.NewCoordinate(1, 1, 1, 1), CodeWrongSubgrid)
+	if r := play(bobsSocket, game.NewCoordinate(2, 2, 1, 1)); r.Type != "Ack" {
+		t.Errorf("got %v %s after illegal moves", r.Type, r.Payload)
+	}
+}
+
+func TestMalformedPayload(t *testing.T) {
+	ts := startServer(t)
+	c := ts.login(t, "alice")
+
+	// the envelope is fine, so the error answers the request
+	requestID := c.sendRaw("PlayMove", `{"gameID": 5}`)
+	if r := c.await(requestID); r.errorCode() != CodeMalformedRequest {
+		t.Errorf("got %v %s", r.Type, r.Payload)
+	}
+}
+
+func TestProtocolVersions(t *testing.T) {
+	ts := startServer(t)
+	alice, _ := ts.games.CreatePlayer("alice", "alice")
+	bob, _ := ts.games.CreatePlayer("bob", "bob")
+	gameID, err := ts.games.NewGame(alice.UUID, bob.UUID, store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := ts.login(t, "bob")
+	deltas := ts.loginHello(t, "bob", Hello{ProtocolVersion: 2, Capabilities: []string{CapabilityGameDeltas}})
+	// both are sent the game as it stands first
+	legacy.awaitPush("GameState")
+	deltas.awaitPush("GameState")
+
+	// alice speaks version 2, and sends the move as a coordinate
+	alicesSocket := ts.loginHello(t, "alice", Hello{ProtocolVersion: 2})
+	r := alicesSocket.request("PlayMove", PlayMove{GameID: gameID, Coordinate: game.NewCoordinate(2, 2, 2, 2)})
+	if r.Type != "Ack" {
+		t.Fatalf("got %v %s", r.Type, r.Payload)
+	}
+
+	// a client that never sent a Hello only understands full states
+	if r := legacy.awaitPush("GameState", "GameDelta"); r.Type != "GameState" {
+		t.Errorf("version 1 was pushed a %v", r.Type)
+	}
+	if r := deltas.awaitPush("GameState", "GameDelta"); r.Type != "GameDelta" {
+		t.Errorf("version 2 with gameDeltas was pushed a %v", r.Type)
+	}
+
+	// and sends moves in version 1's form
+	r = legacy.request("PlayMove", playMoveV1{GameID: gameID, Move: game.Move{Coordinate: game.NewCoordinate(2, 2, 1, 1)}})
+	if r.Type != "Ack" {
+		t.Fatalf("got %v %s", r.Type, r.Payload)
+	}
+	state, err := ts.games.GameState(gameID, bob.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner := state.Grids[1][1].Squares[0][0].Owner; owner == nil || *owner != bob.UUID {
+		t.Errorf("the version 1 move was played as %v", owner)
+	}
+}
+
+func TestAnalyzeDuringRatedGame(t *testing.T) {
+	ts := startServerConfig(t, &config.Config{WriteTimeout: time.Second, AnalysisMoveTime: 10 * time.Millisecond})
+	ids := []string{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		p, _ := ts.games.CreatePlayer(name, name)
+		ids = append(ids, p.UUID)
+	}
+
+	alice := ts.login(t, "alice")
+	carol := ts.login(t, "carol")
+	analyze := Analyze{Position: "9/9/9/9/4X4/9/9/9/9 B2b2"}
+
+	// the game is refused as soon as it exists, whether or not this
+	// session has been told about it yet
+	rated, err := ts.games.NewGame(ids[0], ids[1], store.ColorX, store.GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := alice.request("Analyze", analyze); r.errorCode() != CodeAnalysisDisabled {
+		t.Errorf("got %v %s during a rated game", r.Type, r.Payload)
+	}
+	if r := alice.request("Analyze", Analyze{GameID: rated}); r.errorCode() != CodeAnalysisDisabled {
+		t.Errorf("got %v %s analysing the rated game", r.Type, r.Payload)
+	}
+
+	// casual games, and rated games only being watched, don't stop it
+	_, err = ts.games.NewGame(ids[2], ids[3], store.ColorX, store.GameSettings{Casual: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := carol.request("Spectate", Spectate{GameID: rated}); r.Type != "GameState" {
+		t.Fatalf("got %v %s spectating", r.Type, r.Payload)
+	}
+	if r := carol.request("Analyze", analyze); r.Type != "Analyze" {
+		t.Errorf("got %v %s during a casual game", r.Type, r.Payload)
+	}
+}
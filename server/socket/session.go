@@ -0,0 +1,133 @@
+package socket
+
+import (
+	"errors"
+	"time"
+)
+
+// replayBufferSize is how many sent messages each session keeps, so
+// that a client that reconnects can be sent the ones it missed
+const replayBufferSize = 256
+
+// sessionResumeTimeout is how long a session outlives its socket,
+// waiting for the client to reconnect
+const sessionResumeTimeout = 2 * time.Minute
+
+var errSessionNotFound = errors.New("session not found")
+
+// resumeRequest hands a reconnected socket to a session's message loop
+type resumeRequest struct {
+	socket *websocketTransport
+	// requestID is the ID of the LoginRequest
+	requestID int
+	// lastSequence is the sequence number of the last message the
+	// client received
+	lastSequence uint64
+	// protocol and capabilities are what the new socket's handshake
+	// agreed on, which must match the session's
+	protocol     protocol
+	capabilities map[string]bool
+}
+
+// record gives a message the session's next sequence number and keeps
+// it in the replay buffer
+func (conn *clientConn) record(msg OutgoingSocketMessage) OutgoingSocketMessage {
+	conn.sequence++
+	msg.Sequence = conn.sequence
+
+	conn.replay = append(conn.replay, msg)
+	if len(conn.replay) > replayBufferSize {
+		conn.replay = append(conn.replay[:0], conn.replay[1:]...)
+	}
+
+	return msg
+}
+
+// resumeSession passes a socket that logged in with a session token
+// to that session's message loop. login is the socket's unfinished
+// session, which holds the outcome of its handshake
+func (s *Server) resumeSession(login *clientConn, requestID int, request *LoginRequest) error {
+	s.sessionMutex.Lock()
+	conn := s.resumable[request.SessionToken]
+	s.sessionMutex.Unlock()
+
+	if conn != nil {
+		select {
+		case conn.attachCh <- resumeRequest{
+			socket:       login.transport.(*websocketTransport),
+			requestID:    requestID,
+			lastSequence: request.LastSequence,
+			protocol:     login.protocol,
+			capabilities: login.capabilities,
+		}:
+			return nil
+		case <-conn.done:
+			break
+		}
+	}
+
+	login.respondError(requestID, ErrorMessage{
+		Code:    CodeSessionExpired,
+		Message: "session expired",
+	})
+	return errSessionNotFound
+}
+
+// resume attaches a reconnected socket to the session and sends it
+// every message after the last one the client saw. It fails if some
+// of those messages have already left the replay buffer, in which case
+// the client has to log in again. It also fails if the client's
+// handshake differs from the session's, since the messages to replay
+// were written for the session's
+func (s *Server) resume(conn *clientConn, req resumeRequest) bool {
+	oldest := conn.sequence + 1 - uint64(len(conn.replay))
+	if (conn.transport != nil && !conn.transport.readsRequests()) ||
+		req.lastSequence > conn.sequence || req.lastSequence+1 < oldest ||
+		req.protocol.version != conn.protocol.version ||
+		!sameCapabilities(req.capabilities, conn.capabilities) {
+		(&clientConn{transport: req.socket}).respondError(req.requestID, ErrorMessage{
+			Code:    CodeSessionNotResumable,
+			Message: "session can't be resumed",
+		})
+		return false
+	}
+
+	if conn.transport != nil {
+		// the client gave up on the old socket before we noticed
+		// it was gone
+		conn.transport.close()
+	}
+	conn.transport = req.socket
+	conn.lastMessage = time.Now()
+
+	err := conn.sendUnsequenced(req.requestID, LoginSuccess{
+		Username:     conn.username,
+		PlayerID:     conn.playerID,
+		SessionToken: conn.token,
+		Resumed:      true,
+	})
+	for _, msg := range conn.replay {
+		if err != nil {
+			break
+		}
+		if msg.Sequence > req.lastSequence {
+			err = conn.transport.write(msg)
+		}
+	}
+
+	s.setConnected(conn, true)
+	return true
+}
+
+// SessionPlayer returns the player logged in to the session with the
+// given token, so that other transports can accept session tokens
+func (s *Server) SessionPlayer(token string) (string, bool) {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+
+	conn := s.resumable[token]
+	if conn == nil {
+		return "", false
+	}
+	return conn.playerID, true
+}
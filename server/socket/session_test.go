@@ -0,0 +1,238 @@
+package socket
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// rawClient speaks to the server in either codec, and keeps the
+// envelope of every message it reads
+type rawClient struct {
+	t     *testing.T
+	ws    *websocket.Conn
+	codec codec
+	next  int
+}
+
+// envelopeIn is an OutgoingSocketMessage as read by a client
+type envelopeIn struct {
+	Type      string
+	RequestID int
+	Sequence  uint64
+	Payload   map[string]interface{}
+}
+
+func (ts *testServer) dial(t *testing.T, subprotocol string) *rawClient {
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}}
+	ws, _, err := dialer.Dial(ts.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	return &rawClient{t: t, ws: ws, codec: codecFor(ws)}
+}
+
+// send sends a request and returns its ID
+func (c *rawClient) send(typ string, payload interface{}) int {
+	c.next++
+	b := &bytes.Buffer{}
+	err := c.codec.encode(b, map[string]interface{}{
+		"messageType": typ,
+		"requestID":   c.next,
+		"payload":     payload,
+	})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	err = c.ws.WriteMessage(c.codec.frameType(), b.Bytes())
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.next
+}
+
+func (c *rawClient) read() envelopeIn {
+	c.ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, b, err := c.ws.ReadMessage()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	var msg map[string]interface{}
+	if _, ok := c.codec.(msgpackCodec); ok {
+		err = msgpack.Unmarshal(b, &msg)
+	} else {
+		err = json.Unmarshal(b, &msg)
+	}
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	e := envelopeIn{
+		Type:      msg["messageType"].(string),
+		RequestID: int(number(msg["requestID"])),
+		Sequence:  uint64(number(msg["sequence"])),
+	}
+	e.Payload, _ = msg["payload"].(map[string]interface{})
+	return e
+}
+
+// number reads a number decoded by either codec
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int8:
+		return float64(n)
+	case int16:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint8:
+		return float64(n)
+	case uint16:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
+	return 0
+}
+
+// await reads messages until the response to requestID
+func (c *rawClient) await(requestID int) envelopeIn {
+	for {
+		if msg := c.read(); msg.RequestID == requestID {
+			return msg
+		}
+	}
+}
+
+// startSession logs in and fills the replay buffer past its size. It
+// returns the session token and the last sequence number sent, once
+// the socket has dropped
+func startSession(t *testing.T, ts *testServer) (string, uint64) {
+	c := ts.dial(t, SubprotocolJSON)
+	login := c.await(c.send("LoginRequest", LoginRequest{LoginID: "alice"}))
+	token, _ := login.Payload["sessionToken"].(string)
+	if login.Type != "LoginSuccess" || token == "" {
+		t.Fatalf("got %+v", login)
+	}
+
+	var last uint64
+	for i := 0; i < replayBufferSize+20; i++ {
+		msg := c.await(c.send("LeaveQueue", LeaveQueue{}))
+		if msg.Type != "Ack" || msg.Sequence <= last {
+			t.Fatalf("got %+v after sequence %v", msg, last)
+		}
+		last = msg.Sequence
+	}
+
+	c.ws.Close()
+	return token, last
+}
+
+// resume reconnects to a session, and returns the response to the
+// LoginRequest
+func (c *rawClient) resume(token string, lastSequence uint64) envelopeIn {
+	return c.await(c.send("LoginRequest", LoginRequest{SessionToken: token, LastSequence: lastSequence}))
+}
+
+// checkReplay reads the messages replayed after lastSequence, which
+// must be exactly those up to last, and checks that the session goes
+// on from there
+func (c *rawClient) checkReplay(lastSequence, last uint64) {
+	for seq := lastSequence + 1; seq <= last; seq++ {
+		msg := c.read()
+		if msg.Sequence != seq || msg.Type != "Ack" {
+			c.t.Fatalf("got %+v, want sequence %v", msg, seq)
+		}
+	}
+
+	msg := c.await(c.send("LeaveQueue", LeaveQueue{}))
+	if msg.Sequence != last+1 {
+		c.t.Errorf("session went on at sequence %v, want %v", msg.Sequence, last+1)
+	}
+}
+
+func TestResumeBufferEdge(t *testing.T) {
+	ts := startServer(t)
+	token, last := startSession(t, ts)
+
+	// the oldest message kept is the first one missed
+	c := ts.dial(t, SubprotocolJSON)
+	oldest := last - replayBufferSize
+	if r := c.resume(token, oldest); r.Type != "LoginSuccess" || r.Payload["resumed"] != true {
+		t.Fatalf("got %+v", r)
+	}
+	c.checkReplay(oldest, last)
+}
+
+func TestResumeTooOld(t *testing.T) {
+	ts := startServer(t)
+	token, last := startSession(t, ts)
+
+	// one message too many was missed, so the client has to log in
+	// again and be sent everything
+	c := ts.dial(t, SubprotocolJSON)
+	r := c.resume(token, last-replayBufferSize-1)
+	if r.Type != "ErrorMessage" || r.Payload["code"] != string(CodeSessionNotResumable) {
+		t.Fatalf("got %+v", r)
+	}
+
+	c = ts.dial(t, SubprotocolJSON)
+	if r := c.await(c.send("LoginRequest", LoginRequest{LoginID: "alice"})); r.Type != "LoginSuccess" || r.Payload["resumed"] == true {
+		t.Errorf("got %+v logging in again", r)
+	}
+}
+
+func TestResumeAhead(t *testing.T) {
+	ts := startServer(t)
+	token, last := startSession(t, ts)
+
+	c := ts.dial(t, SubprotocolJSON)
+	r := c.resume(token, last+1)
+	if r.Type != "ErrorMessage" || r.Payload["code"] != string(CodeSessionNotResumable) {
+		t.Fatalf("got %+v", r)
+	}
+
+	// the session is still there to be resumed properly
+	c = ts.dial(t, SubprotocolJSON)
+	if r := c.resume(token, last); r.Type != "LoginSuccess" {
+		t.Fatalf("got %+v", r)
+	}
+	c.checkReplay(last, last)
+}
+
+func TestResumeHandshake(t *testing.T) {
+	ts := startServer(t)
+	token, last := startSession(t, ts)
+
+	// the messages were written for version 1, so a client speaking
+	// version 2 can't be sent them
+	c := ts.dial(t, SubprotocolJSON)
+	if r := c.await(c.send("Hello", Hello{ProtocolVersion: 2})); r.Type != "Hello" {
+		t.Fatalf("got %+v", r)
+	}
+	r := c.resume(token, last-1)
+	if r.Type != "ErrorMessage" || r.Payload["code"] != string(CodeSessionNotResumable) {
+		t.Fatalf("got %+v", r)
+	}
+
+	// the codec only changes how they are written
+	c = ts.dial(t, SubprotocolMsgpack)
+	if r := c.resume(token, last-1); r.Type != "LoginSuccess" {
+		t.Fatalf("got %+v", r)
+	}
+	c.checkReplay(last-1, last)
+}
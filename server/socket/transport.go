@@ -0,0 +1,145 @@
+package socket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/heartles/uttt/server/config"
+)
+
+// transport carries a session's messages to its client, and the
+// client's requests back. The message loop only deals with sessions,
+// so it runs the same over websockets and event streams
+type transport interface {
+	// listen returns the client's requests. The channel is closed once
+	// the client disconnects or the transport is closed, and after a
+	// request that couldn't be read
+	listen(p protocol, done <-chan struct{}) <-chan request
+	// readsRequests is whether the client sends its requests over the
+	// transport. Sessions whose clients send them some other way, such
+	// as the HTTP API, are never idle, and end when the client
+	// disconnects instead of waiting for it to resume
+	readsRequests() bool
+	// write sends a message to the client
+	write(msg OutgoingSocketMessage) error
+	// ping lets the client know the session is still there. A failed
+	// ping is left for listen to notice
+	ping()
+	close()
+}
+
+// websocketTransport sends messages over a websocket, encoded with the
+// codec negotiated for it
+type websocketTransport struct {
+	socket *websocket.Conn
+	codec  codec
+	// writeTimeout bounds every write to the socket, and reads fail
+	// once nothing has been heard from the client for pongTimeout
+	writeTimeout time.Duration
+	pongTimeout  time.Duration
+}
+
+func newWebsocketTransport(socket *websocket.Conn, c *config.Config) *websocketTransport {
+	t := &websocketTransport{
+		socket:       socket,
+		codec:        codecFor(socket),
+		writeTimeout: c.WriteTimeout,
+		pongTimeout:  c.PongTimeout,
+	}
+	t.setDeadlines()
+	return t
+}
+
+// setDeadlines makes reads from the socket fail once nothing has been
+// heard from the client for the pong timeout. Pongs and messages both
+// push the deadline back
+func (t *websocketTransport) setDeadlines() {
+	if t.pongTimeout <= 0 {
+		return
+	}
+
+	t.extendReadDeadline()
+	t.socket.SetPongHandler(func(string) error {
+		t.extendReadDeadline()
+		return nil
+	})
+}
+
+func (t *websocketTransport) extendReadDeadline() {
+	if t.pongTimeout > 0 {
+		t.socket.SetReadDeadline(time.Now().Add(t.pongTimeout))
+	}
+}
+
+// next reads the next request from the socket
+func (t *websocketTransport) next(p protocol) (request, error) {
+	_, reader, err := t.socket.NextReader()
+	if err != nil {
+		return request{}, err
+	}
+
+	return parseMessage(t.codec, p, reader)
+}
+
+// listen reads requests from the socket until it closes, or until a
+// request can't be read
+func (t *websocketTransport) listen(p protocol, done <-chan struct{}) <-chan request {
+	ch := make(chan request)
+	go func() {
+		defer recover() // TODO: Log errored socket
+		defer close(ch)
+		for {
+			req, err := t.next(p)
+			if err == errMalformedRequest {
+				req.err = err
+			} else if err != nil {
+				break // TODO: Log errored socket
+			}
+			t.extendReadDeadline()
+
+			select {
+			case ch <- req:
+			case <-done:
+				return
+			}
+			if req.err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (t *websocketTransport) readsRequests() bool {
+	return true
+}
+
+func (t *websocketTransport) write(msg OutgoingSocketMessage) error {
+	if t.writeTimeout > 0 {
+		t.socket.SetWriteDeadline(time.Now().Add(t.writeTimeout))
+	}
+
+	w, err := t.socket.NextWriter(t.codec.frameType())
+	if err != nil {
+		return err
+	}
+
+	err = t.codec.encode(w, msg)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (t *websocketTransport) ping() {
+	deadline := time.Now().Add(t.writeTimeout)
+	if t.writeTimeout <= 0 {
+		deadline = time.Time{}
+	}
+	t.socket.WriteControl(websocket.PingMessage, nil, deadline)
+}
+
+func (t *websocketTransport) close() {
+	t.socket.Close()
+}
@@ -0,0 +1,53 @@
+package store
+
+import "errors"
+
+// ErrAnalysisDisabled is returned when engine analysis is asked for
+// during a rated game
+var ErrAnalysisDisabled = errors.New("analysis is disabled for rated games in progress")
+
+// Analysable reports whether the engine may analyse the game: casual
+// games always, and rated games once they have finished
+func (g *Game) Analysable() bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.analysable()
+}
+
+func (g *Game) analysable() bool {
+	return g.settings.Casual || g.underlying.IsCompleted()
+}
+
+// PlayingRated reports whether a player has a rated game in progress
+func (s *Store) PlayingRated(playerID string) (bool, error) {
+	var playing bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM matches
+			WHERE (UserX = ? OR UserO = ?) AND NOT Finished AND NOT Casual
+		);
+	`, playerID, playerID).Scan(&playing)
+	return playing, err
+}
+
+// AnalysisPosition returns the position of a game for playerID, who must
+// be allowed to look at it, to analyse
+func (s *GameService) AnalysisPosition(gameID, playerID string) (string, error) {
+	err := s.Store.checkViewer(gameID, playerID)
+	if err != nil {
+		return "", err
+	}
+
+	var position string
+	err = s.withGame(gameID, func(g *Game) error {
+		g.mutex.RLock()
+		defer g.mutex.RUnlock()
+
+		if !g.analysable() {
+			return ErrAnalysisDisabled
+		}
+		position = g.underlying.Position()
+		return nil
+	})
+	return position, err
+}
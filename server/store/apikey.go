@@ -0,0 +1,65 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const initAPIKeys = `
+CREATE TABLE IF NOT EXISTS "api_keys"
+(
+	[KeyHash] TEXT PRIMARY KEY,
+	[UserID] TEXT NOT NULL,
+	[Created] INTEGER NOT NULL,
+	FOREIGN KEY (UserID) REFERENCES "users" (PK_UUID)
+);
+`
+
+// ErrInvalidAPIKey is returned for an API key that was never issued or
+// has been revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// hashAPIKey is how API keys are stored, so that a leaked database
+// doesn't leak usable keys
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey issues a new API key for a player. The key itself is
+// only ever returned here
+func (s *Store) CreateAPIKey(playerID string) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(b)
+
+	_, err = s.db.Exec(`
+		INSERT INTO api_keys (KeyHash, UserID, Created) VALUES (?, ?, ?);
+	`, hashAPIKey(key), playerID, time.Now().Unix())
+	return key, err
+}
+
+// RevokeAPIKeys revokes every API key issued to a player
+func (s *Store) RevokeAPIKeys(playerID string) error {
+	_, err := s.db.Exec(`DELETE FROM api_keys WHERE UserID = ?;`, playerID)
+	return err
+}
+
+// APIKeyPlayer returns the player an API key was issued to
+func (s *Store) APIKeyPlayer(key string) (string, error) {
+	row := s.db.QueryRow(`SELECT UserID FROM api_keys WHERE KeyHash = ?;`, hashAPIKey(key))
+
+	var playerID string
+	err := row.Scan(&playerID)
+	if err == sql.ErrNoRows {
+		return "", ErrInvalidAPIKey
+	}
+	return playerID, err
+}
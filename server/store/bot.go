@@ -0,0 +1,73 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/heartles/uttt/server/game"
+)
+
+// ErrUsernameTaken is returned by CreateBot when the username belongs
+// to a person
+var ErrUsernameTaken = errors.New("username taken")
+
+// botLoginPrefix starts the login IDs of bot accounts, which nobody
+// logs in with
+const botLoginPrefix = "bot:"
+
+// CreateBot returns the bot account with the given username, creating
+// it if it doesn't exist yet
+func (s *Store) CreateBot(username string) (*Player, error) {
+	player, err := s.TryLookupPlayerUsername(username)
+	if err != nil {
+		return nil, err
+	} else if player != nil {
+		bot, err := s.IsBot(player.UUID)
+		if err != nil {
+			return nil, err
+		} else if !bot {
+			return nil, ErrUsernameTaken
+		}
+		return player, nil
+	}
+
+	player = &Player{
+		UUID:     uuid.New().String(),
+		Username: username,
+		GoogleID: botLoginPrefix + username,
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO users (PK_UUID, Username, GoogleID, Bot)
+		VALUES (?, ?, ?, 1);
+	`, player.UUID, player.Username, player.GoogleID)
+	if err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// IsBot reports whether a player is a bot account
+func (s *Store) IsBot(playerID string) (bool, error) {
+	var bot bool
+	err := s.db.QueryRow(`SELECT Bot FROM users WHERE PK_UUID = ?;`, playerID).Scan(&bot)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return bot, err
+}
+
+// Position returns the game's position in position notation
+func (g *Game) Position() string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.underlying.Position()
+}
+
+// ValidMoves returns the moves a player may play now. There are none
+// unless it is their turn
+func (g *Game) ValidMoves(playerID string) []game.Move {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.underlying.GetValidMoves(playerID)
+}
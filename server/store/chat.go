@@ -0,0 +1,355 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const initChat = `
+CREATE TABLE IF NOT EXISTS "chat_messages"
+(
+	[Room] TEXT NOT NULL,
+	[GameID] TEXT NOT NULL,
+	[SenderID] TEXT NOT NULL,
+	[Body] TEXT NOT NULL,
+	[Sent] INTEGER NOT NULL,
+	FOREIGN KEY (SenderID) REFERENCES "users" (PK_UUID)
+);
+
+CREATE INDEX IF NOT EXISTS chat_messages_room ON chat_messages (Room, GameID);
+`
+
+// Chat rooms. Game and spectator rooms are per game; there is only
+// one lobby
+const (
+	// LobbyRoom is open to everyone
+	LobbyRoom = "lobby"
+	// GameRoom is shared by the two players of a game
+	GameRoom = "game"
+	// SpectatorRoom is shared by everyone watching a game, and can't
+	// be read by its players
+	SpectatorRoom = "spectators"
+)
+
+// MaxChatLength is the longest chat message, in characters
+const MaxChatLength = 500
+
+// ChatHistoryLimit is how many messages are kept for each room, and
+// sent to users who join it
+const ChatHistoryLimit = 50
+
+// chatRateLimit messages may be sent by a player in any chatRateWindow
+const (
+	chatRateLimit  = 5
+	chatRateWindow = 10 * time.Second
+)
+
+// ErrInvalidChatRoom is returned for an unknown room, or a game room
+// without a game
+var ErrInvalidChatRoom = errors.New("invalid chat room")
+
+// ErrChatNotAllowed is returned when a player tries to use a game's
+// chat room they don't belong in, or isn't a known player
+var ErrChatNotAllowed = errors.New("not allowed in chat room")
+
+// ErrInvalidChatMessage is returned for empty or overlong messages
+var ErrInvalidChatMessage = errors.New("chat message is empty or too long")
+
+// ErrChatRateLimited is returned when a player sends too many messages
+// in a short time
+var ErrChatRateLimited = errors.New("sending chat messages too quickly")
+
+// ChatMessage is a message sent to a chat room
+type ChatMessage struct {
+	Room       string    `json:"room"`
+	GameID     string    `json:"gameID,omitempty"`
+	SenderID   string    `json:"senderID"`
+	SenderName string    `json:"senderName"`
+	Body       string    `json:"body"`
+	Sent       time.Time `json:"sent"`
+}
+
+type chatRoom struct {
+	room   string
+	gameID string
+}
+
+// ChatService delivers chat messages to the members of each room and
+// keeps each room's recent history
+type ChatService struct {
+	games *GameService
+	mutex sync.Mutex
+
+	// members maps each room to the channels of the users in it, and
+	// each channel to the player it belongs to
+	members map[chatRoom]map[chan<- ChatMessage]string
+	// recent holds the times of each player's latest messages, for
+	// rate limiting
+	recent map[string][]time.Time
+}
+
+// NewChatService creates a chat service
+func NewChatService(games *GameService) *ChatService {
+	return &ChatService{
+		games:   games,
+		members: map[chatRoom]map[chan<- ChatMessage]string{},
+		recent:  map[string][]time.Time{},
+	}
+}
+
+// checkAccess returns an error unless playerID may read and write the
+// given room
+func (c *ChatService) checkAccess(room chatRoom, playerID string) error {
+	switch room.room {
+	case LobbyRoom:
+		if room.gameID != "" {
+			return ErrInvalidChatRoom
+		}
+		return nil
+	case GameRoom, SpectatorRoom:
+		break
+	default:
+		return ErrInvalidChatRoom
+	}
+
+	playerX, playerO, err := c.games.gamePlayers(room.gameID)
+	if err == sql.ErrNoRows {
+		return ErrGameNotFound
+	} else if err != nil {
+		return err
+	}
+	isPlayer := playerID == playerX || playerID == playerO
+
+	if room.room == GameRoom {
+		if !isPlayer {
+			return ErrChatNotAllowed
+		}
+
+		blocked, err := c.games.IsBlocked(playerX, playerO)
+		if err != nil {
+			return err
+		} else if blocked {
+			return ErrBlocked
+		}
+		return nil
+	}
+
+	if isPlayer {
+		return ErrChatNotAllowed
+	}
+	return c.games.checkSpectator(room.gameID, playerID)
+}
+
+// Join adds ch to a room, so that it receives every message sent to
+// the room from now on, and returns the room's recent history. gameID
+// is empty for the lobby. Sends to ch don't block, so it should be
+// buffered; messages that don't fit are dropped. Messages from
+// players blocked by or blocking playerID are left out
+func (c *ChatService) Join(room, gameID, playerID string, ch chan<- ChatMessage) ([]ChatMessage, error) {
+	key := chatRoom{room, gameID}
+	err := c.checkAccess(key, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := c.games.chatHistory(room, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := c.games.blockedBetween(playerID)
+	if err != nil {
+		return nil, err
+	}
+	visible := []ChatMessage{}
+	for _, msg := range history {
+		if !blocked[msg.SenderID] {
+			visible = append(visible, msg)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.members[key] == nil {
+		c.members[key] = map[chan<- ChatMessage]string{}
+	}
+	c.members[key][ch] = playerID
+
+	return visible, nil
+}
+
+// Leave removes ch from a room
+func (c *ChatService) Leave(room, gameID string, ch chan<- ChatMessage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := chatRoom{room, gameID}
+	delete(c.members[key], ch)
+	if len(c.members[key]) == 0 {
+		delete(c.members, key)
+	}
+}
+
+// LeaveAll removes ch from every room it is in
+func (c *ChatService) LeaveAll(ch chan<- ChatMessage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, members := range c.members {
+		delete(members, ch)
+		if len(members) == 0 {
+			delete(c.members, key)
+		}
+	}
+}
+
+// allow records a message from playerID, or returns false if they
+// have already sent chatRateLimit messages within chatRateWindow.
+// The mutex must be held
+func (c *ChatService) allow(playerID string, now time.Time) bool {
+	// players whose latest message is outside the window are forgotten,
+	// so that recent only holds players who are chatting
+	for id, times := range c.recent {
+		if now.Sub(times[len(times)-1]) >= chatRateWindow {
+			delete(c.recent, id)
+		}
+	}
+
+	recent := []time.Time{}
+	for _, t := range c.recent[playerID] {
+		if now.Sub(t) < chatRateWindow {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= chatRateLimit {
+		c.recent[playerID] = recent
+		return false
+	}
+	c.recent[playerID] = append(recent, now)
+	return true
+}
+
+// Send saves a message to a room's history and delivers it to every
+// member of the room, except those the sender has blocked or been
+// blocked by
+func (c *ChatService) Send(room, gameID, playerID, body string) error {
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > MaxChatLength {
+		return ErrInvalidChatMessage
+	}
+
+	key := chatRoom{room, gameID}
+	err := c.checkAccess(key, playerID)
+	if err != nil {
+		return err
+	}
+
+	sender, err := c.games.TryLookupPlayerUUID(playerID)
+	if err != nil {
+		return err
+	} else if sender == nil {
+		return ErrChatNotAllowed
+	}
+
+	blocked, err := c.games.blockedBetween(playerID)
+	if err != nil {
+		return err
+	}
+
+	msg := ChatMessage{
+		Room:       room,
+		GameID:     gameID,
+		SenderID:   playerID,
+		SenderName: sender.Username,
+		Body:       body,
+		Sent:       time.Now(),
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.allow(playerID, msg.Sent) {
+		return ErrChatRateLimited
+	}
+
+	err = c.games.saveChatMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	for ch, member := range c.members[key] {
+		if blocked[member] {
+			continue
+		}
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// saveChatMessage adds a message to its room's history, dropping
+// messages older than the last ChatHistoryLimit
+func (s *Store) saveChatMessage(msg ChatMessage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO chat_messages(Room, GameID, SenderID, Body, Sent)
+		VALUES(?,?,?,?,?);
+	`, msg.Room, msg.GameID, msg.SenderID, msg.Body, msg.Sent.Unix())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM chat_messages
+		WHERE Room = ? AND GameID = ? AND rowid NOT IN (
+			SELECT rowid FROM chat_messages
+			WHERE Room = ? AND GameID = ?
+			ORDER BY rowid DESC LIMIT ?
+		);
+	`, msg.Room, msg.GameID, msg.Room, msg.GameID, ChatHistoryLimit)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// chatHistory returns a room's recent messages, oldest first
+func (s *Store) chatHistory(room, gameID string) ([]ChatMessage, error) {
+	rows, err := s.db.Query(`
+		SELECT c.SenderID, u.Username, c.Body, c.Sent
+		FROM chat_messages c JOIN users u ON u.PK_UUID = c.SenderID
+		WHERE c.Room = ? AND c.GameID = ?
+		ORDER BY c.rowid;
+	`, room, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []ChatMessage{}
+	for rows.Next() {
+		msg := ChatMessage{Room: room, GameID: gameID}
+		var sent int64
+		err = rows.Scan(&msg.SenderID, &msg.SenderName, &msg.Body, &sent)
+		if err != nil {
+			return nil, err
+		}
+		msg.Sent = time.Unix(sent, 0)
+		history = append(history, msg)
+	}
+
+	return history, rows.Err()
+}
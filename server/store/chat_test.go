@@ -0,0 +1,43 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChatRateLimitForgets(t *testing.T) {
+	c := &ChatService{recent: map[string][]time.Time{}}
+	start := time.Now()
+
+	for i := 0; i < chatRateLimit; i++ {
+		if !c.allow("alice", start) {
+			t.Fatalf("message %d was rate limited", i+1)
+		}
+	}
+	if c.allow("alice", start) {
+		t.Error("message over the limit was allowed")
+	}
+
+	// once alice's messages are outside the window, bob's message
+	// forgets her
+	if !c.allow("bob", start.Add(chatRateWindow)) {
+		t.Error("bob was rate limited")
+	}
+	if _, ok := c.recent["alice"]; ok || len(c.recent) != 1 {
+		t.Errorf("still holding %v", c.recent)
+	}
+}
+
+func TestChatUnknownSender(t *testing.T) {
+	games, err := NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewChatService(games)
+
+	err = c.Send(LobbyRoom, "", "nobody", "hello")
+	if err != ErrChatNotAllowed {
+		t.Errorf("got %v, want %v", err, ErrChatNotAllowed)
+	}
+}
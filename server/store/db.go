@@ -2,6 +2,8 @@ package store
 
 import (
 	"database/sql"
+	"fmt"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
@@ -22,7 +24,8 @@ CREATE TABLE IF NOT EXISTS "users"
 (
     [PK_UUID] TEXT UNIQUE PRIMARY KEY,
     [Username] TEXT UNIQUE NOT NULL,
-    [GoogleID] INTEGER UNIQUE NOT NULL
+    [GoogleID] INTEGER UNIQUE NOT NULL,
+    [Bot] BOOLEAN NOT NULL DEFAULT 0
 );
 
 REPLACE INTO users(PK_UUID, Username, GoogleID) VALUES("tie", "tie", "tie");
@@ -41,8 +44,15 @@ CREATE TABLE IF NOT EXISTS "matches"
 	[LastMoveSubgridX] INTEGER,
 	[LastMoveSubgridY] INTEGER,
 	[Finished] BOOLEAN NOT NULL,
+	[Created] INTEGER NOT NULL DEFAULT 0,
+	[RematchOf] CHAR(36),
+	[Variant] TEXT NOT NULL DEFAULT "standard",
+	[TimeControl] TEXT NOT NULL DEFAULT "",
+	[Casual] BOOLEAN NOT NULL DEFAULT 0,
+	[Private] BOOLEAN NOT NULL DEFAULT 0,
 	FOREIGN KEY (UserX) REFERENCES "users" (PK_UUID),
-	FOREIGN KEY (UserO) REFERENCES "users" (PK_UUID)
+	FOREIGN KEY (UserO) REFERENCES "users" (PK_UUID),
+	FOREIGN KEY (RematchOf) REFERENCES "matches" (PK_UUID)
 );
 `
 
@@ -62,7 +72,105 @@ func NewStore(filepath string) (*Store, error) {
 		return nil, err
 	}
 
-	return &Store{db}, nil
+	_, err = db.Exec(initRatings)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(initTournaments)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(initChat)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(initSocial)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(initMoves)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(initAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(initAnnotations)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Store{db}
+
+	// databases created before these columns existed need them added
+	err = st.ensureColumn("matches", "Created", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, err
+	}
+	err = st.ensureColumn("matches", "RematchOf", "CHAR(36)")
+	if err != nil {
+		return nil, err
+	}
+	err = st.ensureColumn("matches", "Variant", `TEXT NOT NULL DEFAULT "standard"`)
+	if err != nil {
+		return nil, err
+	}
+	err = st.ensureColumn("matches", "TimeControl", `TEXT NOT NULL DEFAULT ""`)
+	if err != nil {
+		return nil, err
+	}
+	err = st.ensureColumn("matches", "Casual", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, err
+	}
+	err = st.ensureColumn("matches", "Private", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, err
+	}
+	err = st.ensureColumn("users", "Bot", "BOOLEAN NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// ensureColumn adds a column to an existing table if it is not already
+// present. CREATE TABLE IF NOT EXISTS leaves old tables untouched, so
+// any column added after a table was first released goes through here.
+func (s *Store) ensureColumn(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf(`PRAGMA table_info("%v");`, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt *string
+		err = rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk)
+		if err != nil {
+			return err
+		}
+
+		if name == column {
+			return nil
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(`ALTER TABLE "%v" ADD COLUMN [%v] %v;`, table, column, definition))
+	return err
 }
 
 func (s *Store) TryLookupPlayer(googleID string) (*Player, error) {
@@ -147,9 +255,50 @@ func (s *Store) CreatePlayer(username string, googleID string) (*Player, error)
 	}, nil
 }
 
-func (s *Store) saveNewGame(game *game.Game) (string, error) {
+// saveNewGame inserts a freshly created game. rematchOf links it to
+// the game it is a rematch of, and may be empty
+func (s *Store) saveNewGame(game *game.Game, settings GameSettings, rematchOf string) (string, error) {
 	id := uuid.New().String()
-	return id, s.saveGame(id, game)
+	playerX, playerO, state, _ := game.SaveGame()
+
+	var rematch *string
+	if rematchOf != "" {
+		rematch = &rematchOf
+	}
+
+	// every column is written at once, so that a game is never saved
+	// without its settings
+	_, err := s.db.Exec(`
+		INSERT INTO matches(
+			PK_UUID,
+			GameData,
+			UserX,
+			UserO,
+			Finished,
+			Created,
+			RematchOf,
+			Variant,
+			TimeControl,
+			Casual,
+			Private)
+			VALUES(?,?,?,?,?,?,?,?,?,?,?);
+	`, id, state, playerX, playerO, false, time.Now().Unix(), rematch, settings.Variant,
+		settings.TimeControl, settings.Casual, settings.Private)
+	return id, err
+}
+
+func (s *Store) loadSettings(gameID string) (GameSettings, error) {
+	row := s.db.QueryRow(`SELECT Variant, TimeControl, Casual, Private FROM matches WHERE PK_UUID = ?;`, gameID)
+
+	var settings GameSettings
+	err := row.Scan(&settings.Variant, &settings.TimeControl, &settings.Casual, &settings.Private)
+	return settings, err
+}
+
+func (s *Store) gamePlayers(gameID string) (playerX, playerO string, err error) {
+	row := s.db.QueryRow(`SELECT UserX, UserO FROM matches WHERE PK_UUID = ?;`, gameID)
+	err = row.Scan(&playerX, &playerO)
+	return playerX, playerO, err
 }
 
 func (s *Store) saveGame(gameID string, game *game.Game) error {
@@ -170,8 +319,10 @@ func (s *Store) saveGame(gameID string, game *game.Game) error {
 		victor = &v
 	}
 
+	// upsert rather than REPLACE so that columns not owned by the
+	// game itself (creation time, settings) survive a save
 	_, err := s.db.Exec(`
-		REPLACE INTO matches(
+		INSERT INTO matches(
 			PK_UUID,
 			GameData,
 			UserX,
@@ -182,7 +333,15 @@ func (s *Store) saveGame(gameID string, game *game.Game) error {
 			LastMoveSubgridX,
 			LastMoveSubgridY,
 			Finished)
-			VALUES(?,?,?,?,?,?,?,?,?,?);
+			VALUES(?,?,?,?,?,?,?,?,?,?)
+		ON CONFLICT(PK_UUID) DO UPDATE SET
+			GameData = excluded.GameData,
+			Victor = excluded.Victor,
+			LastMoveGameX = excluded.LastMoveGameX,
+			LastMoveGameY = excluded.LastMoveGameY,
+			LastMoveSubgridX = excluded.LastMoveSubgridX,
+			LastMoveSubgridY = excluded.LastMoveSubgridY,
+			Finished = excluded.Finished;
 		`,
 		gameID, state, playerX, playerO, victor, lastGameX, lastGameY,
 		lastSubX, lastSubY, finished)
@@ -240,3 +399,89 @@ func (s *Store) getGameUUIDS(playerID string) ([]string, error) {
 
 	return uuids, nil
 }
+
+// ActiveOpponents returns everyone the player has an unfinished
+// game against
+func (s *Store) ActiveOpponents(playerID string) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT CASE WHEN UserX = ? THEN UserO ELSE UserX END
+		FROM matches
+		WHERE (UserX = ? OR UserO = ?) AND Finished = 0;
+	`, playerID, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	opponents := []string{}
+	for rows.Next() {
+		opponent := ""
+		if err = rows.Scan(&opponent); err != nil {
+			return nil, err
+		}
+		opponents = append(opponents, opponent)
+	}
+
+	return opponents, rows.Err()
+}
+
+// lastPlayerXBetween returns who played X in the most recent game
+// between the two players, or "" if they have never played
+func (s *Store) lastPlayerXBetween(playerA, playerB string) (string, error) {
+	row := s.db.QueryRow(`
+		SELECT UserX FROM matches
+		WHERE (UserX = ? AND UserO = ?) OR (UserX = ? AND UserO = ?)
+		ORDER BY Created DESC, rowid DESC LIMIT 1;
+	`, playerA, playerB, playerB, playerA)
+
+	var playerX string
+	err := row.Scan(&playerX)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return playerX, err
+}
+
+// rematchLinks returns the game the given game is a rematch of, and
+// the game that was started as its rematch. Either may be ""
+func (s *Store) rematchLinks(gameID string) (rematchOf, rematch string, err error) {
+	row := s.db.QueryRow(`
+		SELECT
+			IFNULL((SELECT RematchOf FROM matches WHERE PK_UUID = ?), ""),
+			IFNULL((SELECT PK_UUID FROM matches WHERE RematchOf = ?), "");
+	`, gameID, gameID)
+
+	err = row.Scan(&rematchOf, &rematch)
+	return
+}
+
+// seriesVictors returns the victor of every finished game that the
+// given game is (transitively) a rematch of. The given game itself is
+// not included
+func (s *Store) seriesVictors(gameID string) ([]string, error) {
+	rows, err := s.db.Query(`
+		WITH RECURSIVE series(id) AS (
+			SELECT RematchOf FROM matches WHERE PK_UUID = ?
+			UNION ALL
+			SELECT m.RematchOf FROM matches m JOIN series ON m.PK_UUID = series.id
+		)
+		SELECT Victor FROM matches
+		WHERE PK_UUID IN series AND Finished AND Victor IS NOT NULL;
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	victors := []string{}
+	for rows.Next() {
+		victor := ""
+		if err = rows.Scan(&victor); err != nil {
+			return nil, err
+		}
+		victors = append(victors, victor)
+	}
+
+	return victors, rows.Err()
+}
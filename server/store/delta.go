@@ -0,0 +1,105 @@
+package store
+
+import "github.com/heartles/uttt/server/game"
+
+// maxDeltas is how many of a game's latest changes are kept, so that
+// listeners that have fallen behind can be caught up with deltas
+const maxDeltas = 64
+
+// GameDelta is a change to a game's state. Applied to the GameState
+// with the previous Version, it gives the state at Version. Changes
+// that can't be described by a delta, such as the end of the game, are
+// only sent as a full GameState
+type GameDelta struct {
+	GameID  string `json:"gameID"`
+	Version int    `json:"version"`
+
+	// Move is the move that was played, if any. Its square is now owned
+	// by the player who played it, and GridOwner is the owner of the
+	// move's subgrid, if it has one
+	Move      *game.Move `json:"move,omitempty"`
+	GridOwner *string    `json:"gridOwner,omitempty"`
+
+	// After a move, Turn is the player to move, and PlayableGrids the
+	// subgrids they may play in. Every empty square in those subgrids
+	// is playable, and no other square is
+	Turn          string               `json:"turn,omitempty"`
+	PlayableGrids []game.SubCoordinate `json:"playableGrids,omitempty"`
+
+	Spectators int `json:"spectators"`
+}
+
+// spectatorDelta describes a change to the number of spectators. The
+// mutex must be held
+func (g *Game) spectatorDelta() *GameDelta {
+	return &GameDelta{Spectators: len(g.spectators)}
+}
+
+// moveDelta describes a move that has just been played. The mutex must
+// be held
+func (g *Game) moveDelta(m game.Move) *GameDelta {
+	if g.underlying.IsCompleted() {
+		// finishing the game also changes ratings and the series score
+		return nil
+	}
+
+	d := g.spectatorDelta()
+	d.Move = &m
+	if owner, _ := g.underlying.BlockWinner(m.GameSquare); owner != "" {
+		d.GridOwner = &owner
+	}
+
+	playerX, playerO, _, _ := g.underlying.SaveGame()
+	d.Turn = playerX
+	if m.PlayerID == playerX {
+		d.Turn = playerO
+	}
+
+	seen := map[game.SubCoordinate]bool{}
+	for _, next := range g.underlying.GetValidMoves(d.Turn) {
+		if !seen[next.GameSquare] {
+			seen[next.GameSquare] = true
+			d.PlayableGrids = append(d.PlayableGrids, next.GameSquare)
+		}
+	}
+	return d
+}
+
+// record numbers a change to the game and keeps its delta, which is
+// nil if the change can only be sent as a full GameState. The write
+// mutex must be held
+func (g *Game) record(delta *GameDelta) {
+	g.version++
+	if delta != nil {
+		delta.GameID = g.uuid
+		delta.Version = g.version
+	}
+
+	g.deltas = append(g.deltas, delta)
+	if len(g.deltas) > maxDeltas {
+		g.deltas = append(g.deltas[:0], g.deltas[1:]...)
+	}
+}
+
+// DeltasSince returns the changes made to the game after version, in
+// order, along with the game's current version. It returns false if
+// they can't all be sent as deltas, in which case the full GameState
+// has to be sent instead
+func (g *Game) DeltasSince(version int) ([]GameDelta, int, bool) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	oldest := g.version - len(g.deltas)
+	if version < oldest || version > g.version {
+		return nil, g.version, false
+	}
+
+	deltas := []GameDelta{}
+	for _, d := range g.deltas[version-oldest:] {
+		if d == nil {
+			return nil, g.version, false
+		}
+		deltas = append(deltas, *d)
+	}
+	return deltas, g.version, true
+}
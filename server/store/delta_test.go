@@ -0,0 +1,105 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// recordSpectatorChanges records n deltas on g
+func recordSpectatorChanges(g *Game, n int) {
+	for i := 0; i < n; i++ {
+		g.record(g.spectatorDelta())
+	}
+}
+
+func TestDeltasSinceRingBoundary(t *testing.T) {
+	g := &Game{uuid: "game"}
+	recordSpectatorChanges(g, maxDeltas+10)
+
+	// the oldest delta kept is the one after this version
+	oldest := g.version - maxDeltas
+	deltas, version, ok := g.DeltasSince(oldest)
+	if !ok || version != maxDeltas+10 || len(deltas) != maxDeltas {
+		t.Fatalf("got %d deltas, version %v, %v", len(deltas), version, ok)
+	}
+	for i, d := range deltas {
+		if d.Version != oldest+i+1 || d.GameID != "game" {
+			t.Errorf("delta %d is %+v", i, d)
+		}
+	}
+
+	// one older and the first change needed is gone
+	if _, _, ok := g.DeltasSince(oldest - 1); ok {
+		t.Error("caught up from before the oldest delta")
+	}
+
+	deltas, _, ok = g.DeltasSince(g.version)
+	if !ok || len(deltas) != 0 {
+		t.Errorf("got %d deltas catching up from the current version, %v", len(deltas), ok)
+	}
+	if _, _, ok := g.DeltasSince(g.version + 1); ok {
+		t.Error("caught up from a version from the future")
+	}
+}
+
+func TestDeltasSinceOldGap(t *testing.T) {
+	g := &Game{uuid: "game"}
+	recordSpectatorChanges(g, 3*maxDeltas)
+
+	if _, version, ok := g.DeltasSince(maxDeltas); ok || version != 3*maxDeltas {
+		t.Errorf("caught up %v versions behind, at version %v", 2*maxDeltas, version)
+	}
+	if _, _, ok := g.DeltasSince(0); ok {
+		t.Error("caught up from the start")
+	}
+}
+
+func TestDeltasSinceSnapshot(t *testing.T) {
+	g := &Game{uuid: "game"}
+	recordSpectatorChanges(g, 2)
+	// a change only a full GameState can describe
+	g.record(nil)
+	recordSpectatorChanges(g, 2)
+
+	for version := 0; version < 3; version++ {
+		if _, _, ok := g.DeltasSince(version); ok {
+			t.Errorf("caught up from version %v across a full state", version)
+		}
+	}
+
+	deltas, version, ok := g.DeltasSince(3)
+	if !ok || version != 5 || len(deltas) != 2 || deltas[0].Version != 4 || deltas[1].Version != 5 {
+		t.Errorf("got %+v, version %v, %v catching up after the full state", deltas, version, ok)
+	}
+}
+
+func TestMoveDeltaWinningSubgrid(t *testing.T) {
+	// X has the top two left squares of A1, and O has just sent them
+	// there
+	underlying, err := game.ParsePosition("x", "o", "XX7/9/9/OO7/9/9/9/9/9 A2a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{underlying: underlying, uuid: "game", spectators: map[<-chan struct{}]bool{}}
+
+	m := game.Move{PlayerID: "x", Coordinate: game.NewCoordinate(1, 1, 3, 1)}
+	err = underlying.PlayMove(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := g.moveDelta(m)
+	if d == nil || d.Move == nil || *d.Move != m {
+		t.Fatalf("got %+v", d)
+	}
+	if d.GridOwner == nil || *d.GridOwner != "x" {
+		t.Errorf("got grid owner %v, want x", d.GridOwner)
+	}
+	// the move's square, c1, sends O to the top right subgrid
+	want := []game.SubCoordinate{{X: 3, Y: 1}}
+	if d.Turn != "o" || !reflect.DeepEqual(d.PlayableGrids, want) {
+		t.Errorf("got turn %q in %v, want o in %v", d.Turn, d.PlayableGrids, want)
+	}
+}
@@ -2,9 +2,12 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
+	"regexp"
 	"sync"
 	"time"
 
@@ -19,6 +22,23 @@ type Game struct {
 	service        *GameService
 	uuid           string
 	listenChannels []chan struct{}
+	settings       GameSettings
+
+	// spectators holds the listen channels of users watching the game
+	// rather than playing in it
+	spectators map[<-chan struct{}]bool
+
+	// rematchRequestedBy is the player who has asked for a rematch
+	// and is waiting on their opponent
+	rematchRequestedBy string
+	// rematchStarting is set while the rematch game is being created
+	// so that it is only created once
+	rematchStarting bool
+
+	// version counts the changes to the game since it was loaded, and
+	// deltas holds the latest of them
+	version int
+	deltas  []*GameDelta
 }
 
 func (g *Game) UUID() string {
@@ -45,7 +65,12 @@ func (g *Game) Close(ch <-chan struct{}) error {
 	}
 
 	close(g.listenChannels[idx])
-	g.listenChannels = append(g.listenChannels[:idx], g.listenChannels[idx:]...)
+	g.listenChannels = append(g.listenChannels[:idx], g.listenChannels[idx+1:]...)
+	if g.spectators[ch] {
+		delete(g.spectators, ch)
+		// everyone else is shown the spectator count
+		g.notifyListeners(g.spectatorDelta())
+	}
 
 	g.mutex.Unlock()
 	return g.service.closeGame(g)
@@ -70,8 +95,54 @@ type GameState struct {
 	PlayerXName string `json:"playerXName"`
 	PlayerOName string `json:"playerOName"`
 
-	Victor *string         `json:"victor"`
-	Grids  [3][3]GridState `json:"grids"`
+	Victor   *string         `json:"victor"`
+	Grids    [3][3]GridState `json:"grids"`
+	Settings GameSettings    `json:"settings"`
+
+	// RematchOf is the game this one is a rematch of
+	RematchOf *string `json:"rematchOf"`
+	// Rematch is the game that was started as a rematch of this one
+	Rematch *string `json:"rematch"`
+	// RematchRequestedBy is the player waiting on their opponent
+	// to agree to a rematch
+	RematchRequestedBy *string `json:"rematchRequestedBy"`
+	// Series tallies this game and every game it is a rematch of
+	Series SeriesScore `json:"series"`
+
+	// PlayerXRating and PlayerORating are the players' ratings in the
+	// pool this game is rated in. They are nil for casual games
+	PlayerXRating *Rating `json:"playerXRating"`
+	PlayerORating *Rating `json:"playerORating"`
+
+	// Spectators is the number of users currently watching the game
+	Spectators int `json:"spectators"`
+
+	// Version is the number of the latest change to the game. Each
+	// GameDelta applies to the state with the version before its own
+	Version int `json:"version"`
+
+	// PlayerXPresence and PlayerOPresence are whether each player is
+	// "online", "away" or "offline". The store doesn't track
+	// connections, so these are filled in by whoever sends the state
+	PlayerXPresence string `json:"playerXPresence,omitempty"`
+	PlayerOPresence string `json:"playerOPresence,omitempty"`
+}
+
+// SeriesScore is the running score of a chain of rematches
+type SeriesScore struct {
+	Games int `json:"games"`
+	Ties  int `json:"ties"`
+	// Wins maps player IDs to the number of games they have won
+	Wins map[string]int `json:"wins"`
+}
+
+func (sc *SeriesScore) add(victor string) {
+	sc.Games++
+	if victor == game.StalematePlayer {
+		sc.Ties++
+	} else {
+		sc.Wins[victor]++
+	}
 }
 
 func (g *Game) GetGameState(playerID string) (*GameState, error) {
@@ -89,6 +160,7 @@ func (g *Game) GetGameState(playerID string) (*GameState, error) {
 		return false
 	}
 
+	var err error
 	playerX, playerO, _, _ := g.underlying.SaveGame()
 	playerXFull, _ := g.service.TryLookupPlayerUUID(playerX)
 	playerOFull, _ := g.service.TryLookupPlayerUUID(playerO)
@@ -98,18 +170,62 @@ func (g *Game) GetGameState(playerID string) (*GameState, error) {
 		PlayerO:     playerO,
 		PlayerXName: playerXFull.Username,
 		PlayerOName: playerOFull.Username,
+		Settings:    g.settings,
+		Spectators:  len(g.spectators),
+		Version:     g.version,
 	}
 
 	victor := g.underlying.GameWinner()
 	if victor != "" {
 		gameState.Victor = &victor
 	}
+
+	if pool := Pool(g.settings); pool != "" {
+		gameState.PlayerXRating, err = g.service.Rating(playerX, pool)
+		if err != nil {
+			return nil, err
+		}
+		gameState.PlayerORating, err = g.service.Rating(playerO, pool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if g.rematchRequestedBy != "" {
+		requestedBy := g.rematchRequestedBy
+		gameState.RematchRequestedBy = &requestedBy
+	}
+
+	rematchOf, rematch, err := g.service.rematchLinks(g.uuid)
+	if err != nil {
+		return nil, err
+	}
+	if rematchOf != "" {
+		gameState.RematchOf = &rematchOf
+	}
+	if rematch != "" {
+		gameState.Rematch = &rematch
+	}
+
+	gameState.Series.Wins = map[string]int{playerX: 0, playerO: 0}
+	if rematchOf != "" {
+		victors, err := g.service.seriesVictors(g.uuid)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range victors {
+			gameState.Series.add(v)
+		}
+	}
+	if victor != "" {
+		gameState.Series.add(victor)
+	}
 	// GameCoordinate = {{z, w} {x, y}}
 	for z := 0; z <= 2; z++ {
 		for w := 0; w <= 2; w++ {
 			grid := &gameState.Grids[w][z]
 
-			owner, _ := g.underlying.BlockWinner(game.SubCoordinate{z, w})
+			owner, _ := g.underlying.BlockWinner(game.SubCoordinate{X: z + 1, Y: w + 1})
 
 			if owner != "" {
 				grid.Owner = &owner
@@ -138,36 +254,98 @@ func (g *Game) PlayMove(m game.Move) error {
 	defer g.mutex.Unlock()
 
 	err := g.underlying.PlayMove(m)
+	if err != nil {
+		return err
+	}
+
+	recordErr := g.service.recordMove(g.uuid, m)
+	if recordErr != nil {
+		fmt.Println(recordErr)
+	}
+
+	if g.underlying.IsCompleted() {
+		// a finished game can't be played further, so this only
+		// happens once per game
+		finishErr := g.service.finishGame(g)
+		if finishErr != nil {
+			fmt.Println(finishErr)
+		}
+	}
+	g.notifyListeners(g.moveDelta(m))
+	return nil
+}
+
+// The write mutex must be held during this call. delta describes the
+// change, or is nil if it can only be sent as a full GameState.
+// Listeners only need to know that something changed, so a listener
+// that hasn't picked up its last notification yet isn't sent another
+func (g *Game) notifyListeners(delta *GameDelta) {
+	g.record(delta)
 	for _, ch := range g.listenChannels {
-		go func(ch chan struct{}) {
-			g.mutex.RLock()
-			defer g.mutex.RUnlock()
-			ch <- struct{}{}
-		}(ch)
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
 	}
-	return err
 }
 
 // The write mutex must be held during this call
 func (g *Game) listenForUpdates() <-chan struct{} {
-	ch := make(chan struct{})
+	ch := make(chan struct{}, 1)
 	g.listenChannels = append(g.listenChannels, ch)
 
 	return ch
 }
 
 type GameService struct {
-	games   map[string]*loadedGame
-	players map[string]chan NewGameNotification
-	mutex   sync.Mutex
+	games        map[string]*loadedGame
+	players      map[string][]newGameListener
+	mutex        sync.Mutex
+	leaderboards *leaderboardCache
+
+	// finishListeners has its own lock since it is read while a game's
+	// mutex is held, and that can't wait on the service mutex
+	finishListeners []func(GameResult)
+	finishMutex     sync.RWMutex
+
 	*Store
 }
 
+// GameResult describes a game that has just finished
+type GameResult struct {
+	GameID   string
+	PlayerX  string
+	PlayerO  string
+	Settings GameSettings
+
+	// Victor is the winning player, or game.StalematePlayer for a tie
+	Victor string
+}
+
 type NewGameNotification struct {
 	Game     *Game
 	UpdateCh <-chan struct{}
 }
 
+// newGameListener is one of a player's sessions waiting to hear about
+// new games. closed is closed once the session stops listening
+type newGameListener struct {
+	ch     chan NewGameNotification
+	closed chan struct{}
+}
+
+// notify sends a new game to the listener without blocking the caller.
+// If the listener closes first, the game's listener is closed instead
+func (l newGameListener) notify(notif NewGameNotification) {
+	go func() {
+		select {
+		case l.ch <- notif:
+		case <-l.closed:
+			notif.Game.Close(notif.UpdateCh)
+		}
+	}()
+}
+
 func NewGameService(dbFilename string) (*GameService, error) {
 	st, err := NewStore(dbFilename)
 	if err != nil {
@@ -176,8 +354,11 @@ func NewGameService(dbFilename string) (*GameService, error) {
 
 	return &GameService{
 		map[string]*loadedGame{},
-		map[string]chan NewGameNotification{},
+		map[string][]newGameListener{},
 		sync.Mutex{},
+		newLeaderboardCache(),
+		nil,
+		sync.RWMutex{},
 		st,
 	}, nil
 }
@@ -202,6 +383,54 @@ func (s *GameService) closeGame(g *Game) error {
 	return nil
 }
 
+// OnGameFinished registers a function to be called with the result of
+// every game that finishes from now on. It is called on its own goroutine
+func (s *GameService) OnGameFinished(f func(GameResult)) {
+	s.finishMutex.Lock()
+	defer s.finishMutex.Unlock()
+
+	s.finishListeners = append(s.finishListeners, f)
+}
+
+// finishGame is called once, right after the move that ends a game,
+// with the game's write mutex held. It saves the final position,
+// updates ratings if the game was rated and notifies finish listeners
+func (s *GameService) finishGame(g *Game) error {
+	err := s.Store.saveGame(g.uuid, g.underlying)
+	if err != nil {
+		return err
+	}
+
+	playerX, playerO, _, _ := g.underlying.SaveGame()
+	result := GameResult{
+		GameID:   g.uuid,
+		PlayerX:  playerX,
+		PlayerO:  playerO,
+		Settings: g.settings,
+		Victor:   g.underlying.GameWinner(),
+	}
+
+	if pool := Pool(g.settings); pool != "" {
+		victor := result.Victor
+		if victor == game.StalematePlayer {
+			victor = ""
+		}
+
+		err = s.Store.recordResult(g.uuid, playerX, playerO, victor, pool)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.finishMutex.RLock()
+	defer s.finishMutex.RUnlock()
+	for _, f := range s.finishListeners {
+		go f(result)
+	}
+
+	return nil
+}
+
 func (s *GameService) ListenAny(notifs []NewGameNotification, ctx context.Context) <-chan int {
 	if len(notifs) == 0 {
 		return nil
@@ -229,7 +458,11 @@ func (s *GameService) ListenAny(notifs []NewGameNotification, ctx context.Contex
 			if chosen == len(notifs) {
 				return
 			}
-			ch <- chosen
+			select {
+			case ch <- chosen:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
@@ -242,61 +475,349 @@ func (s *GameService) CloseGames(notifs []NewGameNotification) {
 	}
 }
 
-func (s *GameService) CloseNewGameCh(playerID string) {
+// CloseNewGameCh stops sending new games to a channel returned by
+// OpenGamesForPlayer. Games that were on their way to it are closed
+func (s *GameService) CloseNewGameCh(playerID string, newGameCh <-chan NewGameNotification) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	ch := s.players[playerID]
-	close(ch)
+	listeners := s.players[playerID]
+	for idx, l := range listeners {
+		if l.ch == newGameCh {
+			close(l.closed)
+			listeners = append(listeners[:idx], listeners[idx+1:]...)
+			break
+		}
+	}
 
-	delete(s.players, playerID)
+	if len(listeners) == 0 {
+		delete(s.players, playerID)
+	} else {
+		s.players[playerID] = listeners
+	}
 }
 
-func (s *GameService) NewGame(playerX string, playerO string) error {
-	g, err := game.NewGame(playerX, playerO)
+// FollowPlayerGames calls update with each of the player's open games,
+// then again with a game whenever it changes, and with every game the
+// player starts, until ctx is canceled or update returns an error. The
+// games are closed before it returns
+func (s *GameService) FollowPlayerGames(ctx context.Context, playerID string, update func(*Game) error) error {
+	openGames, newGameCh, err := s.OpenGamesForPlayer(playerID)
 	if err != nil {
 		return err
 	}
+	defer s.CloseNewGameCh(playerID, newGameCh)
+	// added holds new games waiting for the current listener to stop
+	var added []NewGameNotification
+	defer func() {
+		s.CloseGames(openGames)
+		s.CloseGames(added)
+	}()
+
+	for _, n := range openGames {
+		err = update(n.Game)
+		if err != nil {
+			return err
+		}
+	}
+
+	// listen applies the new games and restarts the listener. The
+	// previous listener must have stopped
+	var updates <-chan int
+	cancel := func() {}
+	listen := func() {
+		openGames = append(openGames, added...)
+		added = nil
+
+		var listenCtx context.Context
+		listenCtx, cancel = context.WithCancel(ctx)
+		updates = s.ListenAny(openGames, listenCtx)
+	}
+	listen()
+	defer func() { cancel() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case idx, ok := <-updates:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				// the listener was stopped to pick up new games
+				listen()
+				break
+			}
+			err = update(openGames[idx].Game)
+		case n := <-newGameCh:
+			added = append(added, n)
+			err = update(n.Game)
+			if updates == nil {
+				// with no games open there's no listener to stop
+				listen()
+			} else {
+				cancel()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ColorChoice is the side the challenger asks to play when starting
+// a new game
+type ColorChoice string
+
+const (
+	// ColorX makes the challenger X. This is the default
+	ColorX ColorChoice = "x"
+	// ColorO makes the challenger O
+	ColorO ColorChoice = "o"
+	// ColorRandom assigns sides with a coin flip
+	ColorRandom ColorChoice = "random"
+	// ColorAlternate gives the challenger the opposite side from the
+	// last game the two players had against each other, and X if they
+	// have never played
+	ColorAlternate ColorChoice = "alternate"
+)
+
+// StandardVariant is the only variant of the game currently supported
+const StandardVariant = "standard"
+
+// GameSettings are the rules a game is played under. They are kept
+// for rematches and used to keep matchmaking pools apart
+type GameSettings struct {
+	// Variant names the rule set, defaulting to StandardVariant
+	Variant string `json:"variant"`
+
+	// TimeControl is "<minutes>+<increment seconds>", e.g. "5+3", or
+	// empty for an untimed game. The server does not run clocks yet;
+	// this only records what the players agreed to
+	TimeControl string `json:"timeControl"`
+
+	// Casual games do not affect either player's rating
+	Casual bool `json:"casual"`
+
+	// Private games can't be spectated
+	Private bool `json:"private"`
+}
+
+// ErrInvalidSettings is returned when a game is requested with an
+// unknown variant or a malformed time control
+var ErrInvalidSettings = errors.New("invalid game settings")
+
+var timeControlRegex = regexp.MustCompile(`^[0-9]+\+[0-9]+$`)
+
+// normalize fills in defaults and validates the settings
+func (gs GameSettings) normalize() (GameSettings, error) {
+	if gs.Variant == "" {
+		gs.Variant = StandardVariant
+	}
+	if gs.Variant != StandardVariant {
+		return gs, ErrInvalidSettings
+	}
+
+	if gs.TimeControl != "" && !timeControlRegex.MatchString(gs.TimeControl) {
+		return gs, ErrInvalidSettings
+	}
+
+	return gs, nil
+}
+
+// ErrInvalidColor is returned by NewGame when the requested color
+// is not one of the ColorChoice constants
+var ErrInvalidColor = errors.New("invalid color choice")
+
+func (s *GameService) assignColors(challenger, opponent string, color ColorChoice) (playerX, playerO string, err error) {
+	challengerX := true
+	switch color {
+	case "", ColorX:
+		break
+	case ColorO:
+		challengerX = false
+	case ColorRandom:
+		challengerX = rand.Intn(2) == 0
+	case ColorAlternate:
+		lastX, err := s.Store.lastPlayerXBetween(challenger, opponent)
+		if err != nil {
+			return "", "", err
+		}
+		challengerX = lastX != challenger
+	default:
+		return "", "", ErrInvalidColor
+	}
+
+	if challengerX {
+		return challenger, opponent, nil
+	}
+	return opponent, challenger, nil
+}
+
+// NewGame starts a game between the challenger and their opponent,
+// with sides assigned according to color. It returns the ID of the
+// new game
+func (s *GameService) NewGame(challenger string, opponent string, color ColorChoice, settings GameSettings) (string, error) {
+	settings, err := settings.normalize()
+	if err != nil {
+		return "", err
+	}
+
+	blocked, err := s.Store.IsBlocked(challenger, opponent)
+	if err != nil {
+		return "", err
+	} else if blocked {
+		return "", ErrBlocked
+	}
+
+	playerX, playerO, err := s.assignColors(challenger, opponent, color)
+	if err != nil {
+		return "", err
+	}
+
+	return s.startGame(playerX, playerO, settings, "")
+}
+
+// StartGame starts a game between two players paired by the server,
+// such as in a tournament, rather than by a challenge. Unlike NewGame,
+// it doesn't check whether either player has blocked the other
+func (s *GameService) StartGame(playerX, playerO string, settings GameSettings) (string, error) {
+	settings, err := settings.normalize()
+	if err != nil {
+		return "", err
+	}
+
+	return s.startGame(playerX, playerO, settings, "")
+}
+
+// ErrGameNotFinished is returned by RequestRematch, and for the review,
+// of a game that is still in progress
+var ErrGameNotFinished = errors.New("game not finished")
+
+// RequestRematch records that playerID wants a rematch of g. Once both
+// players have asked, a new game is started with the same settings and
+// sides swapped, and its ID is returned. Until then, "" is returned.
+// Asking again after the rematch has started returns the existing game
+func (s *GameService) RequestRematch(g *Game, playerID string) (string, error) {
+	g.mutex.Lock()
+	playerX, playerO, _, _ := g.underlying.SaveGame()
+	if playerID != playerX && playerID != playerO {
+		g.mutex.Unlock()
+		return "", game.ErrInvalidPlayer
+	}
+	if !g.underlying.IsCompleted() {
+		g.mutex.Unlock()
+		return "", ErrGameNotFinished
+	}
 
-	uuid, err := s.Store.saveNewGame(g)
+	blocked, err := s.Store.IsBlocked(playerX, playerO)
+	if err != nil || blocked {
+		g.mutex.Unlock()
+		if blocked {
+			err = ErrBlocked
+		}
+		return "", err
+	}
+
+	_, rematch, err := s.Store.rematchLinks(g.uuid)
+	if err != nil || rematch != "" || g.rematchStarting {
+		g.mutex.Unlock()
+		return rematch, err
+	}
+
+	if g.rematchRequestedBy == "" || g.rematchRequestedBy == playerID {
+		g.rematchRequestedBy = playerID
+		g.notifyListeners(nil)
+		g.mutex.Unlock()
+		return "", nil
+	}
+
+	// both players agree. The game mutex can't be held while starting
+	// the new game, since that takes the service mutex
+	g.rematchStarting = true
+	g.mutex.Unlock()
+
+	rematch, err = s.startGame(playerO, playerX, g.settings, g.uuid)
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.rematchStarting = false
+	if err != nil {
+		return "", err
+	}
+	g.rematchRequestedBy = ""
+	g.notifyListeners(nil)
+
+	return rematch, nil
+}
+
+// DeclineRematch turns down the rematch of g that playerID's opponent
+// asked for, or withdraws playerID's own request
+func (s *GameService) DeclineRematch(g *Game, playerID string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	playerX, playerO, _, _ := g.underlying.SaveGame()
+	if playerID != playerX && playerID != playerO {
+		return game.ErrInvalidPlayer
+	}
+	if g.rematchRequestedBy == "" || g.rematchStarting {
+		return nil
+	}
+
+	g.rematchRequestedBy = ""
+	g.notifyListeners(nil)
+	return nil
+}
+
+// startGame creates, saves and loads a game, notifying both players
+// that it exists
+func (s *GameService) startGame(playerX, playerO string, settings GameSettings, rematchOf string) (string, error) {
+	g, err := game.NewGame(playerX, playerO)
+	if err != nil {
+		return "", err
+	}
+
+	// the game is saved under the service mutex so that a player opening
+	// their games meanwhile can't load a second copy of it
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	uuid, err := s.Store.saveNewGame(g, settings, rematchOf)
 	if err != nil {
 		panic(err)
 	}
 
+	// each of the players' sessions gets its own listener. The game is
+	// only kept loaded while it has listeners, so if neither player is
+	// online it is left to be loaded when it is first opened
+	listeners := append(append([]newGameListener{}, s.players[playerX]...), s.players[playerO]...)
+	if len(listeners) == 0 {
+		return uuid, nil
+	}
+
 	loaded := &loadedGame{
-		openConns: 1,
 		game: &Game{
 			underlying:     g,
 			service:        s,
 			uuid:           uuid,
-			listenChannels: []chan struct{}{make(chan struct{})},
+			listenChannels: []chan struct{}{},
+			settings:       settings,
+			spectators:     map[<-chan struct{}]bool{},
 		},
 	}
 
 	loaded.game.mutex.Lock()
 	defer loaded.game.mutex.Unlock()
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
 	s.games[uuid] = loaded
-
-	updateCh, ok := s.players[playerX]
-	if ok {
-		notif := NewGameNotification{loaded.game, loaded.game.listenForUpdates()}
-		go func(ch chan NewGameNotification, notif NewGameNotification) {
-			ch <- notif
-		}(updateCh, notif)
+	for _, l := range listeners {
+		loaded.openConns++
+		l.notify(NewGameNotification{loaded.game, loaded.game.listenForUpdates()})
 	}
 
-	updateCh, ok = s.players[playerO]
-	if ok {
-		notif := NewGameNotification{loaded.game, loaded.game.listenForUpdates()}
-		go func(ch chan NewGameNotification, notif NewGameNotification) {
-			ch <- notif
-		}(updateCh, notif)
-	}
-
-	return nil
+	return uuid, nil
 }
 
 func (s *GameService) OpenGamesForPlayer(playerUUID string) ([]NewGameNotification, <-chan NewGameNotification, error) {
@@ -312,41 +833,111 @@ func (s *GameService) OpenGamesForPlayer(playerUUID string) ([]NewGameNotificati
 	games := make([]NewGameNotification, count)
 
 	for i, uuid := range uuids {
-		loaded, ok := s.games[uuid]
+		games[i], err = s.openGame(uuid, false)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-		if !ok {
-			// load game from db
-			underlying, err := s.Store.loadGame(uuid)
-			if err != nil {
-				return nil, nil, err
-			}
-			loaded = &loadedGame{
-				openConns: 1,
-				game: &Game{
-					underlying:     underlying,
-					service:        s,
-					uuid:           uuid,
-					listenChannels: []chan struct{}{},
-				},
-			}
+	l := newGameListener{
+		ch:     make(chan NewGameNotification),
+		closed: make(chan struct{}),
+	}
+	s.players[playerUUID] = append(s.players[playerUUID], l)
+	return games, l.ch, nil
+}
+
+// ErrGameNotFound is returned when a game does not exist
+var ErrGameNotFound = errors.New("game not found")
+
+// ErrPrivateGame is returned by SpectateGame for games that have
+// spectating turned off
+var ErrPrivateGame = errors.New("game is private")
+
+// SpectateGame subscribes playerID to updates for a game that isn't
+// private. Spectators are notified of the same changes as the players,
+// and unsubscribe by closing the notification like any other
+func (s *GameService) SpectateGame(gameID, playerID string) (NewGameNotification, error) {
+	err := s.Store.checkSpectator(gameID, playerID)
+	if err != nil {
+		return NewGameNotification{}, err
+	}
 
-			s.games[uuid] = loaded
-			// don't need the game mutex held here because nobody else can have
-			// a handle to it yet
-			games[i] = NewGameNotification{loaded.game, loaded.game.listenForUpdates()}
-			go s.periodicFlushToDB(loaded.game)
-		} else {
-			// attach to loaded game
-			loaded.openConns++
-			loaded.game.mutex.Lock()
-			games[i] = NewGameNotification{loaded.game, loaded.game.listenForUpdates()}
-			loaded.game.mutex.Unlock()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.openGame(gameID, true)
+}
+
+// checkSpectator returns an error unless playerID may watch the game:
+// it must exist, not be private, and neither player may have blocked
+// playerID or been blocked by them
+func (s *Store) checkSpectator(gameID, playerID string) error {
+	settings, err := s.loadSettings(gameID)
+	if err == sql.ErrNoRows {
+		return ErrGameNotFound
+	} else if err != nil {
+		return err
+	} else if settings.Private {
+		return ErrPrivateGame
+	}
+
+	playerX, playerO, err := s.gamePlayers(gameID)
+	if err != nil {
+		return err
+	}
+
+	blocked, err := s.blockedBetween(playerID)
+	if err != nil {
+		return err
+	} else if blocked[playerX] || blocked[playerO] {
+		return ErrBlocked
+	}
+
+	return nil
+}
+
+// openGame attaches a new listener to a game, loading it from the
+// database if nobody has it open. The service mutex must be held
+func (s *GameService) openGame(uuid string, spectator bool) (NewGameNotification, error) {
+	loaded, ok := s.games[uuid]
+	if !ok {
+		underlying, err := s.Store.loadGame(uuid)
+		if err != nil {
+			return NewGameNotification{}, err
+		}
+		settings, err := s.Store.loadSettings(uuid)
+		if err != nil {
+			return NewGameNotification{}, err
 		}
+		loaded = &loadedGame{
+			game: &Game{
+				underlying:     underlying,
+				service:        s,
+				uuid:           uuid,
+				listenChannels: []chan struct{}{},
+				settings:       settings,
+				spectators:     map[<-chan struct{}]bool{},
+			},
+		}
+
+		s.games[uuid] = loaded
+		go s.periodicFlushToDB(loaded.game)
+	}
+
+	loaded.openConns++
+	loaded.game.mutex.Lock()
+	defer loaded.game.mutex.Unlock()
+
+	ch := loaded.game.listenForUpdates()
+	if spectator {
+		loaded.game.spectators[ch] = true
+		// everyone is shown the new spectator count. The spectator is
+		// sent the game by whoever asked for it, and has nothing to
+		// catch up on by the time they read the notification
+		loaded.game.notifyListeners(loaded.game.spectatorDelta())
 	}
 
-	ch := make(chan NewGameNotification)
-	s.players[playerUUID] = ch
-	return games, ch, nil
+	return NewGameNotification{loaded.game, ch}, nil
 }
 
 func (s *GameService) periodicFlushToDB(g *Game) {
@@ -356,6 +947,7 @@ func (s *GameService) periodicFlushToDB(g *Game) {
 		if len(g.listenChannels) == 0 {
 			// game has been unloaded, do nothing
 			// and exit
+			g.mutex.RUnlock()
 			return
 		}
 
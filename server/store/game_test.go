@@ -0,0 +1,429 @@
+package store
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// newTestService returns a game service on a fresh database
+func newTestService(t *testing.T) *GameService {
+	games, err := NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return games
+}
+
+// newPlayers creates a player for each name, and returns their IDs
+func newPlayers(t *testing.T, games *GameService, names ...string) []string {
+	ids := []string{}
+	for _, name := range names {
+		p, err := games.CreatePlayer(name, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p.UUID)
+	}
+	return ids
+}
+
+// finishedGame returns the moves of a game of random moves that ends
+// with winner, "x", "o" or game.StalematePlayer. The same moves are
+// returned every time
+func finishedGame(winner string) []game.Coordinate {
+	for seed := int64(0); ; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		g, _ := game.NewGame("x", "o")
+		players := [2]string{"x", "o"}
+		moves := []game.Coordinate{}
+		for i := 0; !g.IsCompleted(); i++ {
+			valid := g.GetValidMoves(players[i%2])
+			c := valid[r.Intn(len(valid))].Coordinate
+			g.PlayMove(game.Move{PlayerID: players[i%2], Coordinate: c})
+			moves = append(moves, c)
+		}
+		if g.GameWinner() == winner {
+			return moves
+		}
+	}
+}
+
+// playGame starts a game and plays it to the end, won by winner, "x",
+// "o" or game.StalematePlayer. It returns the game's ID
+func playGame(t *testing.T, games *GameService, playerX, playerO string, settings GameSettings, winner string) string {
+	gameID, err := games.StartGame(playerX, playerO, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finishGame(t, games, gameID, playerX, playerO, winner)
+	return gameID
+}
+
+// finishGame plays a new game to the end, won by winner
+func finishGame(t *testing.T, games *GameService, gameID, playerX, playerO, winner string) {
+	players := [2]string{playerX, playerO}
+	for i, c := range finishedGame(winner) {
+		_, err := games.PlayMove(gameID, game.Move{PlayerID: players[i%2], Coordinate: c})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGameStateGridOwner(t *testing.T) {
+	games, err := NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, _ := games.CreatePlayer("alice", "alice")
+	bob, _ := games.CreatePlayer("bob", "bob")
+	gameID, err := games.NewGame(alice.UUID, bob.UUID, ColorX, GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// X keeps sending O back to A1, where O takes the middle column
+	moves := []game.Coordinate{
+		game.NewCoordinate(1, 1, 1, 1), game.NewCoordinate(1, 1, 2, 2),
+		game.NewCoordinate(2, 2, 1, 1), game.NewCoordinate(1, 1, 2, 1),
+		game.NewCoordinate(2, 1, 1, 1), game.NewCoordinate(1, 1, 2, 3),
+	}
+	players := [2]string{alice.UUID, bob.UUID}
+	var state *GameState
+	for i, c := range moves {
+		state, err = games.PlayMove(gameID, game.Move{PlayerID: players[i%2], Coordinate: c})
+		if err != nil {
+			t.Fatalf("move %d: %v", i+1, err)
+		}
+	}
+
+	if owner := state.Grids[0][0].Owner; owner == nil || *owner != bob.UUID {
+		t.Errorf("A1 is owned by %v, want O", owner)
+	}
+	for i, row := range state.Grids {
+		for j, grid := range row {
+			if (i != 0 || j != 0) && grid.Owner != nil {
+				t.Errorf("grid %d,%d is owned by %v", i, j, *grid.Owner)
+			}
+		}
+	}
+}
+
+func TestStartGameOffline(t *testing.T) {
+	games, err := NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, _ := games.CreatePlayer("alice", "alice")
+	bob, _ := games.CreatePlayer("bob", "bob")
+	gameID, err := games.NewGame(alice.UUID, bob.UUID, ColorX, GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := games.games[gameID]; ok {
+		t.Fatal("game without listeners was left loaded")
+	}
+
+	// playing it loads it, and it is unloaded again once played
+	_, err = games.PlayMove(gameID, game.Move{PlayerID: alice.UUID, Coordinate: game.NewCoordinate(2, 2, 2, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := games.games[gameID]; ok {
+		t.Error("game was left loaded after a move")
+	}
+
+	openGames, newGameCh, err := games.OpenGamesForPlayer(bob.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer games.CloseNewGameCh(bob.UUID, newGameCh)
+	defer games.CloseGames(openGames)
+	if len(openGames) != 1 || len(openGames[0].Game.ValidMoves(bob.UUID)) == 0 {
+		t.Errorf("got open games %+v, want the game on bob's turn", openGames)
+	}
+}
+
+func TestNewGameColors(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+
+	playerX := func(color ColorChoice) string {
+		gameID, err := games.NewGame(alice, bob, color, GameSettings{})
+		if err != nil {
+			t.Fatalf("%q: %v", color, err)
+		}
+		x, o, err := games.gamePlayers(gameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (x != alice || o != bob) && (x != bob || o != alice) {
+			t.Fatalf("%q: got %v against %v", color, x, o)
+		}
+		return x
+	}
+
+	// with no game between them yet, the challenger starts as X
+	if x := playerX(ColorAlternate); x != alice {
+		t.Errorf("first alternate game has %v as X, want alice", x)
+	}
+	for i, want := range []string{bob, alice, bob} {
+		if x := playerX(ColorAlternate); x != want {
+			t.Errorf("alternate game %d has %v as X, want %v", i+2, x, want)
+		}
+	}
+
+	if x := playerX(""); x != alice {
+		t.Errorf("default color has %v as X, want alice", x)
+	}
+	if x := playerX(ColorX); x != alice {
+		t.Errorf("x has %v as X, want alice", x)
+	}
+	if x := playerX(ColorO); x != bob {
+		t.Errorf("o has %v as X, want bob", x)
+	}
+	// the last game had bob as X
+	if x := playerX(ColorAlternate); x != alice {
+		t.Errorf("alternate after o has %v as X, want alice", x)
+	}
+
+	sides := map[string]bool{}
+	for i := 0; i < 64; i++ {
+		sides[playerX(ColorRandom)] = true
+	}
+	if len(sides) != 2 {
+		t.Errorf("random always picked %v", sides)
+	}
+
+	if _, err := games.NewGame(alice, bob, "purple", GameSettings{}); err != ErrInvalidColor {
+		t.Errorf("got %v, want %v", err, ErrInvalidColor)
+	}
+}
+
+func TestNewGameSettingsSaved(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob")
+
+	settings := GameSettings{Variant: StandardVariant, TimeControl: "5+3", Casual: true, Private: true}
+	gameID, err := games.NewGame(ids[0], ids[1], ColorX, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := games.loadSettings(gameID)
+	if err != nil || saved != settings {
+		t.Errorf("got settings %+v, %v, want %+v", saved, err, settings)
+	}
+	var created int64
+	err = games.db.QueryRow(`SELECT Created FROM matches WHERE PK_UUID = ?;`, gameID).Scan(&created)
+	if err != nil || created == 0 {
+		t.Errorf("got creation time %v, %v", created, err)
+	}
+}
+
+// keepLoaded keeps a game loaded until the test ends, as it would be
+// while its players are online. Rematch requests are only kept by
+// loaded games
+func keepLoaded(t *testing.T, games *GameService, gameID string) {
+	games.mutex.Lock()
+	n, err := games.openGame(gameID, false)
+	games.mutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { n.Game.Close(n.UpdateCh) })
+}
+
+// rematch has each of players ask for a rematch of gameID in turn, and
+// returns what the last of them got
+func rematch(t *testing.T, games *GameService, gameID string, players ...string) string {
+	var rematchID string
+	err := games.withGame(gameID, func(g *Game) error {
+		for _, playerID := range players {
+			var err error
+			rematchID, err = games.RequestRematch(g, playerID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rematchID
+}
+
+func TestRematch(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	settings := GameSettings{Variant: StandardVariant, TimeControl: "5+3", Casual: true}
+
+	gameID, err := games.StartGame(alice, bob, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = games.withGame(gameID, func(g *Game) error {
+		if _, err := games.RequestRematch(g, alice); err != ErrGameNotFinished {
+			t.Errorf("unfinished game got %v, want %v", err, ErrGameNotFinished)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finishGame(t, games, gameID, alice, bob, "x")
+	keepLoaded(t, games, gameID)
+
+	err = games.withGame(gameID, func(g *Game) error {
+		if _, err := games.RequestRematch(g, carol); err != game.ErrInvalidPlayer {
+			t.Errorf("another player got %v, want %v", err, game.ErrInvalidPlayer)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// asking twice doesn't count as both players agreeing
+	if rematchID := rematch(t, games, gameID, alice, alice); rematchID != "" {
+		t.Fatalf("rematch %v started with only alice asking", rematchID)
+	}
+	state, err := games.GameState(gameID, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.RematchRequestedBy == nil || *state.RematchRequestedBy != alice || state.Rematch != nil {
+		t.Errorf("got requested by %v and rematch %v, want alice waiting", state.RematchRequestedBy, state.Rematch)
+	}
+
+	rematchID := rematch(t, games, gameID, bob)
+	if rematchID == "" {
+		t.Fatal("no rematch once both players asked")
+	}
+	if again := rematch(t, games, gameID, alice); again != rematchID {
+		t.Errorf("asking again got %v, want %v", again, rematchID)
+	}
+
+	state, err = games.GameState(rematchID, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.PlayerX != bob || state.PlayerO != alice {
+		t.Errorf("rematch has %v as X and %v as O, want the sides swapped", state.PlayerX, state.PlayerO)
+	}
+	if state.Settings != settings {
+		t.Errorf("rematch has settings %+v, want %+v", state.Settings, settings)
+	}
+	if state.RematchOf == nil || *state.RematchOf != gameID {
+		t.Errorf("rematch is a rematch of %v, want %v", state.RematchOf, gameID)
+	}
+
+	state, err = games.GameState(gameID, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Rematch == nil || *state.Rematch != rematchID || state.RematchRequestedBy != nil {
+		t.Errorf("got rematch %v requested by %v, want %v", state.Rematch, state.RematchRequestedBy, rematchID)
+	}
+}
+
+func TestDeclineRematch(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	gameID := playGame(t, games, alice, bob, GameSettings{}, "x")
+	keepLoaded(t, games, gameID)
+
+	decline := func(playerID string) error {
+		return games.withGame(gameID, func(g *Game) error {
+			return games.DeclineRematch(g, playerID)
+		})
+	}
+	requestedBy := func() *string {
+		state, err := games.GameState(gameID, alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.RematchRequestedBy
+	}
+
+	rematch(t, games, gameID, alice)
+	if err := decline(carol); err != game.ErrInvalidPlayer {
+		t.Errorf("another player got %v, want %v", err, game.ErrInvalidPlayer)
+	}
+	if err := decline(bob); err != nil {
+		t.Fatal(err)
+	}
+	if r := requestedBy(); r != nil {
+		t.Errorf("rematch still requested by %v after bob declined", *r)
+	}
+
+	// after declining, bob's own request waits on alice again rather
+	// than accepting the one he declined
+	if rematchID := rematch(t, games, gameID, bob); rematchID != "" {
+		t.Errorf("declined rematch %v started", rematchID)
+	}
+	if err := decline(bob); err != nil {
+		t.Fatal(err)
+	}
+	if r := requestedBy(); r != nil {
+		t.Errorf("rematch still requested by %v after bob withdrew", *r)
+	}
+
+	if rematchID := rematch(t, games, gameID, alice, bob); rematchID == "" {
+		t.Error("no rematch once both players asked")
+	}
+}
+
+func TestSeriesScore(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+
+	series := func(gameID string) SeriesScore {
+		state, err := games.GameState(gameID, alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.Series
+	}
+	check := func(gameID string, games, ties, aliceWins, bobWins int) {
+		t.Helper()
+		got := series(gameID)
+		if got.Games != games || got.Ties != ties || len(got.Wins) != 2 ||
+			got.Wins[alice] != aliceWins || got.Wins[bob] != bobWins {
+			t.Errorf("got %+v, want %d games, %d ties, %d-%d", got, games, ties, aliceWins, bobWins)
+		}
+	}
+
+	first := playGame(t, games, alice, bob, GameSettings{}, "x")
+	check(first, 1, 0, 1, 0)
+
+	second := rematch(t, games, first, alice, bob)
+	check(second, 1, 0, 1, 0)
+	finishGame(t, games, second, bob, alice, game.StalematePlayer)
+	check(second, 2, 1, 1, 0)
+
+	third := rematch(t, games, second, bob, alice)
+	finishGame(t, games, third, alice, bob, "o")
+	check(third, 3, 1, 1, 1)
+
+	// earlier games don't count later ones
+	check(first, 1, 0, 1, 0)
+	check(second, 2, 1, 1, 0)
+
+	// a game that isn't over yet doesn't count either
+	fourth := rematch(t, games, third, alice, bob)
+	check(fourth, 3, 1, 1, 1)
+
+	// nor do games outside the series
+	playGame(t, games, alice, bob, GameSettings{}, "x")
+	check(fourth, 3, 1, 1, 1)
+}
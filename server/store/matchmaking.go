@@ -0,0 +1,233 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultRatingRange is how far apart two players' ratings may be
+// when they first enter the queue, if they don't ask for a range
+const DefaultRatingRange = 100
+
+// ratingRangeGrowth is how many rating points a queued player's range
+// widens by for every second they have been waiting
+const ratingRangeGrowth = 10
+
+// matchmakingInterval is how often the queue is rescanned so that
+// widened ranges can produce new pairings
+const matchmakingInterval = 1 * time.Second
+
+// ErrInvalidRatingRange is returned by Enqueue for a negative range
+var ErrInvalidRatingRange = errors.New("invalid rating range")
+
+// MatchPreferences describes the games a queued player is willing
+// to play
+type MatchPreferences struct {
+	GameSettings
+
+	// RatingRange is how far from the player's own rating an opponent
+	// may be. It widens the longer the player waits. 0 means
+	// DefaultRatingRange
+	RatingRange int
+}
+
+// MatchFound is sent to both players once they have been paired and
+// their game has started
+type MatchFound struct {
+	GameID     string
+	OpponentID string
+}
+
+type queueEntry struct {
+	playerID string
+	prefs    MatchPreferences
+	rating   float64
+	joined   time.Time
+	found    chan MatchFound
+}
+
+// window is how far from its own rating the entry will accept an
+// opponent at the given time
+func (e *queueEntry) window(now time.Time) float64 {
+	waited := now.Sub(e.joined).Seconds()
+	return float64(e.prefs.RatingRange) + ratingRangeGrowth*waited
+}
+
+func (e *queueEntry) accepts(other *queueEntry, now time.Time) bool {
+	if e.playerID == other.playerID || e.prefs.GameSettings != other.prefs.GameSettings {
+		return false
+	}
+
+	return math.Abs(e.rating-other.rating) <= e.window(now)
+}
+
+// Matchmaker pairs players waiting for a game with the same settings
+// and a close enough rating, and starts their game through the
+// GameService
+type Matchmaker struct {
+	games *GameService
+	mutex sync.Mutex
+	queue []*queueEntry
+	// done is closed to stop the background scan
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMatchmaker creates a matchmaker and starts the background scan
+// that widens rating ranges over time, until Close is called
+func NewMatchmaker(games *GameService) *Matchmaker {
+	m := &Matchmaker{
+		games: games,
+		queue: []*queueEntry{},
+		done:  make(chan struct{}),
+	}
+
+	go m.run()
+	return m
+}
+
+// Enqueue adds a player to the queue, replacing any entry they already
+// had. The returned channel receives a MatchFound once they are paired,
+// and is closed without a value if they leave the queue first
+func (m *Matchmaker) Enqueue(playerID string, prefs MatchPreferences) (<-chan MatchFound, error) {
+	settings, err := prefs.GameSettings.normalize()
+	if err != nil {
+		return nil, err
+	}
+	prefs.GameSettings = settings
+
+	if prefs.RatingRange < 0 {
+		return nil, ErrInvalidRatingRange
+	} else if prefs.RatingRange == 0 {
+		prefs.RatingRange = DefaultRatingRange
+	}
+
+	// casual games are matched on the rating the player would have
+	// if the game were rated
+	ratedSettings := settings
+	ratedSettings.Casual = false
+	rating, err := m.games.Rating(playerID, Pool(ratedSettings))
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &queueEntry{
+		playerID: playerID,
+		prefs:    prefs,
+		rating:   rating.Rating,
+		joined:   time.Now(),
+		found:    make(chan MatchFound, 1),
+	}
+
+	m.mutex.Lock()
+	m.removeLocked(playerID)
+	m.queue = append(m.queue, entry)
+	m.mutex.Unlock()
+
+	m.pair()
+	return entry.found, nil
+}
+
+// Leave removes the queue entry whose channel Enqueue returned, if it
+// is still queued. A player's other sessions keep any entry they made
+func (m *Matchmaker) Leave(found <-chan MatchFound) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, e := range m.queue {
+		if e.found == found {
+			close(e.found)
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// The mutex must be held during this call
+func (m *Matchmaker) removeLocked(playerID string) {
+	for i, e := range m.queue {
+		if e.playerID == playerID {
+			close(e.found)
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *Matchmaker) run() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-time.After(matchmakingInterval):
+			m.pair()
+		}
+	}
+}
+
+// Close stops the background scan. Players still queued are only
+// paired when someone else joins
+func (m *Matchmaker) Close() {
+	m.closeOnce.Do(func() { close(m.done) })
+}
+
+// blocked reports whether two queued players have blocked each other,
+// and so can't be paired
+func (m *Matchmaker) blocked(a, b *queueEntry) bool {
+	blocked, err := m.games.IsBlocked(a.playerID, b.playerID)
+	if err != nil {
+		fmt.Println(err)
+		return true
+	}
+	return blocked
+}
+
+// pair matches up every pair of queued players who accept each other,
+// longest waiting first, and starts their games
+func (m *Matchmaker) pair() {
+	now := time.Now()
+	pairs := [][2]*queueEntry{}
+
+	m.mutex.Lock()
+	remaining := []*queueEntry{}
+	matched := map[*queueEntry]bool{}
+	for i, a := range m.queue {
+		if matched[a] {
+			continue
+		}
+		for _, b := range m.queue[i+1:] {
+			if !matched[b] && a.accepts(b, now) && b.accepts(a, now) && !m.blocked(a, b) {
+				matched[a] = true
+				matched[b] = true
+				pairs = append(pairs, [2]*queueEntry{a, b})
+				break
+			}
+		}
+		if !matched[a] {
+			remaining = append(remaining, a)
+		}
+	}
+	m.queue = remaining
+	m.mutex.Unlock()
+
+	for _, p := range pairs {
+		a, b := p[0], p[1]
+		gameID, err := m.games.NewGame(a.playerID, b.playerID, ColorRandom, a.prefs.GameSettings)
+		if err != nil {
+			// the players are dropped from the queue; closing their
+			// channels tells them to try again
+			fmt.Println(err)
+			close(a.found)
+			close(b.found)
+			continue
+		}
+
+		a.found <- MatchFound{GameID: gameID, OpponentID: b.playerID}
+		b.found <- MatchFound{GameID: gameID, OpponentID: a.playerID}
+		close(a.found)
+		close(b.found)
+	}
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMatchmakerLeave(t *testing.T) {
+	games, err := NewGameService(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMatchmaker(games)
+	defer m.Close()
+	alice, _ := games.CreatePlayer("alice", "alice")
+	bob, _ := games.CreatePlayer("bob", "bob")
+
+	found, err := m.Enqueue(alice.UUID, MatchPreferences{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// leaving with a channel from another session, or none, keeps the
+	// entry
+	m.Leave(nil)
+	m.Leave(make(chan MatchFound))
+	if _, err := m.Enqueue(bob.UUID, MatchPreferences{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case match, ok := <-found:
+		if !ok || match.OpponentID != bob.UUID {
+			t.Errorf("got %+v, %v", match, ok)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("alice wasn't paired")
+	}
+
+	found, err = m.Enqueue(alice.UUID, MatchPreferences{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Leave(found)
+	if _, ok := <-found; ok {
+		t.Error("left entry was paired")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.queue) != 0 {
+		t.Errorf("queue still holds %d entries", len(m.queue))
+	}
+}
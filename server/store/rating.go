@@ -0,0 +1,225 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/heartles/uttt/server/rating"
+)
+
+const initRatings = `
+CREATE TABLE IF NOT EXISTS "ratings"
+(
+	[UserID] TEXT NOT NULL,
+	[Pool] TEXT NOT NULL,
+	[Rating] REAL NOT NULL,
+	[Deviation] REAL NOT NULL,
+	[Volatility] REAL NOT NULL,
+	[Games] INTEGER NOT NULL,
+	PRIMARY KEY (UserID, Pool),
+	FOREIGN KEY (UserID) REFERENCES "users" (PK_UUID)
+);
+
+CREATE TABLE IF NOT EXISTS "rating_history"
+(
+	[UserID] TEXT NOT NULL,
+	[Pool] TEXT NOT NULL,
+	[GameID] CHAR(36) NOT NULL,
+	[Rating] REAL NOT NULL,
+	[Deviation] REAL NOT NULL,
+	[Volatility] REAL NOT NULL,
+	[Recorded] INTEGER NOT NULL,
+	FOREIGN KEY (UserID) REFERENCES "users" (PK_UUID),
+	FOREIGN KEY (GameID) REFERENCES "matches" (PK_UUID)
+);
+
+CREATE INDEX IF NOT EXISTS rating_history_user ON rating_history (UserID, Pool);
+`
+
+// Rating is a player's standing in one rating pool
+type Rating struct {
+	Pool       string  `json:"pool"`
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	Games      int     `json:"games"`
+}
+
+// RatingChange is a player's rating in a pool right after a game
+type RatingChange struct {
+	GameID     string    `json:"gameID"`
+	Rating     float64   `json:"rating"`
+	Deviation  float64   `json:"deviation"`
+	Volatility float64   `json:"volatility"`
+	Recorded   time.Time `json:"recorded"`
+}
+
+// Pool returns the name of the rating pool games with the given
+// settings are rated in. Each variant and time control is rated
+// separately. Casual games are not rated and have no pool
+func Pool(settings GameSettings) string {
+	if settings.Casual {
+		return ""
+	}
+
+	variant := settings.Variant
+	if variant == "" {
+		variant = StandardVariant
+	}
+
+	timeControl := settings.TimeControl
+	if timeControl == "" {
+		timeControl = "untimed"
+	}
+
+	return variant + "/" + timeControl
+}
+
+func defaultRating(pool string) *Rating {
+	r := rating.Default()
+	return &Rating{
+		Pool:       pool,
+		Rating:     r.Rating,
+		Deviation:  r.Deviation,
+		Volatility: r.Volatility,
+	}
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func lookupRating(db queryRower, playerID, pool string) (*Rating, error) {
+	row := db.QueryRow(`
+		SELECT Rating, Deviation, Volatility, Games FROM ratings
+		WHERE UserID = ? AND Pool = ?;
+	`, playerID, pool)
+
+	r := Rating{Pool: pool}
+	err := row.Scan(&r.Rating, &r.Deviation, &r.Volatility, &r.Games)
+	if err == sql.ErrNoRows {
+		return defaultRating(pool), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Rating returns a player's rating in the given pool. Players who have
+// not played in the pool have the default rating
+func (s *Store) Rating(playerID, pool string) (*Rating, error) {
+	return lookupRating(s.db, playerID, pool)
+}
+
+// PlayerRatings returns a player's rating in every pool they have
+// played a rated game in
+func (s *Store) PlayerRatings(playerID string) ([]Rating, error) {
+	rows, err := s.db.Query(`
+		SELECT Pool, Rating, Deviation, Volatility, Games FROM ratings
+		WHERE UserID = ? ORDER BY Pool;
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []Rating{}
+	for rows.Next() {
+		r := Rating{}
+		err = rows.Scan(&r.Pool, &r.Rating, &r.Deviation, &r.Volatility, &r.Games)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+
+	return ratings, rows.Err()
+}
+
+// RatingHistory returns a player's rating after each rated game in
+// the given pool, oldest first
+func (s *Store) RatingHistory(playerID, pool string) ([]RatingChange, error) {
+	rows, err := s.db.Query(`
+		SELECT GameID, Rating, Deviation, Volatility, Recorded FROM rating_history
+		WHERE UserID = ? AND Pool = ? ORDER BY Recorded, rowid;
+	`, playerID, pool)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []RatingChange{}
+	for rows.Next() {
+		c := RatingChange{}
+		var recorded int64
+		err = rows.Scan(&c.GameID, &c.Rating, &c.Deviation, &c.Volatility, &recorded)
+		if err != nil {
+			return nil, err
+		}
+		c.Recorded = time.Unix(recorded, 0)
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
+// recordResult updates both players' ratings after a finished rated
+// game. Each game is treated as its own rating period
+func (s *Store) recordResult(gameID, playerX, playerO, victor, pool string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ratingX, err := lookupRating(tx, playerX, pool)
+	if err != nil {
+		return err
+	}
+	ratingO, err := lookupRating(tx, playerO, pool)
+	if err != nil {
+		return err
+	}
+
+	scoreX := rating.Tie
+	if victor == playerX {
+		scoreX = rating.Win
+	} else if victor == playerO {
+		scoreX = rating.Loss
+	}
+
+	oldX := rating.Rating{Rating: ratingX.Rating, Deviation: ratingX.Deviation, Volatility: ratingX.Volatility}
+	oldO := rating.Rating{Rating: ratingO.Rating, Deviation: ratingO.Deviation, Volatility: ratingO.Volatility}
+	newX := rating.Update(oldX, []rating.Result{{Opponent: oldO, Score: scoreX}})
+	newO := rating.Update(oldO, []rating.Result{{Opponent: oldX, Score: 1 - scoreX}})
+
+	now := time.Now().Unix()
+	for _, update := range []struct {
+		playerID string
+		r        rating.Rating
+	}{{playerX, newX}, {playerO, newO}} {
+		_, err = tx.Exec(`
+			INSERT INTO ratings(UserID, Pool, Rating, Deviation, Volatility, Games)
+			VALUES(?,?,?,?,?,1)
+			ON CONFLICT(UserID, Pool) DO UPDATE SET
+				Rating = excluded.Rating,
+				Deviation = excluded.Deviation,
+				Volatility = excluded.Volatility,
+				Games = Games + 1;
+		`, update.playerID, pool, update.r.Rating, update.r.Deviation, update.r.Volatility)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO rating_history(UserID, Pool, GameID, Rating, Deviation, Volatility, Recorded)
+			VALUES(?,?,?,?,?,?,?);
+		`, update.playerID, pool, gameID, update.r.Rating, update.r.Deviation, update.r.Volatility, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
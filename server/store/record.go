@@ -0,0 +1,294 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+const initMoves = `
+CREATE TABLE IF NOT EXISTS "moves"
+(
+	[GameID] CHAR(36) NOT NULL,
+	[Number] INTEGER NOT NULL,
+	[PlayerID] TEXT NOT NULL,
+	[Move] TEXT NOT NULL,
+	[Played] INTEGER NOT NULL,
+	PRIMARY KEY (GameID, Number),
+	FOREIGN KEY (GameID) REFERENCES "matches" (PK_UUID),
+	FOREIGN KEY (PlayerID) REFERENCES "users" (PK_UUID)
+);
+`
+
+// GameSummary describes a game without loading it
+type GameSummary struct {
+	GameID      string       `json:"gameID"`
+	PlayerX     string       `json:"playerX"`
+	PlayerO     string       `json:"playerO"`
+	PlayerXName string       `json:"playerXName"`
+	PlayerOName string       `json:"playerOName"`
+	Victor      *string      `json:"victor"`
+	Finished    bool         `json:"finished"`
+	Created     time.Time    `json:"created"`
+	Settings    GameSettings `json:"settings"`
+}
+
+// recordMove appends a move to a game's move list, in notation
+func (s *Store) recordMove(gameID string, m game.Move) error {
+	_, err := s.db.Exec(`
+		INSERT INTO moves (GameID, Number, PlayerID, Move, Played)
+		SELECT ?, COUNT(*) + 1, ?, ?, ? FROM moves WHERE GameID = ?;
+	`, gameID, m.PlayerID, m.Coordinate.String(), time.Now().Unix(), gameID)
+	return err
+}
+
+// PlayerGames returns every game a player has played in, newest first
+func (s *Store) PlayerGames(playerID string) ([]GameSummary, error) {
+	rows, err := s.db.Query(`
+		SELECT m.PK_UUID, m.UserX, m.UserO, x.Username, o.Username, m.Victor,
+			m.Finished, m.Created, m.Variant, m.TimeControl, m.Casual, m.Private
+		FROM matches m
+		JOIN users x ON x.PK_UUID = m.UserX
+		JOIN users o ON o.PK_UUID = m.UserO
+		WHERE m.UserX = ? OR m.UserO = ?
+		ORDER BY m.Created DESC, m.rowid DESC;
+	`, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []GameSummary{}
+	for rows.Next() {
+		g := GameSummary{}
+		var created int64
+		err = rows.Scan(&g.GameID, &g.PlayerX, &g.PlayerO, &g.PlayerXName, &g.PlayerOName,
+			&g.Victor, &g.Finished, &created, &g.Settings.Variant, &g.Settings.TimeControl,
+			&g.Settings.Casual, &g.Settings.Private)
+		if err != nil {
+			return nil, err
+		}
+		g.Created = time.Unix(created, 0)
+		games = append(games, g)
+	}
+
+	return games, rows.Err()
+}
+
+// checkViewer returns an error unless playerID may look at the game:
+// they must be playing in it, or be allowed to spectate it
+func (s *Store) checkViewer(gameID, playerID string) error {
+	playerX, playerO, err := s.gamePlayers(gameID)
+	if err == sql.ErrNoRows {
+		return ErrGameNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if playerID == playerX || playerID == playerO {
+		return nil
+	}
+	return s.checkSpectator(gameID, playerID)
+}
+
+// gameRecord writes out a game's tags and recorded moves
+func (s *Store) gameRecord(gameID string) (game.Record, error) {
+	row := s.db.QueryRow(`
+		SELECT x.Username, o.Username, m.UserX, IFNULL(m.Victor, ""), m.Finished,
+			m.Created, m.Variant, m.TimeControl, m.Casual
+		FROM matches m
+		JOIN users x ON x.PK_UUID = m.UserX
+		JOIN users o ON o.PK_UUID = m.UserO
+		WHERE m.PK_UUID = ?;
+	`, gameID)
+
+	var nameX, nameO, playerX, victor string
+	var finished bool
+	var created int64
+	var settings GameSettings
+	err := row.Scan(&nameX, &nameO, &playerX, &victor, &finished, &created,
+		&settings.Variant, &settings.TimeControl, &settings.Casual)
+	if err != nil {
+		return game.Record{}, err
+	}
+
+	record := game.Record{
+		Tags: []game.Tag{
+			{Name: "Game", Value: gameID},
+			{Name: "Date", Value: time.Unix(created, 0).UTC().Format("2006.01.02")},
+			{Name: "X", Value: nameX},
+			{Name: "O", Value: nameO},
+			{Name: "Variant", Value: settings.Variant},
+		},
+		Result: game.ResultUnfinished,
+	}
+	if settings.TimeControl != "" {
+		record.Tags = append(record.Tags, game.Tag{Name: "TimeControl", Value: settings.TimeControl})
+	}
+	if settings.Casual {
+		record.Tags = append(record.Tags, game.Tag{Name: "Casual", Value: "true"})
+	}
+
+	if finished {
+		switch victor {
+		case game.StalematePlayer:
+			record.Result = game.ResultTie
+		case playerX:
+			record.Result = game.ResultXWins
+		default:
+			record.Result = game.ResultOWins
+		}
+	}
+	record.Tags = append(record.Tags, game.Tag{Name: "Result", Value: record.Result})
+
+	record.Moves, err = s.recordedMoves(gameID)
+	return record, err
+}
+
+func (s *Store) recordedMoves(gameID string) ([]game.Coordinate, error) {
+	rows, err := s.db.Query(`SELECT Move FROM moves WHERE GameID = ? ORDER BY Number;`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	moves := []game.Coordinate{}
+	for rows.Next() {
+		var move string
+		if err = rows.Scan(&move); err != nil {
+			return nil, err
+		}
+
+		c, err := game.ParseCoordinate(move)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, c)
+	}
+
+	return moves, rows.Err()
+}
+
+// FinishedRecords returns the records of every finished standard game
+// that isn't private, oldest first. Games from before moves were
+// recorded, which have fewer moves recorded than played, are left out
+func (s *Store) FinishedRecords() ([]game.Record, error) {
+	rows, err := s.db.Query(`
+		SELECT m.PK_UUID, m.GameData, COUNT(mv.Number)
+		FROM matches m
+		LEFT JOIN moves mv ON mv.GameID = m.PK_UUID
+		WHERE m.Finished AND NOT m.Private AND m.Variant = ?
+		GROUP BY m.PK_UUID
+		ORDER BY m.Created, m.rowid;
+	`, StandardVariant)
+	if err != nil {
+		return nil, err
+	}
+
+	gameIDs := []string{}
+	for rows.Next() {
+		var gameID, gameData string
+		var recorded int
+		if err = rows.Scan(&gameID, &gameData, &recorded); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if recorded != 0 && recorded == moveCount(gameData) {
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	records := []game.Record{}
+	for _, gameID := range gameIDs {
+		record, err := s.gameRecord(gameID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// withGame opens a game for the length of f, loading it if nobody has
+// it open
+func (s *GameService) withGame(gameID string, f func(*Game) error) error {
+	s.mutex.Lock()
+	n, err := s.openGame(gameID, false)
+	s.mutex.Unlock()
+	if err == sql.ErrNoRows {
+		return ErrGameNotFound
+	} else if err != nil {
+		return err
+	}
+	defer n.Game.Close(n.UpdateCh)
+
+	return f(n.Game)
+}
+
+// GameRecord writes out a game move by move, as seen by playerID, who
+// must be allowed to look at it. Moves were only recorded from when the
+// move list was added, so the records of older games have an
+// "Incomplete" tag and may be missing moves
+func (s *GameService) GameRecord(gameID, playerID string) (game.Record, error) {
+	err := s.Store.checkViewer(gameID, playerID)
+	if err != nil {
+		return game.Record{}, err
+	}
+
+	var record game.Record
+	err = s.withGame(gameID, func(g *Game) error {
+		// read the moves while the game can't change, so they match
+		// the board
+		g.mutex.RLock()
+		defer g.mutex.RUnlock()
+
+		record, err = s.Store.gameRecord(gameID)
+		if err != nil {
+			return err
+		}
+
+		_, _, board, _ := g.underlying.SaveGame()
+		if len(record.Moves) != moveCount(board) {
+			record.Tags = append(record.Tags, game.Tag{Name: "Incomplete", Value: "true"})
+		}
+		return nil
+	})
+	return record, err
+}
+
+// GameState returns the state of a game as seen by playerID, who must
+// be allowed to look at it
+func (s *GameService) GameState(gameID, playerID string) (*GameState, error) {
+	err := s.Store.checkViewer(gameID, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var state *GameState
+	err = s.withGame(gameID, func(g *Game) error {
+		state, err = g.GetGameState(playerID)
+		return err
+	})
+	return state, err
+}
+
+// PlayMove plays a move in a game that the player may not have open,
+// and returns the game's new state
+func (s *GameService) PlayMove(gameID string, m game.Move) (*GameState, error) {
+	var state *GameState
+	err := s.withGame(gameID, func(g *Game) error {
+		err := g.PlayMove(m)
+		if err != nil {
+			return err
+		}
+
+		state, err = g.GetGameState(m.PlayerID)
+		return err
+	})
+	return state, err
+}
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+const initAnnotations = `
+CREATE TABLE IF NOT EXISTS "annotations"
+(
+	[GameID] CHAR(36) NOT NULL,
+	[Number] INTEGER NOT NULL,
+	[Move] TEXT NOT NULL,
+	[Class] TEXT NOT NULL,
+	[Score] INTEGER NOT NULL,
+	[Best] TEXT NOT NULL,
+	[BestScore] INTEGER NOT NULL,
+	[PV] TEXT NOT NULL,
+	PRIMARY KEY (GameID, Number),
+	FOREIGN KEY (GameID) REFERENCES "matches" (PK_UUID)
+);
+`
+
+// Move classes, from how much worse a move was than the best one
+const (
+	MoveBest       = "best"
+	MoveGood       = "good"
+	MoveInaccuracy = "inaccuracy"
+	MoveMistake    = "mistake"
+	MoveBlunder    = "blunder"
+)
+
+// Review statuses
+const (
+	ReviewPending = "pending"
+	ReviewDone    = "done"
+)
+
+// ErrNotReviewable is returned for the review of a game that won't have
+// one: a variant the engine doesn't play, or a game from before moves
+// were recorded
+var ErrNotReviewable = errors.New("game can't be reviewed")
+
+// MoveAnnotation is the engine's verdict on one move of a game. Scores
+// are from the point of view of the player who moved
+type MoveAnnotation struct {
+	// Number counts the game's moves from 1
+	Number int             `json:"number"`
+	Move   game.Coordinate `json:"move"`
+	Class  string          `json:"class"`
+	Score  int             `json:"score"`
+	// Best is the move the engine preferred, and PV the line it
+	// expected to follow
+	Best      game.Coordinate   `json:"best"`
+	BestScore int               `json:"bestScore"`
+	PV        []game.Coordinate `json:"pv"`
+}
+
+// GameReview is a finished game's moves annotated by the engine. Until
+// the review has run, Status is ReviewPending and there are no moves
+type GameReview struct {
+	GameID string           `json:"gameID"`
+	Status string           `json:"status"`
+	Moves  []MoveAnnotation `json:"moves"`
+}
+
+// reviewable reports whether a finished game can be reviewed, from its
+// variant, stored board and number of recorded moves
+func reviewable(variant, gameData string, recorded int) bool {
+	return variant == StandardVariant && recorded != 0 && recorded == moveCount(gameData)
+}
+
+// UnreviewedGames returns the IDs of finished games that can be
+// reviewed but haven't been, oldest first
+func (s *Store) UnreviewedGames() ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT m.PK_UUID, m.Variant, m.GameData, COUNT(mv.Number)
+		FROM matches m
+		LEFT JOIN moves mv ON mv.GameID = m.PK_UUID
+		WHERE m.Finished AND NOT EXISTS (SELECT 1 FROM annotations a WHERE a.GameID = m.PK_UUID)
+		GROUP BY m.PK_UUID
+		ORDER BY m.Created, m.rowid;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gameIDs := []string{}
+	for rows.Next() {
+		var gameID, variant, gameData string
+		var recorded int
+		if err = rows.Scan(&gameID, &variant, &gameData, &recorded); err != nil {
+			return nil, err
+		}
+		if reviewable(variant, gameData, recorded) {
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+	return gameIDs, rows.Err()
+}
+
+// Reviewed reports whether a game's review has been saved
+func (s *Store) Reviewed(gameID string) (bool, error) {
+	var reviewed bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM annotations WHERE GameID = ?);
+	`, gameID).Scan(&reviewed)
+	return reviewed, err
+}
+
+// reviewState returns whether a game has finished and whether it can be
+// reviewed
+func (s *Store) reviewState(gameID string) (bool, bool, error) {
+	row := s.db.QueryRow(`
+		SELECT m.Finished, m.Variant, m.GameData,
+			(SELECT COUNT(*) FROM moves mv WHERE mv.GameID = m.PK_UUID)
+		FROM matches m
+		WHERE m.PK_UUID = ?;
+	`, gameID)
+
+	var finished bool
+	var variant, gameData string
+	var recorded int
+	err := row.Scan(&finished, &variant, &gameData, &recorded)
+	if err == sql.ErrNoRows {
+		return false, false, ErrGameNotFound
+	} else if err != nil {
+		return false, false, err
+	}
+	return finished, reviewable(variant, gameData, recorded), nil
+}
+
+// ReviewRecord returns the record of a game to review
+func (s *Store) ReviewRecord(gameID string) (game.Record, error) {
+	finished, ok, err := s.reviewState(gameID)
+	if err != nil {
+		return game.Record{}, err
+	} else if !finished {
+		return game.Record{}, ErrGameNotFinished
+	} else if !ok {
+		return game.Record{}, ErrNotReviewable
+	}
+	return s.gameRecord(gameID)
+}
+
+// SaveReview stores the annotations of every move of a game, replacing
+// any earlier review
+func (s *Store) SaveReview(gameID string, moves []MoveAnnotation) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM annotations WHERE GameID = ?;`, gameID)
+	if err != nil {
+		return err
+	}
+	for _, m := range moves {
+		pv := []string{}
+		for _, c := range m.PV {
+			pv = append(pv, c.String())
+		}
+		_, err = tx.Exec(`
+			INSERT INTO annotations (GameID, Number, Move, Class, Score, Best, BestScore, PV)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+		`, gameID, m.Number, m.Move.String(), m.Class, m.Score, m.Best.String(), m.BestScore,
+			strings.Join(pv, " "))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GameReview returns the review of a finished game as seen by playerID,
+// who must be allowed to look at it
+func (s *Store) GameReview(gameID, playerID string) (*GameReview, error) {
+	err := s.checkViewer(gameID, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	finished, ok, err := s.reviewState(gameID)
+	if err != nil {
+		return nil, err
+	} else if !finished {
+		return nil, ErrGameNotFinished
+	} else if !ok {
+		return nil, ErrNotReviewable
+	}
+
+	rows, err := s.db.Query(`
+		SELECT Number, Move, Class, Score, Best, BestScore, PV
+		FROM annotations
+		WHERE GameID = ?
+		ORDER BY Number;
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	review := &GameReview{GameID: gameID, Status: ReviewPending, Moves: []MoveAnnotation{}}
+	for rows.Next() {
+		var m MoveAnnotation
+		var move, best, pv string
+		err = rows.Scan(&m.Number, &move, &m.Class, &m.Score, &best, &m.BestScore, &pv)
+		if err != nil {
+			return nil, err
+		}
+
+		m.Move, err = game.ParseCoordinate(move)
+		if err != nil {
+			return nil, err
+		}
+		m.Best, err = game.ParseCoordinate(best)
+		if err != nil {
+			return nil, err
+		}
+		m.PV = []game.Coordinate{}
+		for _, field := range strings.Fields(pv) {
+			c, err := game.ParseCoordinate(field)
+			if err != nil {
+				return nil, err
+			}
+			m.PV = append(m.PV, c)
+		}
+
+		review.Moves = append(review.Moves, m)
+		review.Status = ReviewDone
+	}
+	return review, rows.Err()
+}
@@ -0,0 +1,333 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const initSocial = `
+CREATE TABLE IF NOT EXISTS "friend_requests"
+(
+	[FromID] TEXT NOT NULL,
+	[ToID] TEXT NOT NULL,
+	[Sent] INTEGER NOT NULL,
+	PRIMARY KEY (FromID, ToID),
+	FOREIGN KEY (FromID) REFERENCES "users" (PK_UUID),
+	FOREIGN KEY (ToID) REFERENCES "users" (PK_UUID)
+);
+
+CREATE TABLE IF NOT EXISTS "friends"
+(
+	[UserID] TEXT NOT NULL,
+	[FriendID] TEXT NOT NULL,
+	[Since] INTEGER NOT NULL,
+	PRIMARY KEY (UserID, FriendID),
+	FOREIGN KEY (UserID) REFERENCES "users" (PK_UUID),
+	FOREIGN KEY (FriendID) REFERENCES "users" (PK_UUID)
+);
+
+CREATE TABLE IF NOT EXISTS "blocks"
+(
+	[UserID] TEXT NOT NULL,
+	[BlockedID] TEXT NOT NULL,
+	[Created] INTEGER NOT NULL,
+	PRIMARY KEY (UserID, BlockedID),
+	FOREIGN KEY (UserID) REFERENCES "users" (PK_UUID),
+	FOREIGN KEY (BlockedID) REFERENCES "users" (PK_UUID)
+);
+`
+
+// ErrBlocked is returned when one of two players has blocked the other
+var ErrBlocked = errors.New("player is blocked")
+
+// ErrInvalidFriend is returned when adding or blocking yourself, or a
+// player who does not exist
+var ErrInvalidFriend = errors.New("invalid player")
+
+// ErrNoFriendRequest is returned when accepting or declining a friend
+// request that was never sent
+var ErrNoFriendRequest = errors.New("no friend request from player")
+
+// Friend is another player in someone's friends, requests or block list
+type Friend struct {
+	PlayerID string `json:"playerID"`
+	Username string `json:"username"`
+}
+
+// FriendsList is everyone a player has a relationship with
+type FriendsList struct {
+	Friends []Friend `json:"friends"`
+	// Incoming are friend requests waiting on the player to answer
+	Incoming []Friend `json:"incoming"`
+	// Outgoing are friend requests the player has sent
+	Outgoing []Friend `json:"outgoing"`
+	Blocked  []Friend `json:"blocked"`
+}
+
+// IsBlocked reports whether either player has blocked the other
+func (s *Store) IsBlocked(playerA, playerB string) (bool, error) {
+	row := s.db.QueryRow(`
+		SELECT COUNT(*) FROM blocks
+		WHERE (UserID = ? AND BlockedID = ?) OR (UserID = ? AND BlockedID = ?);
+	`, playerA, playerB, playerB, playerA)
+
+	count := 0
+	err := row.Scan(&count)
+	return count > 0, err
+}
+
+// blockedBetween returns everyone the player has blocked or been
+// blocked by
+func (s *Store) blockedBetween(playerID string) (map[string]bool, error) {
+	rows, err := s.db.Query(`
+		SELECT BlockedID FROM blocks WHERE UserID = ?
+		UNION
+		SELECT UserID FROM blocks WHERE BlockedID = ?;
+	`, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := map[string]bool{}
+	for rows.Next() {
+		other := ""
+		if err = rows.Scan(&other); err != nil {
+			return nil, err
+		}
+		blocked[other] = true
+	}
+
+	return blocked, rows.Err()
+}
+
+func (s *Store) checkFriendable(playerID, otherID string) error {
+	if playerID == otherID {
+		return ErrInvalidFriend
+	}
+
+	other, err := s.TryLookupPlayerUUID(otherID)
+	if err != nil {
+		return err
+	} else if other == nil {
+		return ErrInvalidFriend
+	}
+
+	blocked, err := s.IsBlocked(playerID, otherID)
+	if err != nil {
+		return err
+	} else if blocked {
+		return ErrBlocked
+	}
+
+	return nil
+}
+
+// SendFriendRequest asks otherID to be playerID's friend. If otherID
+// has already asked playerID, they become friends straight away
+func (s *Store) SendFriendRequest(playerID, otherID string) error {
+	err := s.checkFriendable(playerID, otherID)
+	if err != nil {
+		return err
+	}
+
+	err = s.AcceptFriendRequest(playerID, otherID)
+	if err != ErrNoFriendRequest {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT OR IGNORE INTO friend_requests(FromID, ToID, Sent)
+		SELECT ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM friends WHERE UserID = ? AND FriendID = ?);
+	`, playerID, otherID, time.Now().Unix(), playerID, otherID)
+	return err
+}
+
+// AcceptFriendRequest makes two players friends, if fromID has sent
+// playerID a friend request
+func (s *Store) AcceptFriendRequest(playerID, fromID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		DELETE FROM friend_requests WHERE FromID = ? AND ToID = ?;
+	`, fromID, playerID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNoFriendRequest
+	}
+
+	now := time.Now().Unix()
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO friends(UserID, FriendID, Since)
+		VALUES(?, ?, ?), (?, ?, ?);
+	`, playerID, fromID, now, fromID, playerID, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeclineFriendRequest removes a friend request fromID sent playerID
+func (s *Store) DeclineFriendRequest(playerID, fromID string) error {
+	result, err := s.db.Exec(`
+		DELETE FROM friend_requests WHERE FromID = ? AND ToID = ?;
+	`, fromID, playerID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNoFriendRequest
+	}
+	return nil
+}
+
+// RemoveFriend ends a friendship, or withdraws a friend request
+func (s *Store) RemoveFriend(playerID, friendID string) error {
+	return s.unfriend(s.db, playerID, friendID)
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *Store) unfriend(db execer, playerID, otherID string) error {
+	_, err := db.Exec(`
+		DELETE FROM friends
+		WHERE (UserID = ? AND FriendID = ?) OR (UserID = ? AND FriendID = ?);
+	`, playerID, otherID, otherID, playerID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		DELETE FROM friend_requests
+		WHERE (FromID = ? AND ToID = ?) OR (FromID = ? AND ToID = ?);
+	`, playerID, otherID, otherID, playerID)
+	return err
+}
+
+// BlockPlayer blocks otherID from challenging, chatting with or
+// spectating playerID. Any friendship or friend request between them
+// is removed
+func (s *Store) BlockPlayer(playerID, otherID string) error {
+	if playerID == otherID {
+		return ErrInvalidFriend
+	}
+
+	other, err := s.TryLookupPlayerUUID(otherID)
+	if err != nil {
+		return err
+	} else if other == nil {
+		return ErrInvalidFriend
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = s.unfriend(tx, playerID, otherID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO blocks(UserID, BlockedID, Created) VALUES(?, ?, ?);
+	`, playerID, otherID, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnblockPlayer lifts a block
+func (s *Store) UnblockPlayer(playerID, otherID string) error {
+	_, err := s.db.Exec(`
+		DELETE FROM blocks WHERE UserID = ? AND BlockedID = ?;
+	`, playerID, otherID)
+	return err
+}
+
+func (s *Store) queryFriends(query string, playerID string) ([]Friend, error) {
+	rows, err := s.db.Query(query, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	friends := []Friend{}
+	for rows.Next() {
+		f := Friend{}
+		if err = rows.Scan(&f.PlayerID, &f.Username); err != nil {
+			return nil, err
+		}
+		friends = append(friends, f)
+	}
+
+	return friends, rows.Err()
+}
+
+// Friends returns a player's friends, ordered by username
+func (s *Store) Friends(playerID string) ([]Friend, error) {
+	return s.queryFriends(`
+		SELECT u.PK_UUID, u.Username FROM friends f
+		JOIN users u ON u.PK_UUID = f.FriendID
+		WHERE f.UserID = ? ORDER BY u.Username;
+	`, playerID)
+}
+
+// FriendsList returns a player's friends, friend requests and blocks
+func (s *Store) FriendsList(playerID string) (*FriendsList, error) {
+	var err error
+	list := &FriendsList{}
+
+	list.Friends, err = s.Friends(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Incoming, err = s.queryFriends(`
+		SELECT u.PK_UUID, u.Username FROM friend_requests r
+		JOIN users u ON u.PK_UUID = r.FromID
+		WHERE r.ToID = ? ORDER BY r.Sent;
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Outgoing, err = s.queryFriends(`
+		SELECT u.PK_UUID, u.Username FROM friend_requests r
+		JOIN users u ON u.PK_UUID = r.ToID
+		WHERE r.FromID = ? ORDER BY r.Sent;
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Blocked, err = s.queryFriends(`
+		SELECT u.PK_UUID, u.Username FROM blocks b
+		JOIN users u ON u.PK_UUID = b.BlockedID
+		WHERE b.UserID = ? ORDER BY u.Username;
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,142 @@
+package store
+
+import "testing"
+
+func TestBlockUnknownPlayer(t *testing.T) {
+	games := newTestService(t)
+	alice := newPlayers(t, games, "alice")[0]
+
+	for _, otherID := range []string{alice, "nobody"} {
+		if err := games.BlockPlayer(alice, otherID); err != ErrInvalidFriend {
+			t.Errorf("blocking %q got %v, want %v", otherID, err, ErrInvalidFriend)
+		}
+		if blocked, _ := games.IsBlocked(alice, otherID); blocked {
+			t.Errorf("%q was blocked", otherID)
+		}
+	}
+}
+
+func TestBlockChallenge(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+
+	err := games.BlockPlayer(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, players := range [][2]string{{alice, bob}, {bob, alice}} {
+		if _, err := games.NewGame(players[0], players[1], ColorX, GameSettings{}); err != ErrBlocked {
+			t.Errorf("challenge got %v, want %v", err, ErrBlocked)
+		}
+	}
+
+	err = games.UnblockPlayer(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.NewGame(alice, bob, ColorX, GameSettings{}); err != nil {
+		t.Errorf("challenge after unblocking got %v", err)
+	}
+}
+
+func TestBlockChat(t *testing.T) {
+	games := newTestService(t)
+	chat := NewChatService(games)
+	ids := newPlayers(t, games, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	gameID, err := games.StartGame(alice, bob, GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = chat.Send(LobbyRoom, "", alice, "before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = games.BlockPlayer(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bob no longer sees alice's messages, old or new
+	bobCh, carolCh := make(chan ChatMessage, 10), make(chan ChatMessage, 10)
+	history, err := chat.Join(LobbyRoom, "", bob, bobCh)
+	if err != nil || len(history) != 0 {
+		t.Errorf("bob got history %v, %v", history, err)
+	}
+	history, err = chat.Join(LobbyRoom, "", carol, carolCh)
+	if err != nil || len(history) != 1 {
+		t.Errorf("carol got history %v, %v", history, err)
+	}
+	err = chat.Send(LobbyRoom, "", alice, "after")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bobCh) != 0 || len(carolCh) != 1 {
+		t.Errorf("bob got %d messages and carol %d, want 0 and 1", len(bobCh), len(carolCh))
+	}
+
+	// and the players of their game can't talk in it
+	if _, err := chat.Join(GameRoom, gameID, alice, make(chan ChatMessage, 1)); err != ErrBlocked {
+		t.Errorf("joining the game room got %v, want %v", err, ErrBlocked)
+	}
+	if err := chat.Send(GameRoom, gameID, bob, "hi"); err != ErrBlocked {
+		t.Errorf("sending to the game room got %v, want %v", err, ErrBlocked)
+	}
+}
+
+func TestBlockSpectating(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	gameID, err := games.StartGame(alice, bob, GameSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// whichever of them blocked the other
+	err = games.BlockPlayer(carol, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.SpectateGame(gameID, carol); err != ErrBlocked {
+		t.Errorf("spectating got %v, want %v", err, ErrBlocked)
+	}
+	if _, err := games.GameState(gameID, carol); err != ErrBlocked {
+		t.Errorf("looking at the game got %v, want %v", err, ErrBlocked)
+	}
+
+	dave := newPlayers(t, games, "dave")[0]
+	err = games.BlockPlayer(alice, dave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.SpectateGame(gameID, dave); err != ErrBlocked {
+		t.Errorf("spectating got %v, want %v", err, ErrBlocked)
+	}
+}
+
+func TestBlockRematch(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+	gameID := playGame(t, games, alice, bob, GameSettings{}, "x")
+
+	err := games.BlockPlayer(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = games.withGame(gameID, func(g *Game) error {
+		for _, playerID := range []string{alice, bob} {
+			if _, err := games.RequestRematch(g, playerID); err != ErrBlocked {
+				t.Errorf("rematch got %v, want %v", err, ErrBlocked)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
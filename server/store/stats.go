@@ -0,0 +1,334 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+// leaderboardTTL is how long a leaderboard page is served from cache
+// before it is queried again
+const leaderboardTTL = 1 * time.Minute
+
+// MaxLeaderboardLimit is the largest page of a leaderboard that can
+// be requested at once
+const MaxLeaderboardLimit = 100
+
+// DefaultLeaderboardLimit is the page size used when none is given
+const DefaultLeaderboardLimit = 20
+
+// Leaderboard orderings
+const (
+	LeaderboardByRating = "rating"
+	LeaderboardByWins   = "wins"
+)
+
+// ErrInvalidLeaderboard is returned for an unknown leaderboard ordering,
+// a missing pool or a bad page
+var ErrInvalidLeaderboard = errors.New("invalid leaderboard request")
+
+// SideStats are a player's results when playing one side
+type SideStats struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	Ties    int     `json:"ties"`
+	WinRate float64 `json:"winRate"`
+}
+
+func (ss *SideStats) add(playerID, victor string) {
+	ss.Games++
+	switch victor {
+	case playerID:
+		ss.Wins++
+	case game.StalematePlayer:
+		ss.Ties++
+	default:
+		ss.Losses++
+	}
+	ss.WinRate = float64(ss.Wins) / float64(ss.Games)
+}
+
+// PlayerStats summarize every finished game a player has played
+type PlayerStats struct {
+	PlayerID string `json:"playerID"`
+	SideStats
+
+	AsX SideStats `json:"asX"`
+	AsO SideStats `json:"asO"`
+
+	LongestWinStreak int `json:"longestWinStreak"`
+	CurrentWinStreak int `json:"currentWinStreak"`
+
+	// AverageMoves is the average number of moves (by both players)
+	// in the player's finished games
+	AverageMoves float64 `json:"averageMoves"`
+}
+
+// HeadToHead is a player's record against one opponent
+type HeadToHead struct {
+	PlayerID   string `json:"playerID"`
+	OpponentID string `json:"opponentID"`
+	SideStats
+}
+
+// LeaderboardEntry is one player's row on a leaderboard. Rating is only
+// set on rating leaderboards
+type LeaderboardEntry struct {
+	Rank     int      `json:"rank"`
+	PlayerID string   `json:"playerID"`
+	Username string   `json:"username"`
+	Rating   *float64 `json:"rating,omitempty"`
+	Wins     int      `json:"wins"`
+	Games    int      `json:"games"`
+}
+
+// LeaderboardPage is one page of a leaderboard
+type LeaderboardPage struct {
+	By      string             `json:"by"`
+	Pool    string             `json:"pool,omitempty"`
+	Offset  int                `json:"offset"`
+	Limit   int                `json:"limit"`
+	Total   int                `json:"total"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// moveCount counts the moves played in a saved game
+func moveCount(gameData string) int {
+	return strings.Count(gameData, "X") + strings.Count(gameData, "O")
+}
+
+// PlayerStats computes a player's statistics from their finished games
+func (s *Store) PlayerStats(playerID string) (*PlayerStats, error) {
+	rows, err := s.db.Query(`
+		SELECT UserX, Victor, GameData FROM matches
+		WHERE Finished AND (UserX = ? OR UserO = ?)
+		ORDER BY Created, rowid;
+	`, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &PlayerStats{PlayerID: playerID}
+	totalMoves := 0
+	for rows.Next() {
+		var playerX, victor, gameData string
+		err = rows.Scan(&playerX, &victor, &gameData)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.add(playerID, victor)
+		if playerX == playerID {
+			stats.AsX.add(playerID, victor)
+		} else {
+			stats.AsO.add(playerID, victor)
+		}
+
+		if victor == playerID {
+			stats.CurrentWinStreak++
+			if stats.CurrentWinStreak > stats.LongestWinStreak {
+				stats.LongestWinStreak = stats.CurrentWinStreak
+			}
+		} else {
+			stats.CurrentWinStreak = 0
+		}
+
+		totalMoves += moveCount(gameData)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if stats.Games > 0 {
+		stats.AverageMoves = float64(totalMoves) / float64(stats.Games)
+	}
+
+	return stats, nil
+}
+
+// HeadToHead returns a player's record in finished games against
+// one opponent
+func (s *Store) HeadToHead(playerID, opponentID string) (*HeadToHead, error) {
+	rows, err := s.db.Query(`
+		SELECT Victor FROM matches
+		WHERE Finished AND
+			((UserX = ? AND UserO = ?) OR (UserX = ? AND UserO = ?));
+	`, playerID, opponentID, opponentID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	h2h := &HeadToHead{PlayerID: playerID, OpponentID: opponentID}
+	for rows.Next() {
+		var victor string
+		if err = rows.Scan(&victor); err != nil {
+			return nil, err
+		}
+		h2h.add(playerID, victor)
+	}
+
+	return h2h, rows.Err()
+}
+
+func (s *Store) ratingLeaderboard(pool string, offset, limit int) (*LeaderboardPage, error) {
+	page := &LeaderboardPage{
+		By:      LeaderboardByRating,
+		Pool:    pool,
+		Offset:  offset,
+		Limit:   limit,
+		Entries: []LeaderboardEntry{},
+	}
+
+	row := s.db.QueryRow(`SELECT COUNT(*) FROM ratings WHERE Pool = ?;`, pool)
+	if err := row.Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT r.UserID, u.Username, r.Rating, r.Games
+		FROM ratings r JOIN users u ON r.UserID = u.PK_UUID
+		WHERE r.Pool = ?
+		ORDER BY r.Rating DESC, r.Games DESC
+		LIMIT ? OFFSET ?;
+	`, pool, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rating float64
+		entry := LeaderboardEntry{Rank: offset + len(page.Entries) + 1}
+		err = rows.Scan(&entry.PlayerID, &entry.Username, &rating, &entry.Games)
+		if err != nil {
+			return nil, err
+		}
+		entry.Rating = &rating
+		page.Entries = append(page.Entries, entry)
+	}
+
+	return page, rows.Err()
+}
+
+func (s *Store) winsLeaderboard(offset, limit int) (*LeaderboardPage, error) {
+	page := &LeaderboardPage{
+		By:      LeaderboardByWins,
+		Offset:  offset,
+		Limit:   limit,
+		Entries: []LeaderboardEntry{},
+	}
+
+	row := s.db.QueryRow(`
+		SELECT COUNT(DISTINCT Victor) FROM matches
+		WHERE Finished AND Victor != ?;
+	`, game.StalematePlayer)
+	if err := row.Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT u.PK_UUID, u.Username,
+			(SELECT COUNT(*) FROM matches m
+				WHERE m.Finished AND m.Victor = u.PK_UUID) AS Wins,
+			(SELECT COUNT(*) FROM matches m
+				WHERE m.Finished AND (m.UserX = u.PK_UUID OR m.UserO = u.PK_UUID)) AS Games
+		FROM users u
+		WHERE Wins > 0 AND u.PK_UUID != ?
+		ORDER BY Wins DESC, Games ASC
+		LIMIT ? OFFSET ?;
+	`, game.StalematePlayer, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := LeaderboardEntry{Rank: offset + len(page.Entries) + 1}
+		err = rows.Scan(&entry.PlayerID, &entry.Username, &entry.Wins, &entry.Games)
+		if err != nil {
+			return nil, err
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+
+	return page, rows.Err()
+}
+
+type leaderboardKey struct {
+	by, pool      string
+	offset, limit int
+}
+
+type cachedLeaderboard struct {
+	page    *LeaderboardPage
+	expires time.Time
+}
+
+// leaderboardCache keeps recently requested leaderboard pages around
+// so that repeated requests don't rerun the aggregate queries
+type leaderboardCache struct {
+	mutex sync.Mutex
+	pages map[leaderboardKey]cachedLeaderboard
+}
+
+func newLeaderboardCache() *leaderboardCache {
+	return &leaderboardCache{
+		pages: map[leaderboardKey]cachedLeaderboard{},
+	}
+}
+
+// Leaderboard returns a page of the leaderboard ordered by rating in
+// the given pool, or by total wins. limit is capped at
+// MaxLeaderboardLimit, and 0 means DefaultLeaderboardLimit
+func (s *GameService) Leaderboard(by, pool string, offset, limit int) (*LeaderboardPage, error) {
+	if limit == 0 {
+		limit = DefaultLeaderboardLimit
+	}
+	if offset < 0 || limit < 0 || limit > MaxLeaderboardLimit {
+		return nil, ErrInvalidLeaderboard
+	}
+	if by == LeaderboardByWins {
+		pool = ""
+	} else if by != LeaderboardByRating || pool == "" {
+		return nil, ErrInvalidLeaderboard
+	}
+
+	key := leaderboardKey{by, pool, offset, limit}
+	now := time.Now()
+
+	s.leaderboards.mutex.Lock()
+	cached, ok := s.leaderboards.pages[key]
+	s.leaderboards.mutex.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.page, nil
+	}
+
+	var page *LeaderboardPage
+	var err error
+	if by == LeaderboardByWins {
+		page, err = s.Store.winsLeaderboard(offset, limit)
+	} else {
+		page, err = s.Store.ratingLeaderboard(pool, offset, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.leaderboards.mutex.Lock()
+	defer s.leaderboards.mutex.Unlock()
+	for k, v := range s.leaderboards.pages {
+		// drop expired pages so the cache doesn't grow without bound
+		if now.After(v.expires) {
+			delete(s.leaderboards.pages, k)
+		}
+	}
+	s.leaderboards.pages[key] = cachedLeaderboard{page, now.Add(leaderboardTTL)}
+
+	return page, nil
+}
@@ -0,0 +1,267 @@
+package store
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/heartles/uttt/server/game"
+)
+
+func TestPlayerStats(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+
+	moves := 0
+	for _, g := range []struct {
+		playerX, playerO, winner string
+	}{
+		{alice, bob, "x"},                  // win as X
+		{bob, alice, "o"},                  // win as O
+		{alice, bob, "x"},                  // win as X, a streak of 3
+		{bob, alice, "x"},                  // loss as O
+		{alice, bob, game.StalematePlayer}, // tie as X
+		{bob, alice, "o"},                  // win as O
+	} {
+		playGame(t, games, g.playerX, g.playerO, GameSettings{Casual: true}, g.winner)
+		moves += len(finishedGame(g.winner))
+	}
+	// neither unfinished games nor other players' games count
+	if _, err := games.StartGame(alice, bob, GameSettings{}); err != nil {
+		t.Fatal(err)
+	}
+	playGame(t, games, bob, carol, GameSettings{Casual: true}, "x")
+
+	stats, err := games.PlayerStats(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := SideStats{Games: 6, Wins: 4, Losses: 1, Ties: 1, WinRate: 4.0 / 6}
+	if stats.SideStats != want {
+		t.Errorf("got %+v, want %+v", stats.SideStats, want)
+	}
+	want = SideStats{Games: 3, Wins: 2, Ties: 1, WinRate: 2.0 / 3}
+	if stats.AsX != want {
+		t.Errorf("got %+v as X, want %+v", stats.AsX, want)
+	}
+	want = SideStats{Games: 3, Wins: 2, Losses: 1, WinRate: 2.0 / 3}
+	if stats.AsO != want {
+		t.Errorf("got %+v as O, want %+v", stats.AsO, want)
+	}
+
+	if stats.LongestWinStreak != 3 || stats.CurrentWinStreak != 1 {
+		t.Errorf("got streaks of %v and %v now, want 3 and 1", stats.LongestWinStreak, stats.CurrentWinStreak)
+	}
+	if avg := float64(moves) / 6; math.Abs(stats.AverageMoves-avg) > 1e-9 {
+		t.Errorf("got %v average moves, want %v", stats.AverageMoves, avg)
+	}
+
+	stats, err = games.PlayerStats(newPlayers(t, games, "dave")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games != 0 || stats.AverageMoves != 0 || stats.WinRate != 0 {
+		t.Errorf("got %+v with no games", stats)
+	}
+}
+
+func TestHeadToHead(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+
+	playGame(t, games, alice, bob, GameSettings{Casual: true}, "x")
+	playGame(t, games, bob, alice, GameSettings{Casual: true}, "x")
+	playGame(t, games, bob, alice, GameSettings{Casual: true}, game.StalematePlayer)
+	playGame(t, games, alice, bob, GameSettings{Casual: true}, "x")
+	playGame(t, games, alice, carol, GameSettings{Casual: true}, "o")
+
+	h2h, err := games.HeadToHead(alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SideStats{Games: 4, Wins: 2, Losses: 1, Ties: 1, WinRate: 0.5}
+	if h2h.PlayerID != alice || h2h.OpponentID != bob || h2h.SideStats != want {
+		t.Errorf("got %+v, want %+v", h2h, want)
+	}
+
+	h2h, err = games.HeadToHead(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = SideStats{Games: 4, Wins: 1, Losses: 2, Ties: 1, WinRate: 0.25}
+	if h2h.SideStats != want {
+		t.Errorf("got %+v for bob, want %+v", h2h.SideStats, want)
+	}
+}
+
+func TestWinsLeaderboard(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob", "carol", "dave")
+	alice, bob, carol, dave := ids[0], ids[1], ids[2], ids[3]
+
+	playGame(t, games, alice, dave, GameSettings{Casual: true}, "x")
+	playGame(t, games, alice, dave, GameSettings{Casual: true}, "x")
+	playGame(t, games, dave, alice, GameSettings{Casual: true}, "o")
+	playGame(t, games, bob, dave, GameSettings{Casual: true}, "x")
+	playGame(t, games, bob, dave, GameSettings{Casual: true}, "x")
+	playGame(t, games, carol, dave, GameSettings{Casual: true}, "x")
+	// carol has played more games for her one win than bob for his two
+	playGame(t, games, carol, dave, GameSettings{Casual: true}, game.StalematePlayer)
+
+	page, err := games.Leaderboard(LeaderboardByWins, "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.Limit != DefaultLeaderboardLimit || len(page.Entries) != 3 {
+		t.Fatalf("got %+v", page)
+	}
+	for i, want := range []struct {
+		playerID, username string
+		wins, games        int
+	}{
+		{alice, "alice", 3, 3},
+		{bob, "bob", 2, 2},
+		{carol, "carol", 1, 2},
+	} {
+		entry := page.Entries[i]
+		if entry.Rank != i+1 || entry.PlayerID != want.playerID || entry.Username != want.username ||
+			entry.Wins != want.wins || entry.Games != want.games || entry.Rating != nil {
+			t.Errorf("got %+v at %d, want %+v", entry, i, want)
+		}
+	}
+
+	// pages go on ranking from their offset, and count every winner
+	page, err = games.Leaderboard(LeaderboardByWins, "pool is ignored", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.Offset != 1 || page.Pool != "" || len(page.Entries) != 1 {
+		t.Fatalf("got %+v", page)
+	}
+	if entry := page.Entries[0]; entry.Rank != 2 || entry.PlayerID != bob {
+		t.Errorf("got %+v, want bob ranked 2nd", entry)
+	}
+
+	page, err = games.Leaderboard(LeaderboardByWins, "", 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.Entries == nil || len(page.Entries) != 0 {
+		t.Errorf("got %+v past the end", page)
+	}
+}
+
+func TestRatingLeaderboard(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob", "carol")
+	alice, bob, carol := ids[0], ids[1], ids[2]
+
+	pool := Pool(GameSettings{})
+	playGame(t, games, alice, bob, GameSettings{}, "x")
+	playGame(t, games, alice, bob, GameSettings{}, "x")
+	// casual games and other pools aren't on the leaderboard
+	playGame(t, games, carol, alice, GameSettings{Casual: true}, "x")
+	playGame(t, games, carol, bob, GameSettings{TimeControl: "5+0"}, "x")
+
+	page, err := games.Leaderboard(LeaderboardByRating, pool, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || page.Pool != pool || len(page.Entries) != 2 {
+		t.Fatalf("got %+v", page)
+	}
+	for i, playerID := range []string{alice, bob} {
+		entry := page.Entries[i]
+		r, err := games.Rating(playerID, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Rank != i+1 || entry.PlayerID != playerID || entry.Rating == nil ||
+			*entry.Rating != r.Rating || entry.Games != 2 {
+			t.Errorf("got %+v at %d, want %v rated %v", entry, i, playerID, r.Rating)
+		}
+	}
+
+	page, err = games.Leaderboard(LeaderboardByRating, pool, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Entries) != 1 || page.Entries[0].Rank != 2 || page.Entries[0].PlayerID != bob {
+		t.Errorf("got %+v", page)
+	}
+}
+
+func TestLeaderboardInvalid(t *testing.T) {
+	games := newTestService(t)
+
+	for _, req := range []struct {
+		by, pool      string
+		offset, limit int
+	}{
+		{LeaderboardByWins, "", 0, -1},
+		{LeaderboardByWins, "", 0, MaxLeaderboardLimit + 1},
+		{LeaderboardByWins, "", -1, 10},
+		{LeaderboardByRating, "", 0, 10},
+		{"losses", "", 0, 10},
+		{"", "", 0, 10},
+	} {
+		if _, err := games.Leaderboard(req.by, req.pool, req.offset, req.limit); err != ErrInvalidLeaderboard {
+			t.Errorf("%+v: got %v, want %v", req, err, ErrInvalidLeaderboard)
+		}
+	}
+
+	page, err := games.Leaderboard(LeaderboardByWins, "", 0, MaxLeaderboardLimit)
+	if err != nil || page.Limit != MaxLeaderboardLimit {
+		t.Errorf("got %+v, %v with the largest limit", page, err)
+	}
+}
+
+func TestLeaderboardCache(t *testing.T) {
+	games := newTestService(t)
+	ids := newPlayers(t, games, "alice", "bob")
+	alice, bob := ids[0], ids[1]
+
+	playGame(t, games, alice, bob, GameSettings{Casual: true}, "x")
+	page, err := games.Leaderboard(LeaderboardByWins, "", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// until the page expires, it is served as it was
+	playGame(t, games, bob, alice, GameSettings{Casual: true}, "x")
+	cached, err := games.Leaderboard(LeaderboardByWins, "", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != page || cached.Total != 1 {
+		t.Errorf("got %+v, want the cached page", cached)
+	}
+
+	// other pages aren't cached yet
+	other, err := games.Leaderboard(LeaderboardByWins, "", 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Total != 2 {
+		t.Errorf("got %+v for an uncached page", other)
+	}
+
+	key := leaderboardKey{LeaderboardByWins, "", 0, 10}
+	games.leaderboards.mutex.Lock()
+	if expires := games.leaderboards.pages[key].expires; time.Until(expires) > leaderboardTTL {
+		t.Errorf("page expires in %v, after %v", time.Until(expires), leaderboardTTL)
+	}
+	games.leaderboards.pages[key] = cachedLeaderboard{page, time.Now().Add(-time.Second)}
+	games.leaderboards.mutex.Unlock()
+
+	fresh, err := games.Leaderboard(LeaderboardByWins, "", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh == page || fresh.Total != 2 {
+		t.Errorf("got %+v after the page expired", fresh)
+	}
+}
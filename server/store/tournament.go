@@ -0,0 +1,260 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const initTournaments = `
+CREATE TABLE IF NOT EXISTS "tournaments"
+(
+	[PK_UUID] CHAR(36) UNIQUE PRIMARY KEY,
+	[Name] TEXT NOT NULL,
+	[Format] TEXT NOT NULL,
+	[CreatedBy] TEXT NOT NULL,
+	[Status] TEXT NOT NULL,
+	[Variant] TEXT NOT NULL,
+	[TimeControl] TEXT NOT NULL,
+	[Casual] BOOLEAN NOT NULL,
+	[Rounds] INTEGER NOT NULL,
+	[CurrentRound] INTEGER NOT NULL,
+	[Created] INTEGER NOT NULL,
+	FOREIGN KEY (CreatedBy) REFERENCES "users" (PK_UUID)
+);
+
+CREATE TABLE IF NOT EXISTS "tournament_players"
+(
+	[TournamentID] CHAR(36) NOT NULL,
+	[UserID] TEXT NOT NULL,
+	[Seed] INTEGER NOT NULL,
+	PRIMARY KEY (TournamentID, UserID),
+	FOREIGN KEY (TournamentID) REFERENCES "tournaments" (PK_UUID),
+	FOREIGN KEY (UserID) REFERENCES "users" (PK_UUID)
+);
+
+CREATE TABLE IF NOT EXISTS "tournament_games"
+(
+	[TournamentID] CHAR(36) NOT NULL,
+	[Round] INTEGER NOT NULL,
+	[Board] INTEGER NOT NULL,
+	[GameID] CHAR(36) UNIQUE,
+	[PlayerX] TEXT NOT NULL,
+	[PlayerO] TEXT,
+	[Victor] TEXT,
+	FOREIGN KEY (TournamentID) REFERENCES "tournaments" (PK_UUID),
+	FOREIGN KEY (GameID) REFERENCES "matches" (PK_UUID)
+);
+
+CREATE INDEX IF NOT EXISTS tournament_games_tournament ON tournament_games (TournamentID, Round);
+`
+
+// Tournament statuses
+const (
+	TournamentRegistering = "registering"
+	TournamentRunning     = "running"
+	TournamentFinished    = "finished"
+)
+
+// Tournament is the persisted state of a tournament
+type Tournament struct {
+	ID        string       `json:"tournamentID"`
+	Name      string       `json:"name"`
+	Format    string       `json:"format"`
+	CreatedBy string       `json:"createdBy"`
+	Status    string       `json:"status"`
+	Settings  GameSettings `json:"settings"`
+
+	// Rounds is the number of rounds that will be played. It is only
+	// known ahead of time for Swiss tournaments, and is filled in for
+	// the other formats when the tournament starts
+	Rounds       int `json:"rounds"`
+	CurrentRound int `json:"currentRound"`
+}
+
+// TournamentGame is one pairing in a tournament round. A bye has no
+// GameID or PlayerO, and PlayerX as its Victor
+type TournamentGame struct {
+	Round   int    `json:"round"`
+	Board   int    `json:"board"`
+	GameID  string `json:"gameID,omitempty"`
+	PlayerX string `json:"playerX"`
+	PlayerO string `json:"playerO,omitempty"`
+
+	// Victor is empty until the game finishes, and is
+	// game.StalematePlayer for a tie
+	Victor string `json:"victor,omitempty"`
+}
+
+// CreateTournament saves a new tournament and returns its ID
+func (s *Store) CreateTournament(t *Tournament) (string, error) {
+	settings, err := t.Settings.normalize()
+	if err != nil {
+		return "", err
+	}
+	t.Settings = settings
+
+	id := uuid.New().String()
+	_, err = s.db.Exec(`
+		INSERT INTO tournaments(
+			PK_UUID, Name, Format, CreatedBy, Status, Variant, TimeControl,
+			Casual, Rounds, CurrentRound, Created)
+		VALUES(?,?,?,?,?,?,?,?,?,?,?);
+	`, id, t.Name, t.Format, t.CreatedBy, t.Status, t.Settings.Variant,
+		t.Settings.TimeControl, t.Settings.Casual, t.Rounds, t.CurrentRound,
+		time.Now().Unix())
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// TryLookupTournament returns the tournament with the given ID, or nil
+// if it does not exist
+func (s *Store) TryLookupTournament(id string) (*Tournament, error) {
+	row := s.db.QueryRow(`
+		SELECT Name, Format, CreatedBy, Status, Variant, TimeControl, Casual,
+			Rounds, CurrentRound
+		FROM tournaments WHERE PK_UUID = ?;
+	`, id)
+
+	t := Tournament{ID: id}
+	err := row.Scan(&t.Name, &t.Format, &t.CreatedBy, &t.Status,
+		&t.Settings.Variant, &t.Settings.TimeControl, &t.Settings.Casual,
+		&t.Rounds, &t.CurrentRound)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// UpdateTournament saves a tournament's status and round counters
+func (s *Store) UpdateTournament(t *Tournament) error {
+	_, err := s.db.Exec(`
+		UPDATE tournaments SET Status = ?, Rounds = ?, CurrentRound = ?
+		WHERE PK_UUID = ?;
+	`, t.Status, t.Rounds, t.CurrentRound, t.ID)
+	return err
+}
+
+// AddTournamentPlayer registers a player in a tournament. Registering
+// twice has no effect
+func (s *Store) AddTournamentPlayer(tournamentID, playerID string) error {
+	_, err := s.db.Exec(`
+		INSERT OR IGNORE INTO tournament_players(TournamentID, UserID, Seed)
+		VALUES(?, ?, (SELECT COUNT(*) + 1 FROM tournament_players WHERE TournamentID = ?));
+	`, tournamentID, playerID, tournamentID)
+	return err
+}
+
+// SetTournamentSeeds reorders a tournament's players. seeded lists
+// every registered player, top seed first
+func (s *Store) SetTournamentSeeds(tournamentID string, seeded []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, playerID := range seeded {
+		_, err = tx.Exec(`
+			UPDATE tournament_players SET Seed = ?
+			WHERE TournamentID = ? AND UserID = ?;
+		`, i+1, tournamentID, playerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// TournamentPlayers returns the players registered in a tournament,
+// top seed first
+func (s *Store) TournamentPlayers(tournamentID string) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT UserID FROM tournament_players
+		WHERE TournamentID = ? ORDER BY Seed;
+	`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []string{}
+	for rows.Next() {
+		playerID := ""
+		if err = rows.Scan(&playerID); err != nil {
+			return nil, err
+		}
+		players = append(players, playerID)
+	}
+
+	return players, rows.Err()
+}
+
+// AddTournamentGame records a pairing in a tournament round
+func (s *Store) AddTournamentGame(tournamentID string, tg TournamentGame) error {
+	_, err := s.db.Exec(`
+		INSERT INTO tournament_games(TournamentID, Round, Board, GameID, PlayerX, PlayerO, Victor)
+		VALUES(?,?,?,?,?,?,?);
+	`, tournamentID, tg.Round, tg.Board, nullable(tg.GameID), tg.PlayerX,
+		nullable(tg.PlayerO), nullable(tg.Victor))
+	return err
+}
+
+// TournamentGames returns every pairing in a tournament, in round and
+// board order
+func (s *Store) TournamentGames(tournamentID string) ([]TournamentGame, error) {
+	rows, err := s.db.Query(`
+		SELECT Round, Board, IFNULL(GameID, ""), PlayerX, IFNULL(PlayerO, ""), IFNULL(Victor, "")
+		FROM tournament_games
+		WHERE TournamentID = ? ORDER BY Round, Board, rowid;
+	`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := []TournamentGame{}
+	for rows.Next() {
+		tg := TournamentGame{}
+		err = rows.Scan(&tg.Round, &tg.Board, &tg.GameID, &tg.PlayerX, &tg.PlayerO, &tg.Victor)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, tg)
+	}
+
+	return games, rows.Err()
+}
+
+// SetTournamentGameResult records the victor of a tournament game. It
+// returns the tournament the game belongs to, or "" if it isn't part
+// of one
+func (s *Store) SetTournamentGameResult(gameID, victor string) (string, error) {
+	row := s.db.QueryRow(`SELECT TournamentID FROM tournament_games WHERE GameID = ?;`, gameID)
+
+	tournamentID := ""
+	err := row.Scan(&tournamentID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec(`UPDATE tournament_games SET Victor = ? WHERE GameID = ?;`, victor, gameID)
+	return tournamentID, err
+}
+
+func nullable(str string) *string {
+	if str == "" {
+		return nil
+	}
+	return &str
+}
@@ -0,0 +1,340 @@
+package tournament
+
+import (
+	"sort"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// Pairing is one game in a round. If O is empty, X has a bye
+type Pairing struct {
+	X string
+	O string
+}
+
+// Standing is a player's position in a tournament
+type Standing struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"playerID"`
+	Seed     int     `json:"seed"`
+	Score    float64 `json:"score"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	Ties     int     `json:"ties"`
+	Byes     int     `json:"byes"`
+
+	// Buchholz is the sum of the scores of every opponent played
+	Buchholz float64 `json:"buchholz"`
+	// SonnebornBerger is the sum of the scores of every opponent beaten,
+	// plus half the scores of every opponent tied with
+	SonnebornBerger float64 `json:"sonnebornBerger"`
+
+	// EliminatedRound is the knockout round the player was knocked
+	// out in, or 0 if they haven't been
+	EliminatedRound int `json:"eliminatedRound,omitempty"`
+
+	opponents []string
+	xGames    int
+	oGames    int
+}
+
+func (st *Standing) hasPlayed(playerID string) bool {
+	for _, o := range st.opponents {
+		if o == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeStandings ranks the players (given in seed order) by their
+// results in the finished games. Knockout players are ranked by how
+// long they survived first; everyone else by score, then Buchholz,
+// then Sonneborn-Berger, then seed
+func ComputeStandings(format string, players []string, games []store.TournamentGame) []Standing {
+	byID := map[string]*Standing{}
+	standings := make([]*Standing, len(players))
+	for i, p := range players {
+		standings[i] = &Standing{PlayerID: p, Seed: i + 1}
+		byID[p] = standings[i]
+	}
+
+	finished := []store.TournamentGame{}
+	for _, g := range games {
+		x, ok := byID[g.PlayerX]
+		if !ok || g.Victor == "" {
+			continue
+		}
+
+		if g.PlayerO == "" {
+			x.Byes++
+			x.Score++
+			continue
+		}
+
+		o, ok := byID[g.PlayerO]
+		if !ok {
+			continue
+		}
+		finished = append(finished, g)
+		x.opponents = append(x.opponents, o.PlayerID)
+		o.opponents = append(o.opponents, x.PlayerID)
+		x.xGames++
+		o.oGames++
+
+		switch g.Victor {
+		case game.StalematePlayer:
+			x.Ties++
+			o.Ties++
+			x.Score += 0.5
+			o.Score += 0.5
+		case x.PlayerID:
+			x.Wins++
+			o.Losses++
+			x.Score++
+			if format == Knockout {
+				o.EliminatedRound = g.Round
+			}
+		case o.PlayerID:
+			o.Wins++
+			x.Losses++
+			o.Score++
+			if format == Knockout {
+				x.EliminatedRound = g.Round
+			}
+		}
+	}
+
+	// tiebreaks need every score to be final
+	for _, g := range finished {
+		x, o := byID[g.PlayerX], byID[g.PlayerO]
+		x.Buchholz += o.Score
+		o.Buchholz += x.Score
+
+		switch g.Victor {
+		case game.StalematePlayer:
+			x.SonnebornBerger += o.Score / 2
+			o.SonnebornBerger += x.Score / 2
+		case x.PlayerID:
+			x.SonnebornBerger += o.Score
+		case o.PlayerID:
+			o.SonnebornBerger += x.Score
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if format == Knockout && a.EliminatedRound != b.EliminatedRound {
+			// players still in go first, then whoever lasted longest
+			if a.EliminatedRound == 0 || b.EliminatedRound == 0 {
+				return a.EliminatedRound == 0
+			}
+			return a.EliminatedRound > b.EliminatedRound
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Buchholz != b.Buchholz {
+			return a.Buchholz > b.Buchholz
+		}
+		if a.SonnebornBerger != b.SonnebornBerger {
+			return a.SonnebornBerger > b.SonnebornBerger
+		}
+		return a.Seed < b.Seed
+	})
+
+	result := make([]Standing, len(standings))
+	for i, st := range standings {
+		st.Rank = i + 1
+		result[i] = *st
+	}
+	return result
+}
+
+// RoundRobinRounds is the number of rounds needed for every player
+// to play every other player once
+func RoundRobinRounds(players int) int {
+	if players%2 == 1 {
+		return players
+	}
+	return players - 1
+}
+
+// RoundRobinPairings returns the pairings for a round (starting at 1)
+// of a round robin, using the circle method. With an odd number of
+// players, one player sits out each round with a bye
+func RoundRobinPairings(players []string, round int) []Pairing {
+	circle := append([]string{}, players...)
+	if len(circle)%2 == 1 {
+		circle = append(circle, "")
+	}
+	n := len(circle)
+
+	// keep the first player fixed and rotate everyone else
+	rotated := make([]string, n)
+	rotated[0] = circle[0]
+	for i := 1; i < n; i++ {
+		rotated[i] = circle[1+(i-1+round-1)%(n-1)]
+	}
+
+	pairings := []Pairing{}
+	for i := 0; i < n/2; i++ {
+		a, b := rotated[i], rotated[n-1-i]
+		// alternate sides from round to round so nobody is always X
+		if (i == 0 && round%2 == 0) || (i != 0 && i%2 == 1) {
+			a, b = b, a
+		}
+
+		if a == "" {
+			pairings = append(pairings, Pairing{X: b})
+		} else if b == "" {
+			pairings = append(pairings, Pairing{X: a})
+		} else {
+			pairings = append(pairings, Pairing{X: a, O: b})
+		}
+	}
+
+	return pairings
+}
+
+// SwissRounds is the default number of rounds for a Swiss tournament,
+// enough to separate a single winner
+func SwissRounds(players int) int {
+	rounds := 0
+	for (1 << uint(rounds)) < players {
+		rounds++
+	}
+	return rounds
+}
+
+// SwissPairings pairs players with similar scores who haven't played
+// each other yet. standings must be in rank order. With an odd number
+// of players, the lowest ranked player without a bye gets one
+func SwissPairings(standings []Standing) []Pairing {
+	remaining := []*Standing{}
+	for i := range standings {
+		remaining = append(remaining, &standings[i])
+	}
+
+	var bye *Standing
+	if len(remaining)%2 == 1 {
+		byeIdx := len(remaining) - 1
+		for i := len(remaining) - 1; i >= 0; i-- {
+			if remaining[i].Byes == 0 {
+				byeIdx = i
+				break
+			}
+		}
+		bye = remaining[byeIdx]
+		remaining = append(remaining[:byeIdx], remaining[byeIdx+1:]...)
+	}
+
+	pairs, ok := pairSwiss(remaining, false)
+	if !ok {
+		// everyone has played everyone they could be paired with;
+		// fall back to allowing rematches
+		pairs, _ = pairSwiss(remaining, true)
+	}
+
+	pairings := []Pairing{}
+	for _, p := range pairs {
+		pairings = append(pairings, assignSides(p[0], p[1]))
+	}
+	if bye != nil {
+		pairings = append(pairings, Pairing{X: bye.PlayerID})
+	}
+
+	return pairings
+}
+
+// pairSwiss pairs the top player with the highest ranked opponent
+// they can play such that the rest can still be paired, backtracking
+// when it gets stuck
+func pairSwiss(players []*Standing, allowRematch bool) ([][2]*Standing, bool) {
+	if len(players) == 0 {
+		return [][2]*Standing{}, true
+	}
+
+	top := players[0]
+	for i := 1; i < len(players); i++ {
+		opponent := players[i]
+		if !allowRematch && top.hasPlayed(opponent.PlayerID) {
+			continue
+		}
+
+		rest := []*Standing{}
+		rest = append(rest, players[1:i]...)
+		rest = append(rest, players[i+1:]...)
+
+		pairs, ok := pairSwiss(rest, allowRematch)
+		if ok {
+			return append([][2]*Standing{{top, opponent}}, pairs...), true
+		}
+	}
+
+	return nil, false
+}
+
+// assignSides gives X to whichever player has played it less often,
+// or to the higher ranked player if they are even
+func assignSides(a, b *Standing) Pairing {
+	if b.xGames-b.oGames < a.xGames-a.oGames {
+		return Pairing{X: b.PlayerID, O: a.PlayerID}
+	}
+	return Pairing{X: a.PlayerID, O: b.PlayerID}
+}
+
+// bracketSize is the smallest power of two that fits every player
+func bracketSize(players int) int {
+	size := 1
+	for size < players {
+		size *= 2
+	}
+	return size
+}
+
+// KnockoutRounds is the number of rounds in a single elimination
+// bracket
+func KnockoutRounds(players int) int {
+	return SwissRounds(players)
+}
+
+// KnockoutPairings returns the first round of a single elimination
+// bracket for players in seed order. Top seeds are kept apart until
+// the late rounds, and get byes when the field isn't a power of two
+func KnockoutPairings(players []string) []Pairing {
+	size := bracketSize(len(players))
+
+	// standard seeding: 1v8, 4v5, 2v7, 3v6 for a bracket of 8
+	order := []int{1}
+	for m := 2; m <= size; m *= 2 {
+		next := []int{}
+		for _, seed := range order {
+			next = append(next, seed, m+1-seed)
+		}
+		order = next
+	}
+
+	pairings := []Pairing{}
+	for i := 0; i < len(order); i += 2 {
+		a, b := order[i], order[i+1]
+		if b > len(players) {
+			pairings = append(pairings, Pairing{X: players[a-1]})
+		} else {
+			pairings = append(pairings, Pairing{X: players[a-1], O: players[b-1]})
+		}
+	}
+
+	return pairings
+}
+
+// NextKnockoutPairings pairs the winners of the previous round, given
+// in bracket order
+func NextKnockoutPairings(winners []string) []Pairing {
+	pairings := []Pairing{}
+	for i := 0; i+1 < len(winners); i += 2 {
+		pairings = append(pairings, Pairing{X: winners[i], O: winners[i+1]})
+	}
+	return pairings
+}
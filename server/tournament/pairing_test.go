@@ -0,0 +1,161 @@
+package tournament_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+	"github.com/heartles/uttt/server/tournament"
+)
+
+func players(n int) []string {
+	ps := []string{}
+	for i := 1; i <= n; i++ {
+		ps = append(ps, fmt.Sprintf("p%v", i))
+	}
+	return ps
+}
+
+func testRoundRobin(n int) func(*testing.T) {
+	return func(t *testing.T) {
+		ps := players(n)
+		met := map[[2]string]int{}
+		byes := map[string]int{}
+
+		rounds := tournament.RoundRobinRounds(n)
+		for r := 1; r <= rounds; r++ {
+			seen := map[string]bool{}
+			for _, p := range tournament.RoundRobinPairings(ps, r) {
+				if seen[p.X] || seen[p.O] {
+					t.Errorf("round %v pairs a player twice: %+v", r, p)
+				}
+				seen[p.X] = true
+				if p.O == "" {
+					byes[p.X]++
+					continue
+				}
+				seen[p.O] = true
+
+				if p.X < p.O {
+					met[[2]string{p.X, p.O}]++
+				} else {
+					met[[2]string{p.O, p.X}]++
+				}
+			}
+		}
+
+		for i := range ps {
+			for j := i + 1; j < len(ps); j++ {
+				if count := met[[2]string{ps[i], ps[j]}]; count != 1 {
+					t.Errorf("%v and %v met %v times", ps[i], ps[j], count)
+				}
+			}
+			if n%2 == 1 && byes[ps[i]] != 1 {
+				t.Errorf("%v had %v byes", ps[i], byes[ps[i]])
+			}
+		}
+	}
+}
+
+func TestRoundRobinPairings(t *testing.T) {
+	t.Run("EvenPlayers", testRoundRobin(6))
+	t.Run("OddPlayers", testRoundRobin(5))
+	t.Run("TwoPlayers", testRoundRobin(2))
+}
+
+func TestKnockoutPairings(t *testing.T) {
+	ps := players(6)
+	pairings := tournament.KnockoutPairings(ps)
+	expected := []tournament.Pairing{
+		{X: "p1"}, {X: "p4", O: "p5"}, {X: "p2"}, {X: "p3", O: "p6"},
+	}
+
+	if len(pairings) != len(expected) {
+		t.Fatalf("KnockoutPairings returned %+v, expected %+v", pairings, expected)
+	}
+	for i := range expected {
+		if pairings[i] != expected[i] {
+			t.Errorf("board %v was %+v, expected %+v", i+1, pairings[i], expected[i])
+		}
+	}
+
+	if rounds := tournament.KnockoutRounds(6); rounds != 3 {
+		t.Errorf("KnockoutRounds(6) returned %v, expected 3", rounds)
+	}
+}
+
+func TestSwissPairingsAvoidRematches(t *testing.T) {
+	ps := players(5)
+	games := []store.TournamentGame{
+		{Round: 1, Board: 1, PlayerX: "p1", PlayerO: "p2", Victor: "p1"},
+		{Round: 1, Board: 2, PlayerX: "p3", PlayerO: "p4", Victor: "p3"},
+		{Round: 1, Board: 3, PlayerX: "p5", Victor: "p5"},
+	}
+
+	standings := tournament.ComputeStandings(tournament.Swiss, ps, games)
+	pairings := tournament.SwissPairings(standings)
+
+	played := map[[2]string]bool{{"p1", "p2"}: true, {"p3", "p4"}: true}
+	byes := 0
+	for _, p := range pairings {
+		if p.O == "" {
+			byes++
+			if p.X == "p5" {
+				t.Errorf("p5 was given a second bye")
+			}
+			continue
+		}
+		if played[[2]string{p.X, p.O}] || played[[2]string{p.O, p.X}] {
+			t.Errorf("rematch paired: %+v", p)
+		}
+	}
+	if byes != 1 {
+		t.Errorf("expected exactly one bye, got %v in %+v", byes, pairings)
+	}
+}
+
+func TestComputeStandingsTiebreaks(t *testing.T) {
+	ps := players(4)
+	games := []store.TournamentGame{
+		{Round: 1, Board: 1, PlayerX: "p1", PlayerO: "p2", Victor: "p1"},
+		{Round: 1, Board: 2, PlayerX: "p3", PlayerO: "p4", Victor: "p4"},
+		{Round: 2, Board: 1, PlayerX: "p1", PlayerO: "p4", Victor: game.StalematePlayer},
+		{Round: 2, Board: 2, PlayerX: "p2", PlayerO: "p3", Victor: "p3"},
+	}
+
+	standings := tournament.ComputeStandings(tournament.Swiss, ps, games)
+	order := []string{}
+	for _, st := range standings {
+		order = append(order, st.PlayerID)
+	}
+
+	// p1 and p4 both have 1.5; p1's opponents scored 1.5, p4's scored 2.5
+	expected := []string{"p4", "p1", "p3", "p2"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("standings were %v, expected %v", order, expected)
+		}
+	}
+
+	if standings[0].Score != 1.5 || standings[0].Buchholz != 2.5 {
+		t.Errorf("unexpected leader standing %+v", standings[0])
+	}
+}
+
+func TestComputeStandingsKnockout(t *testing.T) {
+	ps := players(4)
+	games := []store.TournamentGame{
+		{Round: 1, Board: 1, PlayerX: "p1", PlayerO: "p4", Victor: "p4"},
+		{Round: 1, Board: 2, PlayerX: "p2", PlayerO: "p3", Victor: "p2"},
+		{Round: 2, Board: 1, PlayerX: "p4", PlayerO: "p2", Victor: "p2"},
+	}
+
+	standings := tournament.ComputeStandings(tournament.Knockout, ps, games)
+	if standings[0].PlayerID != "p2" || standings[1].PlayerID != "p4" {
+		t.Errorf("expected p2 then p4 at the top, got %+v", standings)
+	}
+	if standings[1].EliminatedRound != 2 {
+		t.Errorf("p4 should have been eliminated in round 2, got %v", standings[1].EliminatedRound)
+	}
+}
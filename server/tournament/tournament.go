@@ -0,0 +1,343 @@
+// Package tournament runs round robin, Swiss and single elimination
+// tournaments on top of store.GameService. Each round's games are
+// created automatically, and the next round starts as soon as every
+// game in the current one has finished
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/heartles/uttt/server/game"
+	"github.com/heartles/uttt/server/store"
+)
+
+// Tournament formats
+const (
+	RoundRobin = "round-robin"
+	Swiss      = "swiss"
+	Knockout   = "knockout"
+)
+
+// ErrInvalidFormat is returned by Create for an unknown format
+var ErrInvalidFormat = errors.New("invalid tournament format")
+
+// ErrNotFound is returned when a tournament does not exist
+var ErrNotFound = errors.New("tournament not found")
+
+// ErrNotRegistering is returned when joining or starting a tournament
+// that has already started
+var ErrNotRegistering = errors.New("tournament is not open for registration")
+
+// ErrNotOrganizer is returned when someone other than the tournament's
+// creator tries to start it
+var ErrNotOrganizer = errors.New("only the organizer can start the tournament")
+
+// ErrTooFewPlayers is returned when starting a tournament with fewer
+// than two players
+var ErrTooFewPlayers = errors.New("not enough players")
+
+// Service manages tournaments
+type Service struct {
+	games *store.GameService
+
+	// mutex serializes changes to tournament state, so that two games
+	// finishing at once can't both start the next round
+	mutex sync.Mutex
+}
+
+// NewService creates a tournament service that advances tournaments
+// as their games finish
+func NewService(games *store.GameService) *Service {
+	s := &Service{games: games}
+	games.OnGameFinished(s.gameFinished)
+	return s
+}
+
+// Create sets up a tournament open for registration and returns its ID.
+// rounds is only used by Swiss tournaments; 0 picks enough rounds to
+// separate a winner
+func (s *Service) Create(organizer, name, format string, settings store.GameSettings, rounds int) (string, error) {
+	if format != RoundRobin && format != Swiss && format != Knockout {
+		return "", ErrInvalidFormat
+	}
+	if rounds < 0 || format != Swiss {
+		rounds = 0
+	}
+
+	return s.games.CreateTournament(&store.Tournament{
+		Name:      name,
+		Format:    format,
+		CreatedBy: organizer,
+		Status:    store.TournamentRegistering,
+		Settings:  settings,
+		Rounds:    rounds,
+	})
+}
+
+func (s *Service) lookup(tournamentID string) (*store.Tournament, error) {
+	t, err := s.games.TryLookupTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	} else if t == nil {
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+// Register adds a player to a tournament that hasn't started yet
+func (s *Service) Register(tournamentID, playerID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, err := s.lookup(tournamentID)
+	if err != nil {
+		return err
+	} else if t.Status != store.TournamentRegistering {
+		return ErrNotRegistering
+	}
+
+	return s.games.AddTournamentPlayer(tournamentID, playerID)
+}
+
+// Start seeds the registered players by rating and creates the first
+// round's games. Only the organizer can start a tournament
+func (s *Service) Start(tournamentID, playerID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, err := s.lookup(tournamentID)
+	if err != nil {
+		return err
+	} else if t.Status != store.TournamentRegistering {
+		return ErrNotRegistering
+	} else if t.CreatedBy != playerID {
+		return ErrNotOrganizer
+	}
+
+	players, err := s.games.TournamentPlayers(tournamentID)
+	if err != nil {
+		return err
+	} else if len(players) < 2 {
+		return ErrTooFewPlayers
+	}
+
+	players, err = s.seed(t, players)
+	if err != nil {
+		return err
+	}
+
+	switch t.Format {
+	case RoundRobin:
+		t.Rounds = RoundRobinRounds(len(players))
+	case Knockout:
+		t.Rounds = KnockoutRounds(len(players))
+	case Swiss:
+		if t.Rounds == 0 {
+			t.Rounds = SwissRounds(len(players))
+		}
+	}
+	t.Status = store.TournamentRunning
+
+	return s.startRound(t, players, nil, 1)
+}
+
+// seed orders players by their rating in the tournament's pool,
+// keeping registration order between equal ratings
+func (s *Service) seed(t *store.Tournament, players []string) ([]string, error) {
+	rated := t.Settings
+	rated.Casual = false
+	pool := store.Pool(rated)
+
+	ratings := map[string]float64{}
+	for _, p := range players {
+		r, err := s.games.Rating(p, pool)
+		if err != nil {
+			return nil, err
+		}
+		ratings[p] = r.Rating
+	}
+
+	seeded := append([]string{}, players...)
+	sort.SliceStable(seeded, func(i, j int) bool {
+		return ratings[seeded[i]] > ratings[seeded[j]]
+	})
+
+	return seeded, s.games.SetTournamentSeeds(t.ID, seeded)
+}
+
+// startRound creates the games for a round and saves the tournament
+func (s *Service) startRound(t *store.Tournament, players []string, games []store.TournamentGame, round int) error {
+	var pairings []Pairing
+	switch t.Format {
+	case RoundRobin:
+		pairings = RoundRobinPairings(players, round)
+	case Swiss:
+		pairings = SwissPairings(ComputeStandings(t.Format, players, games))
+	case Knockout:
+		if round == 1 {
+			pairings = KnockoutPairings(players)
+		} else {
+			pairings = NextKnockoutPairings(knockoutWinners(games, round-1))
+		}
+	}
+
+	t.CurrentRound = round
+	err := s.games.UpdateTournament(t)
+	if err != nil {
+		return err
+	}
+
+	for board, p := range pairings {
+		err = s.addGame(t, round, board+1, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addGame starts a game for a pairing, or records a bye
+func (s *Service) addGame(t *store.Tournament, round, board int, p Pairing) error {
+	tg := store.TournamentGame{
+		Round:   round,
+		Board:   board,
+		PlayerX: p.X,
+		PlayerO: p.O,
+	}
+
+	if p.O == "" {
+		tg.Victor = p.X
+	} else {
+		gameID, err := s.games.StartGame(p.X, p.O, t.Settings)
+		if err != nil {
+			return err
+		}
+		tg.GameID = gameID
+	}
+
+	return s.games.AddTournamentGame(t.ID, tg)
+}
+
+// knockoutWinners returns the winner of each board in a round, in
+// board order. Only the last game on each board counts, since tied
+// games are replayed
+func knockoutWinners(games []store.TournamentGame, round int) []string {
+	winners := []string{}
+	for i, g := range games {
+		last := i+1 == len(games) || games[i+1].Round != g.Round || games[i+1].Board != g.Board
+		if g.Round == round && last {
+			winners = append(winners, g.Victor)
+		}
+	}
+	return winners
+}
+
+func (s *Service) gameFinished(result store.GameResult) {
+	tournamentID, err := s.games.SetTournamentGameResult(result.GameID, result.Victor)
+	if err != nil {
+		fmt.Println(err)
+		return
+	} else if tournamentID == "" {
+		return
+	}
+
+	err = s.advance(tournamentID)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// advance starts the next round of a tournament once every game in
+// the current round has finished, or ends the tournament after the
+// last round. Tied knockout games are replayed first
+func (s *Service) advance(tournamentID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, err := s.lookup(tournamentID)
+	if err != nil {
+		return err
+	} else if t.Status != store.TournamentRunning {
+		return nil
+	}
+
+	games, err := s.games.TournamentGames(tournamentID)
+	if err != nil {
+		return err
+	}
+
+	replays := []store.TournamentGame{}
+	for i, g := range games {
+		if g.Round != t.CurrentRound {
+			continue
+		}
+		if g.Victor == "" {
+			// still waiting on a game
+			return nil
+		}
+
+		last := i+1 == len(games) || games[i+1].Round != g.Round || games[i+1].Board != g.Board
+		if t.Format == Knockout && last && g.Victor == game.StalematePlayer {
+			replays = append(replays, g)
+		}
+	}
+
+	if len(replays) > 0 {
+		for _, g := range replays {
+			// swap sides for the replay
+			err = s.addGame(t, g.Round, g.Board, Pairing{X: g.PlayerO, O: g.PlayerX})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if t.CurrentRound >= t.Rounds {
+		t.Status = store.TournamentFinished
+		return s.games.UpdateTournament(t)
+	}
+
+	players, err := s.games.TournamentPlayers(tournamentID)
+	if err != nil {
+		return err
+	}
+
+	return s.startRound(t, players, games, t.CurrentRound+1)
+}
+
+// Standings is a snapshot of a tournament
+type Standings struct {
+	Tournament *store.Tournament      `json:"tournament"`
+	Standings  []Standing             `json:"standings"`
+	Games      []store.TournamentGame `json:"games"`
+}
+
+// Standings returns a tournament's current standings and every game
+// played in it so far
+func (s *Service) Standings(tournamentID string) (*Standings, error) {
+	t, err := s.lookup(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := s.games.TournamentPlayers(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	games, err := s.games.TournamentGames(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Standings{
+		Tournament: t,
+		Standings:  ComputeStandings(t.Format, players, games),
+		Games:      games,
+	}, nil
+}
@@ -1,5 +1,10 @@
 import Vue from "vue";
 
+// PROTOCOL_VERSION is the version of the socket protocol this client
+// speaks, and CAPABILITIES the optional features it understands
+const PROTOCOL_VERSION = 2;
+const CAPABILITIES = ["gameDeltas"];
+
 class WSMessage {
   constructor(type, payload) {
     this.messageType = type;
@@ -12,9 +17,15 @@ function createLoginRequestVerifier(socket, resolve, reject) {
     socket.removeEventListener("message", handler);
     console.log(ev);
     let data = JSON.parse(ev.data);
-    if (data.messageType === "LoginSuccess") {
+    if (data.messageType === "Hello") {
+      // the answer to the handshake; the login response comes next
+      socket.addEventListener("message", handler);
+    } else if (data.messageType === "LoginSuccess") {
       resolve({ payload: data.payload, socket });
-    } else if (data.messageType === "LoginFailure") {
+    } else if (
+      data.messageType === "LoginFailure" ||
+      data.messageType === "ErrorMessage"
+    ) {
       console.error(data.payload.message);
       reject("login failed");
     } else {
@@ -25,21 +36,41 @@ function createLoginRequestVerifier(socket, resolve, reject) {
   socket.addEventListener("message", handler);
 }
 
-function sendLoginRequest(socket, username) {
+function sendLoginRequest(socket, username, sessionToken, lastSequence) {
+  let hello = new WSMessage("Hello", {
+    protocolVersion: PROTOCOL_VERSION,
+    capabilities: CAPABILITIES,
+  });
+  hello.requestID = webSocketHandler.requestCounter++;
+  socket.send(JSON.stringify(hello));
+
   let msg = new WSMessage("LoginRequest", {
     loginID: username,
+    sessionToken,
+    lastSequence,
   });
+  msg.requestID = webSocketHandler.requestCounter++;
   socket.send(JSON.stringify(msg));
   return new Promise((resolve, reject) => {
     createLoginRequestVerifier(socket, resolve, reject);
   });
 }
 
+function chatKey(room, gameID) {
+  return gameID ? room + "/" + gameID : room;
+}
+
 const webSocketHandler = {
   socket: null,
   store: null,
   openRequests: {},
   requestCounter: 1,
+  url: null,
+  username: null,
+  sessionToken: null,
+  // lastSequence is the sequence number of the last message received,
+  // sent when resuming the session after the socket drops
+  lastSequence: 0,
   installFunc() {
     let handler = this;
     return (store) => {
@@ -47,43 +78,131 @@ const webSocketHandler = {
     };
   },
   setSocket(socket) {
-    if (this.socket !== null) {
-      // todo: support reconnections? Or just refresh
-      console.error("socket being replaced!");
-    }
     this.socket = socket;
     let handler = this;
     socket.addEventListener("message", (ev) => {
       handler.handleMessage(JSON.parse(ev.data));
     });
+    socket.addEventListener("close", () => {
+      if (handler.socket === socket) {
+        handler.reconnect();
+      }
+    });
+  },
+  reconnect() {
+    let handler = this;
+    let socket = new WebSocket(this.url);
+    socket.addEventListener("open", () => {
+      sendLoginRequest(
+        socket,
+        handler.username,
+        handler.sessionToken,
+        handler.lastSequence
+      )
+        .then(({ socket }) => {
+          console.log("resumed session");
+          handler.setSocket(socket);
+        })
+        .catch(() => {
+          // the session has expired, so start over
+          window.location.reload();
+        });
+    });
+    socket.addEventListener("error", () => {
+      setTimeout(() => handler.reconnect(), 1000);
+    });
   },
   handleMessage(msg) {
     console.debug(msg);
+    if (msg.sequence) {
+      if (msg.sequence <= this.lastSequence) {
+        return;
+      }
+      this.lastSequence = msg.sequence;
+    }
     switch (msg.messageType) {
       case "GameState":
         this.store.commit("gameUpdate", msg.payload);
         break;
+      case "GameDelta":
+        this.handleGameDelta(msg.payload);
+        break;
       case "UserLookup":
         this.store.commit("addLookupResult", msg.payload);
         break;
+      case "MatchFound":
+        this.store.commit("setSearching", false);
+        break;
+      case "Presence":
+        this.store.commit("setPresence", msg.payload);
+        break;
+      case "OnlinePlayers":
+        msg.payload.players.forEach((p) => this.store.commit("setPresence", p));
+        break;
+      case "FriendsList":
+        this.store.commit("setFriends", msg.payload);
+        break;
+      case "ChatHistory":
+        this.store.commit("chatHistory", msg.payload);
+        break;
+      case "ChatMessage":
+        this.store.commit("chatMessage", msg.payload);
+        break;
+      case "Ack":
+        break;
+      case "ErrorMessage":
+        if (msg.unsolicited) {
+          console.error(msg.payload.code + ": " + msg.payload.message);
+        }
+        break;
       default:
         console.error("unknown websocket message type: " + msg.messageType);
         break;
     }
-    let id = msg.requestID.toString();
-    if (this.openRequests[id] != undefined) {
-      this.openRequests[id]();
-      this.openRequests[id] = undefined;
+    if (!msg.unsolicited) {
+      this.settleRequest(msg);
+    }
+  },
+  // handleGameDelta applies a delta to the game it belongs to, or asks
+  // for a full snapshot if a delta has been missed
+  handleGameDelta(delta) {
+    let game = this.store.state.games[delta.gameID];
+    if (game && delta.version <= game.version) {
+      return;
+    }
+    if (!game || delta.version != game.version + 1) {
+      this.store.dispatch("gameSnapshot", delta.gameID);
+      return;
+    }
+    this.store.commit("applyGameDelta", delta);
+  },
+  // settleRequest resolves the promise of the request a response
+  // answers, or rejects it if the request failed
+  settleRequest(msg) {
+    let request = this.openRequests[msg.requestID];
+    if (request == undefined) {
+      return;
+    }
+    delete this.openRequests[msg.requestID];
+
+    if (msg.messageType === "ErrorMessage") {
+      request.reject(msg.payload);
+    } else {
+      request.resolve(msg.payload);
     }
   },
   sendMessage(msg) {
-    this.sendMessagePromise(msg);
+    this.sendMessagePromise(msg).catch((err) =>
+      console.error(err.code + ": " + err.message)
+    );
   },
+  // sendMessagePromise sends a request, returning a promise of its
+  // response. It is rejected with the ErrorMessage if the request fails
   sendMessagePromise(msg) {
     msg.requestID = this.requestCounter++;
     console.debug(msg);
-    return new Promise((resolve) => {
-      this.openRequests[msg.requestID.toString()] = resolve;
+    return new Promise((resolve, reject) => {
+      this.openRequests[msg.requestID] = { resolve, reject };
       this.socket.send(JSON.stringify(msg));
     });
   },
@@ -96,6 +215,10 @@ const store = {
     games: {},
     usernameMap: {},
     playerIDMap: {},
+    searching: false,
+    chat: {},
+    presence: {},
+    friends: { friends: [], incoming: [], outgoing: [], blocked: [] },
   },
   mutations: {
     setUser(state, { username, playerID }) {
@@ -107,6 +230,73 @@ const store = {
     },
     gameUpdate(state, game) {
       Vue.set(state.games, game.gameID, game);
+      Vue.set(state.presence, game.playerX, game.playerXPresence);
+      Vue.set(state.presence, game.playerO, game.playerOPresence);
+    },
+    applyGameDelta(state, delta) {
+      let game = state.games[delta.gameID];
+      let playable = {};
+      (delta.playableGrids || []).forEach((c) => {
+        playable[c.x + "," + c.y] = true;
+      });
+
+      game.grids.forEach((gridRow) =>
+        gridRow.forEach((grid) =>
+          grid.squares.forEach((squareRow) =>
+            squareRow.forEach((square) => {
+              let c = square.coordinate;
+              if (
+                delta.move &&
+                c.gameSquare.x == delta.move.coordinate.gameSquare.x &&
+                c.gameSquare.y == delta.move.coordinate.gameSquare.y &&
+                c.subgridSquare.x == delta.move.coordinate.subgridSquare.x &&
+                c.subgridSquare.y == delta.move.coordinate.subgridSquare.y
+              ) {
+                square.owner = delta.move.playerID;
+              }
+              if (delta.move) {
+                square.playable =
+                  delta.turn == state.playerID &&
+                  square.owner == null &&
+                  playable[c.gameSquare.x + "," + c.gameSquare.y] == true;
+              }
+            })
+          )
+        )
+      );
+
+      if (delta.move && delta.gridOwner) {
+        let c = delta.move.coordinate.gameSquare;
+        game.grids[c.y - 1][c.x - 1].owner = delta.gridOwner;
+      }
+      game.spectators = delta.spectators;
+      game.version = delta.version;
+    },
+    setPresence(state, { playerID, status }) {
+      Vue.set(state.presence, playerID, status);
+      let friend = state.friends.friends.find((f) => f.playerID == playerID);
+      if (friend) {
+        friend.status = status;
+      }
+    },
+    setFriends(state, list) {
+      state.friends = {
+        friends: list.friends || [],
+        incoming: list.incoming || [],
+        outgoing: list.outgoing || [],
+        blocked: list.blocked || [],
+      };
+    },
+    setSearching(state, searching) {
+      state.searching = searching;
+    },
+    chatHistory(state, { room, gameID, messages }) {
+      Vue.set(state.chat, chatKey(room, gameID), messages);
+    },
+    chatMessage(state, message) {
+      let key = chatKey(message.room, message.gameID);
+      let messages = state.chat[key] || [];
+      Vue.set(state.chat, key, messages.concat([message]));
     },
     addLookupResult(state, result) {
       Vue.set(state.usernameMap, result.username, result.playerID);
@@ -124,8 +314,17 @@ const store = {
           sendLoginRequest(socket, username)
             .then(({ payload, socket }) => {
               console.log("successful connection");
+              webSocketHandler.url = url;
+              webSocketHandler.username = username;
+              webSocketHandler.sessionToken = payload.sessionToken;
               webSocketHandler.setSocket(socket);
               context.commit("setUser", payload);
+              document.addEventListener("visibilitychange", () => {
+                context.dispatch(
+                  "setPresence",
+                  document.hidden ? "away" : "online"
+                );
+              });
               resolve();
             })
             .catch((reason) => {
@@ -136,16 +335,75 @@ const store = {
       });
     },
     playMove(context, { gameID, move }) {
-      let message = new WSMessage("PlayMove", { gameID, move });
+      let message = new WSMessage("PlayMove", {
+        gameID,
+        coordinate: move.coordinate,
+      });
+      webSocketHandler.sendMessage(message);
+    },
+    newGame(context, { opponentID, color }) {
+      let message = new WSMessage("NewGame", { opponentID, color });
+      webSocketHandler.sendMessage(message);
+    },
+    joinQueue(context, preferences) {
+      let message = new WSMessage("JoinQueue", preferences);
+      webSocketHandler.sendMessage(message);
+      context.commit("setSearching", true);
+    },
+    leaveQueue(context) {
+      let message = new WSMessage("LeaveQueue", {});
+      webSocketHandler.sendMessage(message);
+      context.commit("setSearching", false);
+    },
+    joinChat(context, { room, gameID }) {
+      let message = new WSMessage("JoinChat", { room, gameID });
+      webSocketHandler.sendMessage(message);
+    },
+    leaveChat(context, { room, gameID }) {
+      let message = new WSMessage("LeaveChat", { room, gameID });
+      webSocketHandler.sendMessage(message);
+    },
+    sendChat(context, { room, gameID, body }) {
+      let message = new WSMessage("SendChat", { room, gameID, body });
+      webSocketHandler.sendMessage(message);
+    },
+    fetchFriends() {
+      let message = new WSMessage("FriendsList", {});
+      webSocketHandler.sendMessage(message);
+    },
+    // action is one of FriendRequest, AcceptFriend, DeclineFriend,
+    // RemoveFriend, BlockPlayer or UnblockPlayer
+    updateFriend(context, { action, playerID }) {
+      let message = new WSMessage(action, { playerID });
+      webSocketHandler.sendMessage(message);
+    },
+    setPresence(context, status) {
+      let message = new WSMessage("SetPresence", { status });
+      webSocketHandler.sendMessage(message);
+    },
+    spectate(context, gameID) {
+      let message = new WSMessage("Spectate", { gameID });
+      webSocketHandler.sendMessage(message);
+    },
+    gameSnapshot(context, gameID) {
+      let message = new WSMessage("GameSnapshot", { gameID });
+      webSocketHandler.sendMessage(message);
+    },
+    stopSpectating(context, gameID) {
+      let message = new WSMessage("StopSpectating", { gameID });
+      webSocketHandler.sendMessage(message);
+    },
+    rematch(context, gameID) {
+      let message = new WSMessage("Rematch", { gameID });
       webSocketHandler.sendMessage(message);
     },
-    newGame(context, opponentID) {
-      let message = new WSMessage("NewGame", { opponentID });
+    declineRematch(context, gameID) {
+      let message = new WSMessage("DeclineRematch", { gameID });
       webSocketHandler.sendMessage(message);
     },
     lookupOpponent(context, opponent) {
       let message = new WSMessage("UserLookup", { username: opponent });
-      return webSocketHandler.sendMessagePromise(message); // TODO: set up better promise mechanism
+      return webSocketHandler.sendMessagePromise(message);
     },
   },
   getters: {